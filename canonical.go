@@ -0,0 +1,490 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// DefaultCanonicalWork is the limit on the work done to canonicalize a
+// dataset when Canonicalizer.MaxWork is zero.
+const DefaultCanonicalWork = 1 << 20
+
+// ErrCanonicalWorkLimit is returned by a Canonicalizer when canonicalizing a
+// dataset would take more work than its MaxWork allows. Datasets with many
+// blank nodes that cannot be told apart, which may be crafted to make
+// canonicalization take exponential time, exceed the limit.
+var ErrCanonicalWorkLimit = errors.New("canonicalization work limit exceeded")
+
+// A Canonicalizer relabels the blank nodes of RDF datasets with the RDF
+// Dataset Canonicalization algorithm, RDFC-1.0, using SHA-256 as the hash
+// algorithm. Datasets that are isomorphic are given identical labels, _:c14n0,
+// _:c14n1 and so on, so that their canonical N-Quads encodings are identical
+// and can be hashed or signed.
+//
+// Blank nodes in quoted triples are relabelled too, as if they appeared in
+// the position of the quoted triple. RDFC-1.0 does not cover quoted triples,
+// so their labels may differ from those of other implementations.
+//
+// The exported fields can be changed to customize the details before the
+// first call to Canonicalize.
+type Canonicalizer struct {
+	// MaxWork limits the work done by the Hash N-Degree Quads algorithm,
+	// counted as the number of times it is run plus the number of
+	// permutations of related blank nodes that it examines. If the limit is
+	// reached, canonicalization stops with ErrCanonicalWorkLimit. If MaxWork
+	// is zero, DefaultCanonicalWork is used; if it is negative there is no
+	// limit.
+	MaxWork int
+}
+
+// Canonicalize returns the canonical form of the graph of triples, using a
+// Canonicalizer with the default settings.
+func Canonicalize(triples []Triple) ([]Triple, map[string]string, error) {
+	var c Canonicalizer
+	return c.Canonicalize(triples)
+}
+
+// CanonicalizeQuads returns the canonical form of the dataset of quads, using
+// a Canonicalizer with the default settings.
+func CanonicalizeQuads(quads []Quad) ([]Quad, map[string]string, error) {
+	var c Canonicalizer
+	return c.CanonicalizeQuads(quads)
+}
+
+// CanonicalHash returns the hash of the canonical form of the graph of
+// triples, using a Canonicalizer with the default settings.
+func CanonicalHash(triples []Triple) (string, error) {
+	var c Canonicalizer
+	return c.CanonicalHash(triples)
+}
+
+// CanonicalHashQuads returns the hash of the canonical form of the dataset of
+// quads, using a Canonicalizer with the default settings.
+func CanonicalHashQuads(quads []Quad) (string, error) {
+	var c Canonicalizer
+	return c.CanonicalHashQuads(quads)
+}
+
+// Canonicalize returns the canonical form of the graph of triples, as for
+// CanonicalizeQuads with every triple in the default graph.
+func (c *Canonicalizer) Canonicalize(triples []Triple) ([]Triple, map[string]string, error) {
+	quads := make([]Quad, len(triples))
+	for i, t := range triples {
+		quads[i] = Quad{Triple: t}
+	}
+	quads, labels, err := c.CanonicalizeQuads(quads)
+	if err != nil {
+		return nil, nil, err
+	}
+	triples = make([]Triple, len(quads))
+	for i, q := range quads {
+		triples[i] = q.Triple
+	}
+	return triples, labels, nil
+}
+
+// CanonicalizeQuads returns the canonical form of the dataset of quads: the
+// distinct quads with their blank nodes relabelled and their terms in
+// canonical form, sorted by their canonical N-Quads encoding. Writing each
+// one as in Quad.CanonicalString, followed by a newline, gives the canonical
+// N-Quads document. It also returns the canonical label issued for each
+// blank node label in quads.
+//
+// Each quad is validated first, as for Quad.Validate, and the first invalid
+// quad is reported as an error.
+func (c *Canonicalizer) CanonicalizeQuads(quads []Quad) ([]Quad, map[string]string, error) {
+	s, err := newCanonicalState(quads, c.MaxWork)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.issueLabels(); err != nil {
+		return nil, nil, err
+	}
+
+	relabel := func(label string) string { return s.canonical.issued[label] }
+	type keyedQuad struct {
+		key  string
+		quad Quad
+	}
+	result := make([]keyedQuad, len(s.quads))
+	for i, q := range s.quads {
+		q = canonicalQuad(Quad{Triple: relabelTriple(q.Triple, relabel), G: relabelTerm(q.G, relabel)})
+		result[i] = keyedQuad{key: q.CanonicalString(), quad: q}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].key < result[j].key })
+
+	canonical := make([]Quad, len(result))
+	for i, kq := range result {
+		canonical[i] = kq.quad
+	}
+	return canonical, s.canonical.issued, nil
+}
+
+// CanonicalHash returns the hash of the canonical form of the graph of
+// triples, as for CanonicalHashQuads with every triple in the default graph.
+func (c *Canonicalizer) CanonicalHash(triples []Triple) (string, error) {
+	quads := make([]Quad, len(triples))
+	for i, t := range triples {
+		quads[i] = Quad{Triple: t}
+	}
+	return c.CanonicalHashQuads(quads)
+}
+
+// CanonicalHashQuads returns the SHA-256 hash of the canonical N-Quads
+// document of the dataset of quads, as lowercase hexadecimal. Datasets have
+// the same hash if and only if they are isomorphic, barring hash collisions.
+func (c *Canonicalizer) CanonicalHashQuads(quads []Quad) (string, error) {
+	canonical, _, err := c.CanonicalizeQuads(quads)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	var b []byte
+	for _, q := range canonical {
+		b, _ = appendQuad(b[:0], q)
+		b = append(b, '\n')
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// A canonicalState holds the state of the canonicalization of a dataset.
+type canonicalState struct {
+	quads       []Quad            // distinct quads
+	uses        map[string][]int  // indexes of the quads that use each blank node
+	labels      []string          // blank node labels in order of first use
+	canonical   *labelIssuer      // issuer of canonical labels
+	firstDegree map[string]string // first degree hashes keyed by label
+
+	work    int
+	maxWork int
+}
+
+// newCanonicalState validates quads and returns the state for
+// canonicalizing them, with the maximum work given as for
+// Canonicalizer.MaxWork.
+func newCanonicalState(quads []Quad, maxWork int) (*canonicalState, error) {
+	if maxWork == 0 {
+		maxWork = DefaultCanonicalWork
+	}
+	s := &canonicalState{
+		uses:        map[string][]int{},
+		canonical:   newLabelIssuer("c14n"),
+		firstDegree: map[string]string{},
+		maxWork:     maxWork,
+	}
+
+	seen := map[string]bool{}
+	for _, q := range quads {
+		if err := validateQuad(q); err != nil {
+			return nil, err
+		}
+		key := q.CanonicalString()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		i := len(s.quads)
+		s.quads = append(s.quads, q)
+		labels := blankLabels(nil, q.Triple)
+		if q.G.TermType == RdfBlank {
+			labels = append(labels, q.G.Value)
+		}
+		for _, label := range labels {
+			uses, ok := s.uses[label]
+			if !ok {
+				s.labels = append(s.labels, label)
+			}
+			if len(uses) == 0 || uses[len(uses)-1] != i {
+				s.uses[label] = append(uses, i)
+			}
+		}
+	}
+	return s, nil
+}
+
+// issueLabels issues a canonical label to every blank node, as in steps 3 to
+// 5 of the canonicalization algorithm.
+func (s *canonicalState) issueLabels() error {
+	byHash := map[string][]string{}
+	for _, label := range s.labels {
+		h := s.hashFirstDegree(label)
+		byHash[h] = append(byHash[h], label)
+	}
+	hashes := make([]string, 0, len(byHash))
+	for h := range byHash {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	// Blank nodes with a unique first degree hash are labelled in order of
+	// their hashes
+	for _, h := range hashes {
+		if labels := byHash[h]; len(labels) == 1 {
+			s.canonical.issue(labels[0])
+		}
+	}
+
+	// The others are labelled in order of their first degree hashes, then of
+	// the hashes of their surroundings
+	for _, h := range hashes {
+		labels := byHash[h]
+		if len(labels) == 1 {
+			continue
+		}
+		var results []nDegreeResult
+		for _, label := range labels {
+			if _, ok := s.canonical.issued[label]; ok {
+				continue
+			}
+			issuer := newLabelIssuer("b")
+			issuer.issue(label)
+			result, err := s.hashNDegree(label, issuer)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		sort.SliceStable(results, func(i, j int) bool { return results[i].hash < results[j].hash })
+		for _, result := range results {
+			for _, label := range result.issuer.order {
+				s.canonical.issue(label)
+			}
+		}
+	}
+	return nil
+}
+
+// hashFirstDegree returns the first degree hash of the blank node with the
+// given label: the hash of the quads that use it, with the node relabelled
+// _:a and every other blank node _:z.
+func (s *canonicalState) hashFirstDegree(label string) string {
+	if h, ok := s.firstDegree[label]; ok {
+		return h
+	}
+
+	relabel := func(l string) string {
+		if l == label {
+			return "a"
+		}
+		return "z"
+	}
+	uses := s.uses[label]
+	lines := make([]string, len(uses))
+	for i, j := range uses {
+		q := s.quads[j]
+		lines[i] = Quad{Triple: relabelTriple(q.Triple, relabel), G: relabelTerm(q.G, relabel)}.CanonicalString()
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte{'\n'})
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	s.firstDegree[label] = hash
+	return hash
+}
+
+// hashRelated returns the hash of the blank node related with the given
+// label, which appears in the given position of q: 's', 'o' or 'g'.
+func (s *canonicalState) hashRelated(related string, q Quad, issuer *labelIssuer, position byte) string {
+	input := []byte{position}
+	if position != 'g' {
+		input = append(input, '<')
+		input = append(input, q.P.Value...)
+		input = append(input, '>')
+	}
+	if label, ok := s.canonical.issued[related]; ok {
+		input = append(append(input, '_', ':'), label...)
+	} else if label, ok := issuer.issued[related]; ok {
+		input = append(append(input, '_', ':'), label...)
+	} else {
+		input = append(input, s.hashFirstDegree(related)...)
+	}
+	h := sha256.Sum256(input)
+	return hex.EncodeToString(h[:])
+}
+
+// An nDegreeResult is the result of the Hash N-Degree Quads algorithm.
+type nDegreeResult struct {
+	hash   string
+	issuer *labelIssuer
+}
+
+// hashNDegree returns the hash of the blank node with the given label and its
+// surroundings, together with the issuer of the temporary labels used to
+// compute it. It returns ErrCanonicalWorkLimit if the limit on the work is
+// reached.
+func (s *canonicalState) hashNDegree(label string, issuer *labelIssuer) (nDegreeResult, error) {
+	if err := s.addWork(); err != nil {
+		return nDegreeResult{}, err
+	}
+
+	// Group the related blank nodes by their hashes
+	byHash := map[string][]string{}
+	add := func(related string, q Quad, position byte) {
+		if related != label {
+			h := s.hashRelated(related, q, issuer, position)
+			byHash[h] = append(byHash[h], related)
+		}
+	}
+	for _, i := range s.uses[label] {
+		q := s.quads[i]
+		for _, component := range [3]struct {
+			term     RdfTerm
+			position byte
+		}{{q.S, 's'}, {q.O, 'o'}, {q.G, 'g'}} {
+			switch component.term.TermType {
+			case RdfBlank:
+				add(component.term.Value, q, component.position)
+			case RdfTriple:
+				for _, related := range blankLabels(nil, *component.term.Triple) {
+					add(related, q, component.position)
+				}
+			}
+		}
+	}
+	hashes := make([]string, 0, len(byHash))
+	for h := range byHash {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	h := sha256.New()
+	for _, related := range hashes {
+		h.Write([]byte(related))
+
+		var chosenPath string
+		var chosenIssuer *labelIssuer
+		err := permute(byHash[related], func(permutation []string) error {
+			if err := s.addWork(); err != nil {
+				return err
+			}
+			issuerCopy := issuer.copy()
+			var path []byte
+			var recursion []string
+			longer := func() bool {
+				return chosenPath != "" && len(path) >= len(chosenPath) && string(path) > chosenPath
+			}
+
+			for _, node := range permutation {
+				if label, ok := s.canonical.issued[node]; ok {
+					path = append(append(path, '_', ':'), label...)
+				} else {
+					if _, ok := issuerCopy.issued[node]; !ok {
+						recursion = append(recursion, node)
+					}
+					path = append(append(path, '_', ':'), issuerCopy.issue(node)...)
+				}
+				if longer() {
+					return nil
+				}
+			}
+
+			for _, node := range recursion {
+				result, err := s.hashNDegree(node, issuerCopy)
+				if err != nil {
+					return err
+				}
+				path = append(append(path, '_', ':'), issuerCopy.issue(node)...)
+				path = append(append(append(path, '<'), result.hash...), '>')
+				issuerCopy = result.issuer
+				if longer() {
+					return nil
+				}
+			}
+
+			if chosenPath == "" || string(path) < chosenPath {
+				chosenPath, chosenIssuer = string(path), issuerCopy
+			}
+			return nil
+		})
+		if err != nil {
+			return nDegreeResult{}, err
+		}
+
+		h.Write([]byte(chosenPath))
+		issuer = chosenIssuer
+	}
+	return nDegreeResult{hash: hex.EncodeToString(h.Sum(nil)), issuer: issuer}, nil
+}
+
+// addWork counts one unit of work, returning ErrCanonicalWorkLimit if the
+// limit has been reached.
+func (s *canonicalState) addWork() error {
+	s.work++
+	if s.maxWork > 0 && s.work > s.maxWork {
+		return ErrCanonicalWorkLimit
+	}
+	return nil
+}
+
+// permute calls f with each permutation of labels in turn, stopping at the
+// first error.
+func permute(labels []string, f func([]string) error) error {
+	permutation := append([]string(nil), labels...)
+	var generate func(n int) error
+	generate = func(n int) error {
+		if n <= 1 {
+			return f(permutation)
+		}
+		for i := 0; i < n; i++ {
+			permutation[i], permutation[n-1] = permutation[n-1], permutation[i]
+			if err := generate(n - 1); err != nil {
+				return err
+			}
+			permutation[i], permutation[n-1] = permutation[n-1], permutation[i]
+		}
+		return nil
+	}
+	return generate(len(permutation))
+}
+
+// A labelIssuer issues new blank node labels with a common prefix, numbered
+// in the order they are issued.
+type labelIssuer struct {
+	prefix string
+	issued map[string]string // labels issued keyed by existing label
+	order  []string          // existing labels in the order labels were issued
+}
+
+// newLabelIssuer returns an issuer of labels with the given prefix.
+func newLabelIssuer(prefix string) *labelIssuer {
+	return &labelIssuer{prefix: prefix, issued: map[string]string{}}
+}
+
+// issue returns the label issued for the existing label, issuing a new one
+// if necessary.
+func (i *labelIssuer) issue(label string) string {
+	if issued, ok := i.issued[label]; ok {
+		return issued
+	}
+	issued := i.prefix + strconv.Itoa(len(i.order))
+	i.issued[label] = issued
+	i.order = append(i.order, label)
+	return issued
+}
+
+// copy returns a copy of i that issues labels independently.
+func (i *labelIssuer) copy() *labelIssuer {
+	c := &labelIssuer{
+		prefix: i.prefix,
+		issued: make(map[string]string, len(i.issued)),
+		order:  append([]string(nil), i.order...),
+	}
+	for label, issued := range i.issued {
+		c.issued[label] = issued
+	}
+	return c
+}
@@ -0,0 +1,225 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// quadLines returns the canonical N-Quads document of quads.
+func quadLines(quads []Quad) string {
+	var b strings.Builder
+	for _, q := range quads {
+		b.WriteString(q.CanonicalString())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestCanonicalizeQuads(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+		labels   map[string]string
+	}{
+		{
+			// Example from RDFC-1.0 where every blank node has a unique
+			// first degree hash
+			input: `<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#r> _:e1 .
+_:e0 <http://example.com/#s> <http://example.com/#u> .
+_:e1 <http://example.com/#t> <http://example.com/#u> .
+`,
+			expected: `<http://example.com/#p> <http://example.com/#q> _:c14n0 .
+<http://example.com/#p> <http://example.com/#r> _:c14n1 .
+_:c14n0 <http://example.com/#s> <http://example.com/#u> .
+_:c14n1 <http://example.com/#t> <http://example.com/#u> .
+`,
+			labels: map[string]string{"e0": "c14n0", "e1": "c14n1"},
+		},
+		{
+			// Example from RDFC-1.0 that needs the Hash N-Degree Quads
+			// algorithm
+			input: `_:e0 <http://example.org/vocab#next> _:e1 .
+_:e0 <http://example.org/vocab#prev> _:e2 .
+_:e1 <http://example.org/vocab#next> _:e2 .
+_:e1 <http://example.org/vocab#prev> _:e0 .
+_:e2 <http://example.org/vocab#next> _:e0 .
+_:e2 <http://example.org/vocab#prev> _:e1 .
+`,
+			expected: `_:c14n0 <http://example.org/vocab#next> _:c14n2 .
+_:c14n0 <http://example.org/vocab#prev> _:c14n1 .
+_:c14n1 <http://example.org/vocab#next> _:c14n0 .
+_:c14n1 <http://example.org/vocab#prev> _:c14n2 .
+_:c14n2 <http://example.org/vocab#next> _:c14n1 .
+_:c14n2 <http://example.org/vocab#prev> _:c14n0 .
+`,
+			labels: map[string]string{"e0": "c14n0", "e1": "c14n2", "e2": "c14n1"},
+		},
+		{
+			input: `<http://example.org/a> <http://example.org/p> "x"@EN <http://example.org/g> .
+<http://example.org/a> <http://example.org/p> "x"@en <http://example.org/g> .
+<http://example.org/a> <http://example.org/p> "y"^^<http://www.w3.org/2001/XMLSchema#string> .
+`,
+			expected: `<http://example.org/a> <http://example.org/p> "x"@en <http://example.org/g> .
+<http://example.org/a> <http://example.org/p> "y" .
+`,
+			labels: map[string]string{},
+		},
+		{
+			input: `_:s <http://example.org/p> "x" _:g .
+_:g <http://example.org/p> "y" .
+`,
+			expected: `_:c14n0 <http://example.org/p> "x" _:c14n1 .
+_:c14n1 <http://example.org/p> "y" .
+`,
+			labels: map[string]string{"g": "c14n1", "s": "c14n0"},
+		},
+		{
+			input: `<< _:s <http://example.org/p> _:o >> <http://example.org/q> _:o .
+`,
+			expected: `<< _:c14n1 <http://example.org/p> _:c14n0 >> <http://example.org/q> _:c14n0 .
+`,
+			labels: map[string]string{"o": "c14n0", "s": "c14n1"},
+		},
+	}
+
+	for _, tc := range cases {
+		quads, labels, err := CanonicalizeQuads(readQuads(t, tc.input))
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+			continue
+		}
+		if actual := quadLines(quads); actual != tc.expected {
+			t.Errorf("Expected %q but got %q", tc.expected, actual)
+		}
+		if !reflect.DeepEqual(labels, tc.labels) {
+			t.Errorf("Expected %v but got %v", tc.labels, labels)
+		}
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	// A graph with symmetries, written with different labels and in a
+	// different order
+	a := readTriples(t, `_:a <http://example.org/next> _:b .
+_:b <http://example.org/next> _:c .
+_:c <http://example.org/next> _:d .
+_:d <http://example.org/next> _:a .
+_:a <http://example.org/name> "corner" .
+_:c <http://example.org/name> "corner" .
+`)
+	b := readTriples(t, `_:n3 <http://example.org/name> "corner" .
+_:n2 <http://example.org/next> _:n3 .
+_:n1 <http://example.org/next> _:n2 .
+_:n1 <http://example.org/name> "corner" .
+_:n4 <http://example.org/next> _:n1 .
+_:n3 <http://example.org/next> _:n4 .
+`)
+	ca, _, err := Canonicalize(a)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	cb, _, err := Canonicalize(b)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(ca, cb) {
+		t.Errorf("Expected %v but got %v", ca, cb)
+	}
+	if ok, _ := Isomorphic(a, ca); !ok {
+		t.Errorf("Expected %v to be isomorphic to %v", ca, a)
+	}
+
+	ha, err := CanonicalHash(a)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	hb, err := CanonicalHash(b)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	if ha != hb {
+		t.Errorf("Expected %s but got %s", ha, hb)
+	}
+
+	var document strings.Builder
+	for _, triple := range ca {
+		document.WriteString(triple.CanonicalString() + "\n")
+	}
+	sum := sha256.Sum256([]byte(document.String()))
+	if expected := hex.EncodeToString(sum[:]); ha != expected {
+		t.Errorf("Expected %s but got %s", expected, ha)
+	}
+
+	// Moving one name breaks the symmetry
+	c := readTriples(t, `_:a <http://example.org/next> _:b .
+_:b <http://example.org/next> _:c .
+_:c <http://example.org/next> _:d .
+_:d <http://example.org/next> _:a .
+_:a <http://example.org/name> "corner" .
+_:b <http://example.org/name> "corner" .
+`)
+	hc, err := CanonicalHash(c)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	if hc == ha {
+		t.Errorf("Expected different hashes for non-isomorphic graphs but got %s", hc)
+	}
+}
+
+// clique returns a graph in which each of n blank nodes is linked to every
+// other.
+func clique(n int) []Triple {
+	p, _ := NewIRI("http://example.org/p")
+	var triples []Triple
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j {
+				s, _ := NewBlankNode("n" + strconv.Itoa(i))
+				o, _ := NewBlankNode("n" + strconv.Itoa(j))
+				triples = append(triples, Triple{S: s, P: p, O: o})
+			}
+		}
+	}
+	return triples
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	// Every blank node in a clique looks the same, so the number of
+	// permutations to examine grows factorially
+	c := Canonicalizer{MaxWork: 1000}
+	if _, _, err := c.Canonicalize(clique(6)); err != ErrCanonicalWorkLimit {
+		t.Errorf("Expected %v but got %v", ErrCanonicalWorkLimit, err)
+	}
+	if _, err := c.CanonicalHash(clique(6)); err != ErrCanonicalWorkLimit {
+		t.Errorf("Expected %v but got %v", ErrCanonicalWorkLimit, err)
+	}
+	c.MaxWork = -1
+	if _, _, err := c.Canonicalize(clique(4)); err != nil {
+		t.Errorf("Got unexpected error %v", err)
+	}
+
+	invalid := []Quad{{
+		Triple: Triple{
+			S: RdfTerm{Value: "s", TermType: RdfBlank},
+			P: RdfTerm{Value: "p", TermType: RdfBlank},
+			O: RdfTerm{Value: "o", TermType: RdfBlank},
+		},
+	}}
+	if _, _, err := CanonicalizeQuads(invalid); err != ErrInvalidPredicate {
+		t.Errorf("Expected %v but got %v", ErrInvalidPredicate, err)
+	}
+	if _, err := CanonicalHashQuads(invalid); err != ErrInvalidPredicate {
+		t.Errorf("Expected %v but got %v", ErrInvalidPredicate, err)
+	}
+}
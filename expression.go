@@ -0,0 +1,372 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// An Expression is a FILTER expression, as in SPARQL. An expression with no
+// Op is a variable named by Variable, which evaluates to the term bound to
+// it, or if Variable is "" the constant Term, which evaluates to itself.
+// Otherwise the expression applies the operator or function Op to the values
+// of Args. The operators are
+//
+//	=  !=          terms are ValueEqual, or not
+//	<  <=  >  >=   compare numbers, booleans, dates, times or strings
+//	&&  ||  !      logical and, or and not
+//	+  -  *  /     arithmetic on numbers
+//
+// and the functions, which are named in lower case, are
+//
+//	bound(?v)            whether the variable ?v is bound
+//	lang(l)              the language tag of the literal l, or ""
+//	langmatches(t, r)    whether the language tag t matches the range r
+//	datatype(l)          the datatype IRI of the literal l
+//	str(t)               the lexical form of a literal or the IRI
+//	isiri(t)  isuri(t)   whether t is an IRI
+//	isblank(t)           whether t is a blank node
+//	isliteral(t)         whether t is a literal
+//	isnumeric(t)         whether t is a valid numeric literal
+//	sameterm(t, u)       whether t and u are Equal
+//	regex(s, p, f)       whether the string s matches the pattern p, using
+//	                     the optional flags f
+//
+// as described in SPARQL 1.1 Query. Arithmetic follows the type promotion
+// rules of XPath except that xsd:float is promoted to xsd:double, and
+// patterns use the syntax of the regexp package rather than XPath.
+type Expression struct {
+	Op       string
+	Variable string
+	Term     RdfTerm
+	Args     []Expression
+}
+
+// opArgs is the minimum and maximum number of arguments of each operator
+// and function.
+var opArgs = map[string][2]int{
+	"=": {2, 2}, "!=": {2, 2}, "<": {2, 2}, "<=": {2, 2}, ">": {2, 2}, ">=": {2, 2},
+	"&&": {2, 2}, "||": {2, 2}, "!": {1, 1},
+	"+": {2, 2}, "-": {2, 2}, "*": {2, 2}, "/": {2, 2},
+	"bound": {1, 1}, "lang": {1, 1}, "langmatches": {2, 2}, "datatype": {1, 1}, "str": {1, 1},
+	"isiri": {1, 1}, "isuri": {1, 1}, "isblank": {1, 1}, "isliteral": {1, 1}, "isnumeric": {1, 1},
+	"sameterm": {2, 2}, "regex": {2, 3},
+}
+
+// Ranks of the numeric datatypes in the order used for type promotion.
+const (
+	numericInteger = iota
+	numericDecimal
+	numericDouble
+)
+
+// Holds reports whether e is true for a solution, as for a FILTER in SPARQL.
+// The expression holds if its effective boolean value is true: a boolean
+// literal is true if its value is, a numeric literal if it is neither zero
+// nor NaN and a string literal if it is not empty. If the expression cannot
+// be evaluated or has no effective boolean value it does not hold.
+func (e Expression) Holds(solution map[string]RdfTerm) bool {
+	b, err := e.boolean(solution)
+	return err == nil && b
+}
+
+// Evaluate returns the value of e for a solution. It returns
+// ErrInvalidExpression if e has an unknown operator or the wrong number of
+// arguments, ErrUnboundVariable if e uses a variable that is not bound in
+// the solution and ErrTypeMismatch if an operator is applied to terms of the
+// wrong type. The logical operators follow SPARQL in returning a value if
+// possible even when one of their arguments is an error.
+func (e Expression) Evaluate(solution map[string]RdfTerm) (RdfTerm, error) {
+	if e.Op == "" {
+		if e.Variable == "" {
+			return e.Term, nil
+		}
+		if t, ok := solution[e.Variable]; ok {
+			return t, nil
+		}
+		return RdfTerm{}, ErrUnboundVariable
+	}
+
+	if n, ok := opArgs[e.Op]; !ok || len(e.Args) < n[0] || len(e.Args) > n[1] {
+		return RdfTerm{}, ErrInvalidExpression
+	}
+	switch e.Op {
+	case "&&", "||":
+		// An error is ignored if the other argument decides the result
+		decisive := e.Op == "||"
+		b, err := e.Args[0].boolean(solution)
+		if err == nil && b == decisive {
+			return NewBoolLiteral(decisive), nil
+		}
+		c, err2 := e.Args[1].boolean(solution)
+		switch {
+		case err2 == nil && c == decisive:
+			return NewBoolLiteral(decisive), nil
+		case err != nil:
+			return RdfTerm{}, err
+		case err2 != nil:
+			return RdfTerm{}, err2
+		}
+		return NewBoolLiteral(!decisive), nil
+	case "bound":
+		v := e.Args[0]
+		if v.Op != "" || v.Variable == "" {
+			return RdfTerm{}, ErrInvalidExpression
+		}
+		_, ok := solution[v.Variable]
+		return NewBoolLiteral(ok), nil
+	case "!":
+		b, err := e.Args[0].boolean(solution)
+		if err != nil {
+			return RdfTerm{}, err
+		}
+		return NewBoolLiteral(!b), nil
+	}
+
+	args := make([]RdfTerm, len(e.Args))
+	for i, arg := range e.Args {
+		var err error
+		if args[i], err = arg.Evaluate(solution); err != nil {
+			return RdfTerm{}, err
+		}
+	}
+	t := args[0]
+
+	switch e.Op {
+	case "=":
+		return NewBoolLiteral(t.ValueEqual(args[1])), nil
+	case "!=":
+		return NewBoolLiteral(!t.ValueEqual(args[1])), nil
+	case "<", "<=", ">", ">=":
+		return compareOp(e.Op, t, args[1])
+	case "+", "-", "*", "/":
+		return arithmetic(e.Op, t, args[1])
+	case "regex":
+		return regex(args)
+	case "lang":
+		if !t.IsLiteral() {
+			return RdfTerm{}, ErrTypeMismatch
+		}
+		return NewLiteral(t.Language), nil
+	case "langmatches":
+		if literalCategory(t) != literalString || literalCategory(args[1]) != literalString {
+			return RdfTerm{}, ErrTypeMismatch
+		}
+		return NewBoolLiteral(langMatches(t.Value, args[1].Value)), nil
+	case "datatype":
+		if !t.IsLiteral() {
+			return RdfTerm{}, ErrTypeMismatch
+		}
+		return RdfTerm{Value: t.dataType(), TermType: RdfIri}, nil
+	case "str":
+		if !t.IsIRI() && !t.IsLiteral() {
+			return RdfTerm{}, ErrTypeMismatch
+		}
+		return NewLiteral(t.Value), nil
+	case "isiri", "isuri":
+		return NewBoolLiteral(t.IsIRI()), nil
+	case "isblank":
+		return NewBoolLiteral(t.IsBlank()), nil
+	case "isliteral":
+		return NewBoolLiteral(t.IsLiteral()), nil
+	case "isnumeric":
+		return NewBoolLiteral(t.IsLiteral() && literalCategory(t) == literalNumeric), nil
+	}
+	// sameterm
+	return NewBoolLiteral(t.Equal(args[1])), nil
+}
+
+// Variables returns the names of the variables used in e, in the order they
+// first appear.
+func (e Expression) Variables() []string {
+	var names []string
+	seen := map[string]bool{}
+	var walk func(e Expression)
+	walk = func(e Expression) {
+		if e.Op == "" && e.Variable != "" && !seen[e.Variable] {
+			seen[e.Variable] = true
+			names = append(names, e.Variable)
+		}
+		for _, arg := range e.Args {
+			walk(arg)
+		}
+	}
+	walk(e)
+	return names
+}
+
+// boolean returns the effective boolean value of e for a solution.
+func (e Expression) boolean(solution map[string]RdfTerm) (bool, error) {
+	t, err := e.Evaluate(solution)
+	if err != nil {
+		return false, err
+	}
+	if !t.IsLiteral() {
+		return false, ErrTypeMismatch
+	}
+
+	switch literalCategory(t) {
+	case literalBoolean:
+		return t.Bool()
+	case literalNumeric:
+		f, _ := t.Float64()
+		return f != 0 && !math.IsNaN(f), nil
+	case literalString:
+		return t.Value != "", nil
+	case literalOther:
+		// Invalid booleans and numbers are false
+		if _, ok := integerTypes[t.DataType]; ok || t.DataType == xsdBoolean || t.DataType == xsdDecimal || isFloatDataType(t.DataType) {
+			return false, nil
+		}
+	}
+	return false, ErrTypeMismatch
+}
+
+// compareOp applies one of the ordering operators to t and u, which must be
+// valid literals of the same category.
+func compareOp(op string, t, u RdfTerm) (RdfTerm, error) {
+	if !t.IsLiteral() || !u.IsLiteral() {
+		return RdfTerm{}, ErrTypeMismatch
+	}
+	switch c := literalCategory(t); {
+	case c != literalCategory(u), c == literalDuration, c == literalLangString, c == literalOther:
+		return RdfTerm{}, ErrTypeMismatch
+	case c == literalNumeric:
+		// NaN is not ordered
+		f, _ := t.Float64()
+		g, _ := u.Float64()
+		if math.IsNaN(f) || math.IsNaN(g) {
+			return NewBoolLiteral(false), nil
+		}
+	}
+
+	c := 0
+	if !t.ValueEqual(u) {
+		c = t.Compare(u)
+	}
+	switch op {
+	case "<":
+		return NewBoolLiteral(c < 0), nil
+	case "<=":
+		return NewBoolLiteral(c <= 0), nil
+	case ">":
+		return NewBoolLiteral(c > 0), nil
+	}
+	return NewBoolLiteral(c >= 0), nil
+}
+
+// arithmetic applies one of the arithmetic operators to the numbers t and u.
+// Integer division results in a decimal, which is rounded to 18 decimal
+// places if it cannot be represented exactly.
+func arithmetic(op string, t, u RdfTerm) (RdfTerm, error) {
+	if !t.IsLiteral() || !u.IsLiteral() || literalCategory(t) != literalNumeric || literalCategory(u) != literalNumeric {
+		return RdfTerm{}, ErrTypeMismatch
+	}
+
+	rank := numericRank(t)
+	if r := numericRank(u); r > rank {
+		rank = r
+	}
+	if rank == numericDouble {
+		f, _ := t.Float64()
+		g, _ := u.Float64()
+		switch op {
+		case "+":
+			f += g
+		case "-":
+			f -= g
+		case "*":
+			f *= g
+		default:
+			f /= g
+		}
+		return NewFloat64Literal(f), nil
+	}
+
+	r, _ := t.BigRat()
+	s, _ := u.BigRat()
+	switch op {
+	case "+":
+		r.Add(r, s)
+	case "-":
+		r.Sub(r, s)
+	case "*":
+		r.Mul(r, s)
+	default:
+		if s.Sign() == 0 {
+			return RdfTerm{}, ErrDivisionByZero
+		}
+		r.Quo(r, s)
+		rank = numericDecimal
+	}
+
+	if rank == numericInteger {
+		return NewBigIntLiteral(r.Num()), nil
+	}
+	d, err := NewBigRatLiteral(r)
+	if err != nil {
+		value := strings.TrimRight(r.FloatString(18), "0")
+		d = RdfTerm{Value: value, DataType: xsdDecimal, TermType: RdfLiteral}
+	}
+	return d, nil
+}
+
+// numericRank returns the rank of the datatype of the numeric literal t.
+func numericRank(t RdfTerm) int {
+	switch {
+	case isFloatDataType(t.DataType):
+		return numericDouble
+	case t.DataType == xsdDecimal:
+		return numericDecimal
+	}
+	return numericInteger
+}
+
+// regex applies the regex function to its arguments: a string, a pattern and
+// optional flags, which may be any of "imsq".
+func regex(args []RdfTerm) (RdfTerm, error) {
+	for _, arg := range args {
+		if c := literalCategory(arg); !arg.IsLiteral() || c != literalString && c != literalLangString {
+			return RdfTerm{}, ErrTypeMismatch
+		}
+	}
+
+	pattern := args[1].Value
+	if len(args) == 3 {
+		flags := args[2].Value
+		if strings.Contains(flags, "q") {
+			pattern = regexp.QuoteMeta(pattern)
+			flags = strings.Replace(flags, "q", "", -1)
+		}
+		for _, f := range flags {
+			if !strings.ContainsRune("ims", f) {
+				return RdfTerm{}, ErrInvalidExpression
+			}
+		}
+		if flags != "" {
+			pattern = "(?" + flags + ")" + pattern
+		}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return RdfTerm{}, ErrInvalidExpression
+	}
+	return NewBoolLiteral(re.MatchString(args[0].Value)), nil
+}
+
+// langMatches reports whether the language tag matches the language range,
+// using basic filtering as in RFC 4647. The range "*" matches any tag except
+// the empty tag of literals without a language.
+func langMatches(tag, langRange string) bool {
+	if langRange == "*" {
+		return tag != ""
+	}
+	tag, langRange = strings.ToLower(tag), strings.ToLower(langRange)
+	return tag == langRange || langRange != "" && strings.HasPrefix(tag, langRange+"-")
+}
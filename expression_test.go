@@ -0,0 +1,173 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"reflect"
+	"testing"
+)
+
+// call returns an expression that applies op to args.
+func call(op string, args ...Expression) Expression {
+	return Expression{Op: op, Args: args}
+}
+
+func TestExpressionEvaluate(t *testing.T) {
+	solution := map[string]RdfTerm{
+		"iri":   {Value: "http://example.org/", TermType: RdfIri},
+		"blank": {Value: "b0", TermType: RdfBlank},
+		"int":   {Value: "42", DataType: xsdInteger, TermType: RdfLiteral},
+		"name":  {Value: "chat", Language: "en-GB", TermType: RdfLiteral},
+	}
+	const (
+		yes = `"true"^^<http://www.w3.org/2001/XMLSchema#boolean>`
+		no  = `"false"^^<http://www.w3.org/2001/XMLSchema#boolean>`
+	)
+
+	cases := []struct {
+		expression Expression
+		expected   string
+	}{
+		{variable("iri"), `<http://example.org/>`},
+		{constant(`"chat"`), `"chat"`},
+
+		{call("=", variable("int"), constant(`"42.0"^^<http://www.w3.org/2001/XMLSchema#decimal>`)), yes},
+		{call("!=", variable("int"), constant(`"042"^^<http://www.w3.org/2001/XMLSchema#integer>`)), no},
+		{call("=", variable("name"), constant(`"chat"@EN-gb`)), yes},
+		{call("=", variable("iri"), variable("blank")), no},
+
+		{call("<", variable("int"), constant(`"1e2"^^<http://www.w3.org/2001/XMLSchema#double>`)), yes},
+		{call(">", variable("int"), constant(`"42.0"^^<http://www.w3.org/2001/XMLSchema#decimal>`)), no},
+		{call(">=", variable("int"), constant(`"42.0"^^<http://www.w3.org/2001/XMLSchema#decimal>`)), yes},
+		{call("<=", variable("int"), constant(`"NaN"^^<http://www.w3.org/2001/XMLSchema#double>`)), no},
+		{call("<", constant(`"abc"`), constant(`"abd"^^<http://www.w3.org/2001/XMLSchema#string>`)), yes},
+		{call("<", constant(`"2020-01-01"^^<http://www.w3.org/2001/XMLSchema#date>`), constant(`"2020-01-02"^^<http://www.w3.org/2001/XMLSchema#date>`)), yes},
+
+		{call("&&", variable("int"), constant(`"x"`)), yes},
+		{call("&&", constant(`""`), variable("missing")), no},
+		{call("||", variable("missing"), constant(`"1"^^<http://www.w3.org/2001/XMLSchema#integer>`)), yes},
+		{call("||", constant(`"0.0"^^<http://www.w3.org/2001/XMLSchema#double>`), constant(`"NaN"^^<http://www.w3.org/2001/XMLSchema#double>`)), no},
+		{call("!", constant(`"1"^^<http://www.w3.org/2001/XMLSchema#integer>`)), no},
+
+		{call("bound", variable("blank")), yes},
+		{call("bound", variable("missing")), no},
+		{call("lang", variable("name")), `"en-GB"`},
+		{call("lang", variable("int")), `""`},
+		{call("langmatches", call("lang", variable("name")), constant(`"EN"`)), yes},
+		{call("langmatches", call("lang", variable("name")), constant(`"en-US"`)), no},
+		{call("langmatches", call("lang", variable("name")), constant(`"*"`)), yes},
+		{call("langmatches", constant(`""`), constant(`"*"`)), no},
+
+		{call("datatype", variable("int")), `<http://www.w3.org/2001/XMLSchema#integer>`},
+		{call("datatype", variable("name")), `<http://www.w3.org/1999/02/22-rdf-syntax-ns#langString>`},
+		{call("datatype", constant(`"chat"`)), `<http://www.w3.org/2001/XMLSchema#string>`},
+		{call("str", variable("iri")), `"http://example.org/"`},
+		{call("str", variable("int")), `"42"`},
+		{call("isiri", variable("iri")), yes},
+		{call("isuri", variable("blank")), no},
+		{call("isblank", variable("blank")), yes},
+		{call("isliteral", variable("name")), yes},
+		{call("isnumeric", variable("int")), yes},
+		{call("isnumeric", constant(`"x"^^<http://www.w3.org/2001/XMLSchema#integer>`)), no},
+		{call("sameterm", variable("int"), constant(`"42.0"^^<http://www.w3.org/2001/XMLSchema#decimal>`)), no},
+		{call("sameterm", variable("name"), constant(`"chat"@en-GB`)), yes},
+
+		{call("+", variable("int"), constant(`"1"^^<http://www.w3.org/2001/XMLSchema#integer>`)), `"43"^^<http://www.w3.org/2001/XMLSchema#integer>`},
+		{call("-", variable("int"), constant(`"0.5"^^<http://www.w3.org/2001/XMLSchema#decimal>`)), `"41.5"^^<http://www.w3.org/2001/XMLSchema#decimal>`},
+		{call("*", variable("int"), constant(`"2"^^<http://www.w3.org/2001/XMLSchema#short>`)), `"84"^^<http://www.w3.org/2001/XMLSchema#integer>`},
+		{call("/", variable("int"), constant(`"8"^^<http://www.w3.org/2001/XMLSchema#integer>`)), `"5.25"^^<http://www.w3.org/2001/XMLSchema#decimal>`},
+		{call("/", constant(`"1"^^<http://www.w3.org/2001/XMLSchema#integer>`), constant(`"3"^^<http://www.w3.org/2001/XMLSchema#integer>`)), `"0.333333333333333333"^^<http://www.w3.org/2001/XMLSchema#decimal>`},
+		{call("/", variable("int"), constant(`"0"^^<http://www.w3.org/2001/XMLSchema#double>`)), `"INF"^^<http://www.w3.org/2001/XMLSchema#double>`},
+		{call("+", variable("int"), constant(`"1"^^<http://www.w3.org/2001/XMLSchema#float>`)), `"4.3E1"^^<http://www.w3.org/2001/XMLSchema#double>`},
+		{call("regex", variable("name"), constant(`"^ch"`)), yes},
+		{call("regex", variable("name"), constant(`"^CH"`)), no},
+		{call("regex", variable("name"), constant(`"^CH"`), constant(`"i"`)), yes},
+		{call("regex", constant(`"a.b"`), constant(`"."`), constant(`"q"`)), yes},
+		{call("regex", constant(`"ab"`), constant(`"."`), constant(`"q"`)), no},
+	}
+
+	for _, tc := range cases {
+		actual, err := tc.expression.Evaluate(solution)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %+v", err, tc.expression)
+			continue
+		}
+		if actual.String() != tc.expected {
+			t.Errorf("Expected %s for %+v but got %s", tc.expected, tc.expression, actual)
+		}
+	}
+
+	errorCases := []struct {
+		expression Expression
+		expected   error
+	}{
+		{variable("missing"), ErrUnboundVariable},
+		{call("=", variable("missing"), variable("int")), ErrUnboundVariable},
+		{call("&&", variable("missing"), variable("int")), ErrUnboundVariable},
+		{call("||", variable("iri"), constant(`""`)), ErrTypeMismatch},
+		{call("<", variable("int"), constant(`"42"`)), ErrTypeMismatch},
+		{call("<", variable("name"), variable("name")), ErrTypeMismatch},
+		{call("<", variable("iri"), variable("iri")), ErrTypeMismatch},
+		{call("lang", variable("iri")), ErrTypeMismatch},
+		{call("langmatches", variable("name"), constant(`"en"`)), ErrTypeMismatch},
+		{call("str", variable("blank")), ErrTypeMismatch},
+		{call("bound", constant(`"x"`)), ErrInvalidExpression},
+		{call("=", variable("int")), ErrInvalidExpression},
+		{call("ucase", variable("name")), ErrInvalidExpression},
+		{call("regex", variable("name"), constant(`"c"`), constant(`"x"`)), ErrInvalidExpression},
+		{call("regex", variable("name"), constant(`"("`)), ErrInvalidExpression},
+		{call("regex", variable("iri"), constant(`"c"`)), ErrTypeMismatch},
+		{call("+", variable("int"), constant(`"1"`)), ErrTypeMismatch},
+		{call("/", variable("int"), constant(`"0"^^<http://www.w3.org/2001/XMLSchema#integer>`)), ErrDivisionByZero},
+	}
+	for _, tc := range errorCases {
+		if _, err := tc.expression.Evaluate(solution); err != tc.expected {
+			t.Errorf("Expected %v for %+v but got %v", tc.expected, tc.expression, err)
+		}
+		if tc.expression.Holds(solution) {
+			t.Errorf("Expected %+v not to hold", tc.expression)
+		}
+	}
+}
+
+func TestExpressionHolds(t *testing.T) {
+	cases := map[string]bool{
+		`"true"^^<http://www.w3.org/2001/XMLSchema#boolean>`:  true,
+		`"false"^^<http://www.w3.org/2001/XMLSchema#boolean>`: false,
+		`"yes"^^<http://www.w3.org/2001/XMLSchema#boolean>`:   false,
+		`"0"^^<http://www.w3.org/2001/XMLSchema#integer>`:     false,
+		`"-1"^^<http://www.w3.org/2001/XMLSchema#integer>`:    true,
+		`"0.1"^^<http://www.w3.org/2001/XMLSchema#decimal>`:   true,
+		`"NaN"^^<http://www.w3.org/2001/XMLSchema#double>`:    false,
+		`"one"^^<http://www.w3.org/2001/XMLSchema#integer>`:   false,
+		`""`:  false,
+		`"x"`: true,
+		`"x"^^<http://www.w3.org/2001/XMLSchema#string>`: true,
+		`"x"@en`: false,
+		`"2020-01-01"^^<http://www.w3.org/2001/XMLSchema#date>`: false,
+		`<http://example.org/>`:                                 false,
+	}
+
+	for text, expected := range cases {
+		if actual := constant(text).Holds(nil); actual != expected {
+			t.Errorf("Expected %v for %s but got %v", expected, text, actual)
+		}
+	}
+}
+
+func TestExpressionVariables(t *testing.T) {
+	e := call("&&",
+		call("=", variable("b"), variable("a")),
+		call("||", call("bound", variable("c")), call("<", variable("a"), constant(`"1"`))),
+	)
+	expected := []string{"b", "a", "c"}
+	if actual := e.Variables(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+	if actual := constant(`"1"`).Variables(); actual != nil {
+		t.Errorf("Expected no variables but got %v", actual)
+	}
+}
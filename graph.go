@@ -0,0 +1,375 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+// A TripleSource is a sequence of triples, such as a Reader, TurtleReader,
+// ParallelReader or the result of Graph.Match. Next advances to the next triple, which
+// is returned by Triple, and returns false at the end of the sequence or on
+// an error, which is returned by Err.
+type TripleSource interface {
+	Next() bool
+	Triple() Triple
+	Err() error
+}
+
+// A Graph is a set of triples held in memory.
+//
+// Each distinct term is stored once and triples are indexed by subject,
+// predicate and object so that Match can find the triples with any
+// combination of known terms without scanning the whole graph. Terms are
+// compared as for RdfTerm.Equal, so a graph never holds two triples that are
+// Equal. Where equal terms are written differently, such as language tags
+// that differ in case, the graph keeps the first one added.
+//
+// The zero value is an empty graph ready to use. A Graph is not safe for
+// concurrent use if any goroutine modifies it.
+type Graph struct {
+	ids   map[string]int // term IDs keyed by the canonical N-Triples encoding of the term
+	terms []RdfTerm      // terms indexed by ID
+	keys  []string       // keys of ids indexed by ID
+	refs  []int          // number of times each term is used in the graph
+	free  []int          // IDs that are not in use
+
+	spo index
+	pos index
+	osp index
+	n   int
+
+	buf []byte // buffer for the keys of terms added by intern
+}
+
+// An index maps the IDs of the three terms of a triple, in the order given by
+// the name of the index, to the triples of a graph.
+type index map[int]map[int]map[int]struct{}
+
+// Orders of the terms of a triple in each index, as positions in the triple.
+var (
+	spoOrder = [3]int{posSubject, posPredicate, posObject}
+	posOrder = [3]int{posPredicate, posObject, posSubject}
+	ospOrder = [3]int{posObject, posSubject, posPredicate}
+)
+
+// Len returns the number of triples in g.
+func (g *Graph) Len() int {
+	return g.n
+}
+
+// Add adds t to g. The triple is validated first and is not added if it is
+// not a valid RDF triple, as for Triple.Validate. Triples that are already in
+// g are ignored.
+func (g *Graph) Add(t Triple) error {
+	if err := validateTriple(t); err != nil {
+		return err
+	}
+	if g.Has(t) {
+		return nil
+	}
+
+	if g.ids == nil {
+		g.ids = map[string]int{}
+		g.spo, g.pos, g.osp = index{}, index{}, index{}
+	}
+	s, p, o := g.intern(t.S), g.intern(t.P), g.intern(t.O)
+	g.spo.add(s, p, o)
+	g.pos.add(p, o, s)
+	g.osp.add(o, s, p)
+	g.n++
+	return nil
+}
+
+// AddFrom adds every triple read from src to g, as for Add. It stops at the
+// first triple that cannot be added or when src reports an error.
+func (g *Graph) AddFrom(src TripleSource) error {
+	for src.Next() {
+		if err := g.Add(src.Triple()); err != nil {
+			return err
+		}
+	}
+	return src.Err()
+}
+
+// Remove removes t from g. It reports whether t was in g.
+func (g *Graph) Remove(t Triple) bool {
+	s, p, o, ok := g.lookup(t)
+	if !ok || !g.spo.has(s, p, o) {
+		return false
+	}
+
+	g.spo.remove(s, p, o)
+	g.pos.remove(p, o, s)
+	g.osp.remove(o, s, p)
+	g.n--
+	g.release(s)
+	g.release(p)
+	g.release(o)
+	return true
+}
+
+// Has reports whether g contains t.
+func (g *Graph) Has(t Triple) bool {
+	s, p, o, ok := g.lookup(t)
+	return ok && g.spo.has(s, p, o)
+}
+
+// Match returns the triples in g that match a pattern, in no particular
+// order. A nil subject, predicate or object matches any term; otherwise the
+// term of the triple must be Equal to the one given. The Err method of the
+// result always returns nil.
+//
+// Triples that are removed from g while iterating are not returned if they
+// have not been reached. Triples that are added may or may not be returned.
+func (g *Graph) Match(s, p, o *RdfTerm) TripleSource {
+	it := &graphIterator{g: g}
+	var ok bool
+	if it.x, it.order, it.pattern, ok = g.plan(s, p, o); !ok {
+		return it
+	}
+
+	if it.pattern[0] >= 0 {
+		it.keys[0] = []int{it.pattern[0]}
+	} else {
+		for a := range it.x {
+			it.keys[0] = append(it.keys[0], a)
+		}
+	}
+	return it
+}
+
+// Count returns the number of triples in g that match a pattern, as for Match.
+func (g *Graph) Count(s, p, o *RdfTerm) int {
+	x, _, pattern, ok := g.plan(s, p, o)
+	if !ok {
+		return 0
+	}
+
+	// The known terms are always the leading terms of the index
+	a, b, c := pattern[0], pattern[1], pattern[2]
+	switch {
+	case c >= 0:
+		if x.has(a, b, c) {
+			return 1
+		}
+		return 0
+	case b >= 0:
+		return len(x[a][b])
+	case a >= 0:
+		n := 0
+		for _, cs := range x[a] {
+			n += len(cs)
+		}
+		return n
+	}
+	return g.n
+}
+
+// plan chooses the index x of g to use for the triples that match a pattern,
+// so that the known terms of the pattern are the leading terms of the index.
+// It returns the positions in the triple of the terms at each level of x and
+// the ID required at each level, or -1 for any term. It returns false if no
+// triple can match because a term is not in g.
+func (g *Graph) plan(s, p, o *RdfTerm) (x index, order, pattern [3]int, ok bool) {
+	var ids [3]int
+	for i, t := range [3]*RdfTerm{s, p, o} {
+		ids[i] = -1
+		if t == nil {
+			continue
+		}
+		if ids[i], ok = g.id(*t); !ok {
+			return
+		}
+	}
+
+	switch {
+	case s != nil && p != nil:
+		x, order = g.spo, spoOrder
+	case s != nil && o != nil:
+		x, order = g.osp, ospOrder
+	case s != nil:
+		x, order = g.spo, spoOrder
+	case p != nil:
+		x, order = g.pos, posOrder
+	case o != nil:
+		x, order = g.osp, ospOrder
+	default:
+		x, order = g.spo, spoOrder
+	}
+
+	for level, i := range order {
+		pattern[level] = ids[i]
+	}
+	return x, order, pattern, true
+}
+
+// A graphIterator iterates over the triples of a Graph that match a pattern.
+type graphIterator struct {
+	g       *Graph
+	x       index
+	order   [3]int   // position in the triple of the term at each level of x
+	pattern [3]int   // the ID required at each level of x, or -1 for any term
+	keys    [3][]int // IDs that remain to be visited at each level of x
+	cur     [3]int   // IDs of the current triple at each level of x
+	t       Triple
+}
+
+// Next advances to the next matching triple. It returns false when there are
+// no more triples.
+func (it *graphIterator) Next() bool {
+	for {
+		switch {
+		case len(it.keys[2]) > 0:
+			it.cur[2] = it.pop(2)
+			if !it.x.has(it.cur[0], it.cur[1], it.cur[2]) {
+				continue
+			}
+
+			var ids [3]int
+			for level, i := range it.order {
+				ids[i] = it.cur[level]
+			}
+			it.t = Triple{S: it.g.terms[ids[0]], P: it.g.terms[ids[1]], O: it.g.terms[ids[2]]}
+			return true
+
+		case len(it.keys[1]) > 0:
+			it.cur[1] = it.pop(1)
+			if it.pattern[2] >= 0 {
+				it.keys[2] = append(it.keys[2], it.pattern[2])
+				continue
+			}
+			for c := range it.x[it.cur[0]][it.cur[1]] {
+				it.keys[2] = append(it.keys[2], c)
+			}
+
+		case len(it.keys[0]) > 0:
+			it.cur[0] = it.pop(0)
+			if it.pattern[1] >= 0 {
+				it.keys[1] = append(it.keys[1], it.pattern[1])
+				continue
+			}
+			for b := range it.x[it.cur[0]] {
+				it.keys[1] = append(it.keys[1], b)
+			}
+
+		default:
+			it.t = Triple{}
+			return false
+		}
+	}
+}
+
+// pop removes and returns the last ID to be visited at level.
+func (it *graphIterator) pop(level int) int {
+	keys := it.keys[level]
+	id := keys[len(keys)-1]
+	it.keys[level] = keys[:len(keys)-1]
+	return id
+}
+
+// Triple returns the current triple.
+func (it *graphIterator) Triple() Triple {
+	return it.t
+}
+
+// Err returns nil.
+func (it *graphIterator) Err() error {
+	return nil
+}
+
+// appendKey appends to dst the canonical N-Triples encoding of t, which is
+// the same for terms that are Equal.
+func appendKey(dst []byte, t RdfTerm) []byte {
+	dst, _ = appendTerm(dst, canonicalTerm(t))
+	return dst
+}
+
+// id returns the ID of the term in g that is Equal to t, if any. The key is
+// built in a local buffer so that goroutines can look up terms concurrently.
+func (g *Graph) id(t RdfTerm) (int, bool) {
+	var buf [128]byte
+	id, ok := g.ids[string(appendKey(buf[:0], t))]
+	return id, ok
+}
+
+// lookup returns the IDs of the terms of t, if they are all in g.
+func (g *Graph) lookup(t Triple) (s, p, o int, ok bool) {
+	if s, ok = g.id(t.S); !ok {
+		return
+	}
+	if p, ok = g.id(t.P); !ok {
+		return
+	}
+	o, ok = g.id(t.O)
+	return
+}
+
+// intern returns the ID of t, adding t to the terms of g if necessary, and
+// counts a new use of the term.
+func (g *Graph) intern(t RdfTerm) int {
+	g.buf = appendKey(g.buf[:0], t)
+	id, ok := g.ids[string(g.buf)]
+	if !ok {
+		key := string(g.buf)
+		if n := len(g.free); n > 0 {
+			id = g.free[n-1]
+			g.free = g.free[:n-1]
+			g.terms[id], g.keys[id] = t, key
+		} else {
+			id = len(g.terms)
+			g.terms = append(g.terms, t)
+			g.keys = append(g.keys, key)
+			g.refs = append(g.refs, 0)
+		}
+		g.ids[key] = id
+	}
+	g.refs[id]++
+	return id
+}
+
+// release counts the end of a use of the term with the given ID, removing
+// the term from g once it is no longer used.
+func (g *Graph) release(id int) {
+	g.refs[id]--
+	if g.refs[id] > 0 {
+		return
+	}
+	delete(g.ids, g.keys[id])
+	g.terms[id], g.keys[id] = RdfTerm{}, ""
+	g.free = append(g.free, id)
+}
+
+// add adds the triple with the IDs a, b and c to x.
+func (x index) add(a, b, c int) {
+	bs := x[a]
+	if bs == nil {
+		bs = map[int]map[int]struct{}{}
+		x[a] = bs
+	}
+	cs := bs[b]
+	if cs == nil {
+		cs = map[int]struct{}{}
+		bs[b] = cs
+	}
+	cs[c] = struct{}{}
+}
+
+// remove removes the triple with the IDs a, b and c from x, along with any
+// maps that become empty.
+func (x index) remove(a, b, c int) {
+	bs := x[a]
+	cs := bs[b]
+	delete(cs, c)
+	if len(cs) == 0 {
+		delete(bs, b)
+		if len(bs) == 0 {
+			delete(x, a)
+		}
+	}
+}
+
+// has reports whether x contains the triple with the IDs a, b and c.
+func (x index) has(a, b, c int) bool {
+	_, ok := x[a][b][c]
+	return ok
+}
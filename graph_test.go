@@ -0,0 +1,281 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const graphDocument = `<http://example.org/a> <http://example.org/knows> <http://example.org/b> .
+<http://example.org/a> <http://example.org/knows> <http://example.org/c> .
+<http://example.org/a> <http://example.org/name> "A"@en .
+<http://example.org/b> <http://example.org/knows> <http://example.org/c> .
+<http://example.org/b> <http://example.org/name> "B" .
+<http://example.org/c> <http://example.org/knows> <http://example.org/a> .
+<http://example.org/c> <http://example.org/name> "C"^^<http://www.w3.org/2001/XMLSchema#string> .
+_:x <http://example.org/knows> <http://example.org/a> .
+<< <http://example.org/a> <http://example.org/knows> <http://example.org/b> >> <http://example.org/source> <http://example.org/doc> .
+`
+
+// readQuads returns the quads of an N-Quads document.
+func readQuads(t *testing.T, document string) []Quad {
+	var quads []Quad
+	r := NewQuadReader(strings.NewReader(document))
+	for r.Next() {
+		quads = append(quads, r.Quad())
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	return quads
+}
+
+// readTriples returns the triples of an N-Triples document.
+func readTriples(t *testing.T, document string) []Triple {
+	var triples []Triple
+	r := NewReader(strings.NewReader(document))
+	for r.Next() {
+		triples = append(triples, r.Triple())
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	return triples
+}
+
+// newTestGraph returns the graph of an N-Triples document.
+func newTestGraph(t *testing.T, document string) *Graph {
+	var g Graph
+	if err := g.AddFrom(NewReader(strings.NewReader(document))); err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	return &g
+}
+
+// readTestGraph returns the graph of graphDocument and its triples.
+func readTestGraph(t *testing.T) (*Graph, []Triple) {
+	return newTestGraph(t, graphDocument), readTriples(t, graphDocument)
+}
+
+// sortedStrings returns the N-Triples encoding of each triple from src in sorted order.
+func sortedStrings(src TripleSource) []string {
+	var lines []string
+	for src.Next() {
+		lines = append(lines, src.Triple().String())
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestGraphAdd(t *testing.T) {
+	g, triples := readTestGraph(t)
+	if g.Len() != len(triples) {
+		t.Fatalf("Expected %d triples but got %d", len(triples), g.Len())
+	}
+
+	for _, triple := range triples {
+		if !g.Has(triple) {
+			t.Errorf("Expected graph to contain %s", triple)
+		}
+	}
+
+	// Equal triples are only added once
+	equivalent := []string{
+		`<http://example.org/a> <http://example.org/name> "A"@EN .`,
+		`<http://example.org/b> <http://example.org/name> "B"^^<http://www.w3.org/2001/XMLSchema#string> .`,
+		`<http://example.org/c> <http://example.org/name> "C" .`,
+		`<< <http://example.org/a> <http://example.org/knows> <http://example.org/b> >> <http://example.org/source> <http://example.org/doc> .`,
+	}
+	for _, text := range equivalent {
+		triple, err := ParseTriple(text)
+		if err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+		if !g.Has(triple) {
+			t.Errorf("Expected graph to contain %s", triple)
+		}
+		if err := g.Add(triple); err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		}
+	}
+	if g.Len() != len(triples) {
+		t.Errorf("Expected %d triples but got %d", len(triples), g.Len())
+	}
+
+	// The first of equal terms is kept
+	name := RdfTerm{Value: "http://example.org/name", TermType: RdfIri}
+	it := g.Match(nil, &name, &RdfTerm{Value: "A", Language: "EN", TermType: RdfLiteral})
+	if !it.Next() || it.Triple().O.Language != "en" {
+		t.Errorf("Expected the language tag en but got %s", it.Triple())
+	}
+
+	invalid := Triple{S: NewLiteral("a"), P: name, O: NewLiteral("b")}
+	if err := g.Add(invalid); err != ErrInvalidSubject {
+		t.Errorf("Expected %v but got %v", ErrInvalidSubject, err)
+	}
+	if g.Has(invalid) {
+		t.Errorf("Expected graph not to contain %s", invalid)
+	}
+}
+
+func TestGraphRemove(t *testing.T) {
+	g, triples := readTestGraph(t)
+
+	for i, triple := range triples {
+		if !g.Remove(triple) {
+			t.Errorf("Expected %s to be removed", triple)
+		}
+		if g.Remove(triple) {
+			t.Errorf("Expected %s to be removed only once", triple)
+		}
+		if g.Has(triple) {
+			t.Errorf("Expected graph not to contain %s", triple)
+		}
+		if g.Len() != len(triples)-i-1 {
+			t.Errorf("Expected %d triples but got %d", len(triples)-i-1, g.Len())
+		}
+	}
+
+	// Terms that are no longer used are released
+	if len(g.ids) != 0 || len(g.spo) != 0 || len(g.pos) != 0 || len(g.osp) != 0 {
+		t.Errorf("Expected an empty graph but got %d terms", len(g.ids))
+	}
+
+	for _, triple := range triples {
+		if err := g.Add(triple); err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+	}
+	if g.Len() != len(triples) || len(g.terms) != len(g.ids) {
+		t.Errorf("Expected the IDs of released terms to be reused but got %d IDs for %d terms", len(g.terms), len(g.ids))
+	}
+}
+
+func TestGraphMatch(t *testing.T) {
+	g, triples := readTestGraph(t)
+
+	terms := []*RdfTerm{
+		nil,
+		{Value: "http://example.org/a", TermType: RdfIri},
+		{Value: "http://example.org/c", TermType: RdfIri},
+		{Value: "http://example.org/knows", TermType: RdfIri},
+		{Value: "http://example.org/name", TermType: RdfIri},
+		{Value: "x", TermType: RdfBlank},
+		{Value: "C", TermType: RdfLiteral},
+		{Value: "http://example.org/missing", TermType: RdfIri},
+		{TermType: RdfTriple, Triple: &triples[0]},
+	}
+
+	matches := func(pattern, term *RdfTerm) bool {
+		return pattern == nil || pattern.Equal(*term)
+	}
+
+	for _, s := range terms {
+		for _, p := range terms {
+			for _, o := range terms {
+				var expected []string
+				for _, triple := range triples {
+					if matches(s, &triple.S) && matches(p, &triple.P) && matches(o, &triple.O) {
+						expected = append(expected, triple.String())
+					}
+				}
+				sort.Strings(expected)
+
+				actual := sortedStrings(g.Match(s, p, o))
+				if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+					t.Errorf("Expected %v for %v %v %v but got %v", expected, s, p, o, actual)
+				}
+				if n := g.Count(s, p, o); n != len(expected) {
+					t.Errorf("Expected %d for %v %v %v but got %d", len(expected), s, p, o, n)
+				}
+			}
+		}
+	}
+}
+
+func TestGraphMatchRemove(t *testing.T) {
+	g, _ := readTestGraph(t)
+
+	knows := RdfTerm{Value: "http://example.org/knows", TermType: RdfIri}
+	it := g.Match(nil, &knows, nil)
+	n := 0
+	for it.Next() {
+		if !g.Remove(it.Triple()) {
+			t.Errorf("Expected %s to be removed", it.Triple())
+		}
+		n++
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 triples but got %d", n)
+	}
+	if actual := sortedStrings(g.Match(nil, &knows, nil)); len(actual) != 0 {
+		t.Errorf("Expected no triples but got %v", actual)
+	}
+
+	// Triples removed before they are reached are not returned
+	g, _ = readTestGraph(t)
+	it = g.Match(nil, nil, nil)
+	if !it.Next() {
+		t.Fatalf("Expected a triple")
+	}
+	for _, triple := range sortedStrings(g.Match(nil, nil, nil)) {
+		parsed, _ := ParseTriple(triple)
+		g.Remove(parsed)
+	}
+	if it.Next() {
+		t.Errorf("Expected no more triples but got %s", it.Triple())
+	}
+}
+
+func TestGraphAddFrom(t *testing.T) {
+	g, _ := readTestGraph(t)
+
+	var copied Graph
+	if err := copied.AddFrom(g.Match(nil, nil, nil)); err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	if expected, actual := sortedStrings(g.Match(nil, nil, nil)), sortedStrings(copied.Match(nil, nil, nil)); strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+
+	err := copied.AddFrom(NewReader(strings.NewReader("<http://example.org/s> <http://example.org/p> .\n")))
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("Expected a ParseError but got %v", err)
+	}
+}
+
+func TestGraphConcurrentReads(t *testing.T) {
+	g, triples := readTestGraph(t)
+
+	// Reads do not modify the graph, so they may run concurrently. Run with
+	// -race to detect any shared state.
+	var wg sync.WaitGroup
+	errs := make(chan string, len(triples))
+	for _, triple := range triples {
+		wg.Add(1)
+		go func(triple Triple) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if !g.Has(triple) {
+					errs <- "Expected graph to have " + triple.String()
+					return
+				}
+				if n := len(sortedStrings(g.Match(&triple.S, &triple.P, nil))); n == 0 || g.Count(&triple.S, &triple.P, nil) != n {
+					errs <- "Expected Count to match Match for " + triple.String()
+					return
+				}
+			}
+		}(triple)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,468 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"net"
+	"strings"
+	"unicode/utf8"
+)
+
+// iriParts holds the five components of an IRI reference as defined by
+// RFC 3986 section 3. A component may be present but empty, so each
+// optional component has a flag recording whether it was defined.
+type iriParts struct {
+	scheme       string
+	authority    string
+	path         string
+	query        string
+	fragment     string
+	hasScheme    bool
+	hasAuthority bool
+	hasQuery     bool
+	hasFragment  bool
+}
+
+// splitIRI splits an IRI reference into its components.
+func splitIRI(s string) iriParts {
+	var p iriParts
+
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		p.fragment, p.hasFragment = s[i+1:], true
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		p.query, p.hasQuery = s[i+1:], true
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, ':'); i > 0 && isScheme(s[:i]) {
+		p.scheme, p.hasScheme = s[:i], true
+		s = s[i+1:]
+	}
+	if strings.HasPrefix(s, "//") {
+		s = s[2:]
+		i := strings.IndexByte(s, '/')
+		if i < 0 {
+			i = len(s)
+		}
+		p.authority, p.hasAuthority = s[:i], true
+		s = s[i:]
+	}
+	p.path = s
+
+	return p
+}
+
+// String recomposes the components into an IRI reference following
+// RFC 3986 section 5.3.
+func (p iriParts) String() string {
+	var b strings.Builder
+	if p.hasScheme {
+		b.WriteString(p.scheme)
+		b.WriteByte(':')
+	}
+	if p.hasAuthority {
+		b.WriteString("//")
+		b.WriteString(p.authority)
+	}
+	b.WriteString(p.path)
+	if p.hasQuery {
+		b.WriteByte('?')
+		b.WriteString(p.query)
+	}
+	if p.hasFragment {
+		b.WriteByte('#')
+		b.WriteString(p.fragment)
+	}
+	return b.String()
+}
+
+// resolveIRI resolves the IRI reference ref against the absolute IRI base
+// using the algorithm in RFC 3986 section 5.2.
+func resolveIRI(base, ref string) string {
+	r := splitIRI(ref)
+	if r.hasScheme {
+		r.path = removeDotSegments(r.path)
+		return r.String()
+	}
+
+	b := splitIRI(base)
+	t := iriParts{
+		scheme:      b.scheme,
+		hasScheme:   b.hasScheme,
+		fragment:    r.fragment,
+		hasFragment: r.hasFragment,
+	}
+
+	switch {
+	case r.hasAuthority:
+		t.authority, t.hasAuthority = r.authority, true
+		t.path = removeDotSegments(r.path)
+		t.query, t.hasQuery = r.query, r.hasQuery
+	case r.path == "":
+		t.authority, t.hasAuthority = b.authority, b.hasAuthority
+		t.path = b.path
+		if r.hasQuery {
+			t.query, t.hasQuery = r.query, true
+		} else {
+			t.query, t.hasQuery = b.query, b.hasQuery
+		}
+	default:
+		t.authority, t.hasAuthority = b.authority, b.hasAuthority
+		if strings.HasPrefix(r.path, "/") {
+			t.path = removeDotSegments(r.path)
+		} else {
+			t.path = removeDotSegments(mergePaths(b, r.path))
+		}
+		t.query, t.hasQuery = r.query, r.hasQuery
+	}
+
+	return t.String()
+}
+
+// mergePaths merges a relative path with the path of base as described in
+// RFC 3986 section 5.2.3.
+func mergePaths(base iriParts, path string) string {
+	if base.hasAuthority && base.path == "" {
+		return "/" + path
+	}
+	i := strings.LastIndexByte(base.path, '/')
+	return base.path[:i+1] + path
+}
+
+// removeDotSegments removes the special "." and ".." segments from path as
+// described in RFC 3986 section 5.2.4.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	in := path
+	out := make([]byte, 0, len(path))
+	for len(in) > 0 {
+		switch {
+		case strings.HasPrefix(in, "../"):
+			in = in[3:]
+		case strings.HasPrefix(in, "./"):
+			in = in[2:]
+		case strings.HasPrefix(in, "/./"):
+			in = in[2:]
+		case in == "/.":
+			in = "/"
+		case strings.HasPrefix(in, "/../"):
+			in = in[3:]
+			out = removeLastSegment(out)
+		case in == "/..":
+			in = "/"
+			out = removeLastSegment(out)
+		case in == "." || in == "..":
+			in = ""
+		default:
+			i := strings.IndexByte(in[1:], '/') + 1
+			if i == 0 {
+				i = len(in)
+			}
+			out = append(out, in[:i]...)
+			in = in[i:]
+		}
+	}
+	return string(out)
+}
+
+// removeLastSegment removes the last segment of path and its preceding '/', if any.
+func removeLastSegment(path []byte) []byte {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			return path[:i]
+		}
+	}
+	return path[:0]
+}
+
+// isAbsoluteIRI reports whether s begins with a scheme followed by ':'.
+func isAbsoluteIRI(s string) bool {
+	i := strings.IndexByte(s, ':')
+	return i > 0 && isScheme(s[:i])
+}
+
+// isScheme reports whether s matches ALPHA *( ALPHA / DIGIT / "+" / "-" / "." )
+func isScheme(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := rune(s[i])
+		if !isAlpha(c) && (i == 0 || !(isDigit(c) || c == '+' || c == '-' || c == '.')) {
+			return false
+		}
+	}
+	return true
+}
+
+// An IRI is an Internationalized Resource Identifier, or a reference to one,
+// as defined by RFC 3987.
+type IRI string
+
+// IsAbsolute reports whether i begins with a scheme, as required for the
+// IRIs in an N-Triples document.
+func (i IRI) IsAbsolute() bool {
+	return isAbsoluteIRI(string(i))
+}
+
+// Validate checks that i is an absolute IRI that conforms to the syntax of
+// RFC 3987. It returns ErrRelativeIri if i has no scheme and ErrMalformedIri
+// if it does not match the grammar.
+func (i IRI) Validate() error {
+	if !i.IsAbsolute() {
+		return ErrRelativeIri
+	}
+	if !isIRIReference(string(i)) {
+		return ErrMalformedIri
+	}
+	return nil
+}
+
+// Normalize returns i with the syntax-based normalizations of RFC 3987
+// section 5.3.2 applied: ASCII letters in the scheme and host are
+// lowercased, the hex digits of percent-encodings are uppercased,
+// percent-encoded unreserved characters are decoded and, for absolute IRIs,
+// dot segments are removed from the path. IRIs that are equivalent under
+// these rules normalize to the same string. Neither Unicode normalization nor
+// Unicode case folding is applied. ErrMalformedIri is returned if i is not a
+// valid IRI reference.
+func (i IRI) Normalize() (IRI, error) {
+	if !isIRIReference(string(i)) {
+		return "", ErrMalformedIri
+	}
+
+	p := splitIRI(string(i))
+	p.scheme = strings.ToLower(p.scheme)
+	if p.hasAuthority {
+		userinfo, host, port := splitAuthority(p.authority)
+		p.authority = lowerASCII(normalizePercentEncoding(host)) + port
+		if userinfo != "" {
+			p.authority = normalizePercentEncoding(userinfo) + p.authority
+		}
+	}
+	p.path = normalizePercentEncoding(p.path)
+	if p.hasScheme {
+		p.path = removeDotSegments(p.path)
+	}
+	p.query = normalizePercentEncoding(p.query)
+	p.fragment = normalizePercentEncoding(p.fragment)
+
+	return IRI(p.String()), nil
+}
+
+// ResolveIRI resolves the IRI reference ref against the absolute IRI base
+// using the algorithm of RFC 3986 section 5.2, returning an absolute IRI. It
+// returns ErrRelativeIri if base has no scheme. Neither IRI is otherwise
+// validated, so ResolveIRI can be used to repair documents that contain
+// relative IRIs.
+func ResolveIRI(base, ref string) (string, error) {
+	if !isAbsoluteIRI(base) {
+		return "", ErrRelativeIri
+	}
+	return resolveIRI(base, ref), nil
+}
+
+// lowerASCII returns s with the ASCII letters lowercased, except in the hex
+// digits of percent-encodings, which are left as they are. Other characters
+// are not case folded.
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '%':
+			i += 2
+		case c >= 'A' && c <= 'Z':
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// splitAuthority splits an authority into its userinfo, including the
+// trailing '@', host and port, including the leading ':'.
+func splitAuthority(s string) (userinfo, host, port string) {
+	if i := strings.LastIndexByte(s, '@'); i >= 0 {
+		userinfo, s = s[:i+1], s[i+1:]
+	}
+	host = s
+	if i := strings.LastIndexByte(s, ':'); i >= 0 && !strings.Contains(s[i:], "]") {
+		host, port = s[:i], s[i:]
+	}
+	return userinfo, host, port
+}
+
+// isIRIReference reports whether s matches the IRI-reference production of RFC 3987.
+func isIRIReference(s string) bool {
+	p := splitIRI(s)
+
+	if p.hasAuthority {
+		userinfo, host, port := splitAuthority(p.authority)
+		if !isIRIComponent(strings.TrimSuffix(userinfo, "@"), ":", false) || !isIRIHost(host) {
+			return false
+		}
+		for _, c := range strings.TrimPrefix(port, ":") {
+			if !isDigit(c) {
+				return false
+			}
+		}
+	} else if !p.hasScheme {
+		// The first segment of a relative path may not contain ':'
+		segment := p.path
+		if i := strings.IndexByte(segment, '/'); i >= 0 {
+			segment = segment[:i]
+		}
+		if strings.Contains(segment, ":") {
+			return false
+		}
+	}
+
+	return isIRIComponent(p.path, ":@/", false) &&
+		isIRIComponent(p.query, ":@/?", true) &&
+		isIRIComponent(p.fragment, ":@/?", false)
+}
+
+// isIRIHost reports whether s matches the ihost production of RFC 3987.
+func isIRIHost(s string) bool {
+	if !strings.HasPrefix(s, "[") {
+		// An IPv4 address also matches ireg-name
+		return isIRIComponent(s, "", false)
+	}
+	if !strings.HasSuffix(s, "]") {
+		return false
+	}
+
+	s = s[1 : len(s)-1]
+	if s == "" || (s[0] != 'v' && s[0] != 'V') {
+		return strings.Contains(s, ":") && net.ParseIP(s) != nil
+	}
+
+	// IPvFuture = "v" 1*HEXDIG "." 1*( unreserved / sub-delims / ":" )
+	i := strings.IndexByte(s, '.')
+	if i < 2 || i == len(s)-1 {
+		return false
+	}
+	for _, c := range s[1:i] {
+		if _, ok := hexValue(c); !ok {
+			return false
+		}
+	}
+	for _, c := range s[i+1:] {
+		if c >= utf8.RuneSelf || c == '%' || !isIRIUnreservedOrSubDelim(c) && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// isIRIComponent reports whether s consists of characters matching
+// iunreserved, pct-encoded or sub-delims, or any of the characters in extra.
+// If private is true, characters matching iprivate are also allowed.
+func isIRIComponent(s, extra string, private bool) bool {
+	for i := 0; i < len(s); {
+		r1, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r1 == '%':
+			if i+2 >= len(s) {
+				return false
+			}
+			if _, ok := hexValue(rune(s[i+1])); !ok {
+				return false
+			}
+			if _, ok := hexValue(rune(s[i+2])); !ok {
+				return false
+			}
+			size = 3
+		case r1 == utf8.RuneError && size == 1:
+			return false
+		case isIRIUnreservedOrSubDelim(r1), strings.ContainsRune(extra, r1):
+		case private && isIRIPrivate(r1):
+		default:
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+// isIRIUnreservedOrSubDelim reports whether r1 matches the iunreserved or
+// sub-delims productions of RFC 3987.
+func isIRIUnreservedOrSubDelim(r1 rune) bool {
+	switch {
+	case isAlpha(r1), isDigit(r1):
+		return true
+	case r1 < utf8.RuneSelf:
+		return strings.ContainsRune("-._~!$&'()*+,;=", r1)
+	}
+	return isUcsChar(r1)
+}
+
+// isIRIUnreserved reports whether r1 matches the iunreserved production of RFC 3987.
+func isIRIUnreserved(r1 rune) bool {
+	return isAlpha(r1) || isDigit(r1) || r1 == '-' || r1 == '.' || r1 == '_' || r1 == '~' || isUcsChar(r1)
+}
+
+// isUcsChar reports whether r1 matches the ucschar production of RFC 3987.
+func isUcsChar(r1 rune) bool {
+	switch {
+	case r1 >= 0xA0 && r1 <= 0xD7FF,
+		r1 >= 0xF900 && r1 <= 0xFDCF,
+		r1 >= 0xFDF0 && r1 <= 0xFFEF:
+		return true
+	case r1 >= 0x10000 && r1 <= 0xEFFFD:
+		// Each plane excludes its last two code points
+		return r1&0xFFFF <= 0xFFFD
+	}
+	return false
+}
+
+// isIRIPrivate reports whether r1 matches the iprivate production of RFC 3987.
+func isIRIPrivate(r1 rune) bool {
+	return r1 >= 0xE000 && r1 <= 0xF8FF || r1 >= 0xF0000 && r1 <= 0x10FFFD && r1&0xFFFF <= 0xFFFD
+}
+
+// normalizePercentEncoding uppercases the hex digits of the percent-encodings
+// in s and decodes any that encode iunreserved characters.
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		// Decode the longest run of percent-encoded octets that may form a character
+		var octets []byte
+		j := i
+		for j+2 < len(s) && s[j] == '%' && len(octets) < utf8.UTFMax {
+			hi, _ := hexValue(rune(s[j+1]))
+			lo, _ := hexValue(rune(s[j+2]))
+			octets = append(octets, byte(hi<<4|lo))
+			j += 3
+			if utf8.FullRune(octets) {
+				break
+			}
+		}
+
+		r1, size := utf8.DecodeRune(octets)
+		if r1 != utf8.RuneError && size == len(octets) && isIRIUnreserved(r1) {
+			b.WriteRune(r1)
+		} else {
+			b.WriteString(strings.ToUpper(s[i : i+3]))
+			j = i + 3
+		}
+		i = j
+	}
+	return b.String()
+}
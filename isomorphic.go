@@ -0,0 +1,286 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import "sort"
+
+// Isomorphic reports whether the graphs a and b are isomorphic, that is
+// whether there is a one-to-one mapping between their blank nodes that makes
+// the two sets of triples equal. Duplicate triples are ignored and terms are
+// compared as for RdfTerm.Equal. Blank nodes in quoted triples are mapped in
+// the same way as any others.
+//
+// If the graphs are isomorphic, Isomorphic also returns the mapping it found
+// from the labels of the blank nodes in a to the labels of those in b.
+//
+// Blank nodes are first partitioned by hashing the triples around them until
+// no further nodes can be told apart. Nodes that are still indistinguishable,
+// as in graphs with symmetries, are then paired by backtracking.
+func Isomorphic(a, b []Triple) (bool, map[string]string) {
+	ga, gb := newIsoGraph(a), newIsoGraph(b)
+	if len(ga.ground) != len(gb.ground) || len(ga.triples) != len(gb.triples) || len(ga.nodes) != len(gb.nodes) {
+		return false, nil
+	}
+	for key := range ga.ground {
+		if !gb.ground[key] {
+			return false, nil
+		}
+	}
+
+	ca, cb := make([]uint64, len(ga.nodes)), make([]uint64, len(gb.nodes))
+	if !refineColors(ga, gb, ca, cb) {
+		return false, nil
+	}
+	return ga.match(gb, ca, cb)
+}
+
+// An isoGraph is a graph prepared for comparison by Isomorphic.
+type isoGraph struct {
+	ground  map[string]bool // canonical encodings of the triples without blank nodes
+	triples []Triple        // distinct triples with blank nodes
+	keys    map[string]bool // canonical encodings of triples
+	nodes   []string        // blank node labels in order of first use
+	ids     map[string]int  // indexes of nodes keyed by label
+	uses    [][]int         // indexes of the triples that use each node
+}
+
+// newIsoGraph returns the isoGraph of triples.
+func newIsoGraph(triples []Triple) *isoGraph {
+	g := &isoGraph{
+		ground: map[string]bool{},
+		keys:   map[string]bool{},
+		ids:    map[string]int{},
+	}
+	for _, t := range triples {
+		key := t.CanonicalString()
+		var labels []string
+		labels = blankLabels(labels, t)
+		if len(labels) == 0 {
+			g.ground[key] = true
+			continue
+		}
+		if g.keys[key] {
+			continue
+		}
+		g.keys[key] = true
+
+		i := len(g.triples)
+		g.triples = append(g.triples, t)
+		for _, label := range labels {
+			id, ok := g.ids[label]
+			if !ok {
+				id = len(g.nodes)
+				g.ids[label] = id
+				g.nodes = append(g.nodes, label)
+				g.uses = append(g.uses, nil)
+			}
+			if uses := g.uses[id]; len(uses) == 0 || uses[len(uses)-1] != i {
+				g.uses[id] = append(uses, i)
+			}
+		}
+	}
+	return g
+}
+
+// blankLabels appends the labels of the blank nodes in t, including those in
+// quoted triples, to labels.
+func blankLabels(labels []string, t Triple) []string {
+	for _, term := range [3]RdfTerm{t.S, t.P, t.O} {
+		labels = termBlankLabels(labels, term)
+	}
+	return labels
+}
+
+// termBlankLabels appends to labels the label of t if it is a blank node, or
+// of each blank node in it if it is a quoted triple.
+func termBlankLabels(labels []string, t RdfTerm) []string {
+	switch {
+	case t.TermType == RdfBlank:
+		labels = append(labels, t.Value)
+	case t.TermType == RdfTriple && t.Triple != nil:
+		labels = blankLabels(labels, *t.Triple)
+	}
+	return labels
+}
+
+// refine returns the colors of the nodes of g after one round of refinement
+// starting from colors. The new color of a node combines its old color with
+// the hashes of the triples that use it, in which the node itself and every
+// other blank node are replaced by their colors.
+func (g *isoGraph) refine(colors []uint64) []uint64 {
+	refined := make([]uint64, len(colors))
+	var hashes []uint64
+	for id, uses := range g.uses {
+		hashes = hashes[:0]
+		for _, i := range uses {
+			hashes = append(hashes, g.hashTriple(fnvOffset64, g.triples[i], id, colors))
+		}
+		sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+		h := hashUint(fnvOffset64, colors[id])
+		for _, v := range hashes {
+			h = hashUint(h, v)
+		}
+		refined[id] = h
+	}
+	return refined
+}
+
+// hashTriple combines the hash of t with h, replacing each blank node by its
+// color and marking the node self.
+func (g *isoGraph) hashTriple(h uint64, t Triple, self int, colors []uint64) uint64 {
+	for _, term := range [3]RdfTerm{t.S, t.P, t.O} {
+		switch {
+		case term.TermType == RdfBlank:
+			id := g.ids[term.Value]
+			h = hashUint(h, uint64(RdfBlank))
+			if id == self {
+				h = hashUint(h, 0)
+			} else {
+				h = hashUint(hashUint(h, 1), colors[id])
+			}
+		case term.TermType == RdfTriple && term.Triple != nil:
+			h = hashUint(h, uint64(RdfTriple))
+			h = g.hashTriple(h, *term.Triple, self, colors)
+		default:
+			h = term.hash(h)
+		}
+	}
+	return h
+}
+
+// refineColors refines the colors ca of the nodes of a and cb of the nodes
+// of b in step until the partition of the nodes into colors is stable. It
+// reports false if at any point the colors of a and b differ, in which case
+// the graphs are not isomorphic.
+func refineColors(a, b *isoGraph, ca, cb []uint64) bool {
+	classes := countColors(ca)
+	for {
+		ra, rb := a.refine(ca), b.refine(cb)
+		if !sameColors(ra, rb) {
+			return false
+		}
+		copy(ca, ra)
+		copy(cb, rb)
+		n := countColors(ca)
+		if n == classes {
+			return true
+		}
+		classes = n
+	}
+}
+
+// countColors returns the number of distinct colors.
+func countColors(colors []uint64) int {
+	seen := map[uint64]bool{}
+	for _, c := range colors {
+		seen[c] = true
+	}
+	return len(seen)
+}
+
+// sameColors reports whether ca and cb hold the same colors the same number
+// of times.
+func sameColors(ca, cb []uint64) bool {
+	counts := map[uint64]int{}
+	for _, c := range ca {
+		counts[c]++
+	}
+	for _, c := range cb {
+		if counts[c] == 0 {
+			return false
+		}
+		counts[c]--
+	}
+	return true
+}
+
+// match finds a mapping from the nodes of g to the nodes of h that pairs
+// nodes of the same color and maps the triples of g onto those of h. Nodes
+// that share a color are paired in turn, giving each pair a new color and
+// refining until the choice leads to a mapping or is shown to fail.
+func (g *isoGraph) match(h *isoGraph, ca, cb []uint64) (bool, map[string]string) {
+	// Choose the smallest class with more than one node, preferring the
+	// lowest color so that the search does not depend on the order of nodes
+	counts := map[uint64]int{}
+	for _, c := range ca {
+		counts[c]++
+	}
+	var color uint64
+	size := 0
+	for c, n := range counts {
+		if n > 1 && (size == 0 || n < size || n == size && c < color) {
+			color, size = c, n
+		}
+	}
+
+	if size == 0 {
+		ids := map[uint64]int{}
+		for id, c := range cb {
+			ids[c] = id
+		}
+		mapping := make(map[string]string, len(g.nodes))
+		for id, c := range ca {
+			mapping[g.nodes[id]] = h.nodes[ids[c]]
+		}
+		if !g.maps(h, mapping) {
+			return false, nil
+		}
+		return true, mapping
+	}
+
+	x := -1
+	for id, c := range ca {
+		if c == color {
+			x = id
+			break
+		}
+	}
+	unique := hashUint(hashUint(fnvOffset64, color), uint64(size))
+	for y, c := range cb {
+		if c != color {
+			continue
+		}
+		na, nb := append([]uint64(nil), ca...), append([]uint64(nil), cb...)
+		na[x], nb[y] = unique, unique
+		if !refineColors(g, h, na, nb) {
+			continue
+		}
+		if ok, mapping := g.match(h, na, nb); ok {
+			return true, mapping
+		}
+	}
+	return false, nil
+}
+
+// maps reports whether relabelling the blank nodes of g by mapping gives the
+// triples of h.
+func (g *isoGraph) maps(h *isoGraph, mapping map[string]string) bool {
+	relabel := func(label string) string { return mapping[label] }
+	for _, t := range g.triples {
+		if !h.keys[relabelTriple(t, relabel).CanonicalString()] {
+			return false
+		}
+	}
+	return true
+}
+
+// relabelTriple returns t with each blank node relabelled by relabel,
+// including those in quoted triples.
+func relabelTriple(t Triple, relabel func(label string) string) Triple {
+	return Triple{S: relabelTerm(t.S, relabel), P: relabelTerm(t.P, relabel), O: relabelTerm(t.O, relabel)}
+}
+
+// relabelTerm returns t relabelled by relabel, as for relabelTriple.
+func relabelTerm(t RdfTerm, relabel func(label string) string) RdfTerm {
+	switch {
+	case t.TermType == RdfBlank:
+		t.Value = relabel(t.Value)
+	case t.TermType == RdfTriple && t.Triple != nil:
+		triple := relabelTriple(*t.Triple, relabel)
+		t.Triple = &triple
+	}
+	return t
+}
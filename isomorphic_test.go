@@ -0,0 +1,178 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"testing"
+)
+
+func TestIsomorphic(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"", "", true},
+		{
+			a:        "<http://example.org/a> <http://example.org/p> \"x\" .\n",
+			b:        "<http://example.org/a> <http://example.org/p> \"x\" .\n<http://example.org/a> <http://example.org/p> \"x\" .\n",
+			expected: true,
+		},
+		{
+			a:        "<http://example.org/a> <http://example.org/p> \"x\"@en .\n",
+			b:        "<http://example.org/a> <http://example.org/p> \"x\"@EN .\n",
+			expected: true,
+		},
+		{
+			a:        "<http://example.org/a> <http://example.org/p> \"x\" .\n",
+			b:        "<http://example.org/a> <http://example.org/p> \"y\" .\n",
+			expected: false,
+		},
+		{
+			a: `_:a <http://example.org/name> "A" .
+_:a <http://example.org/knows> _:b .
+_:b <http://example.org/name> "B" .
+`,
+			b: `_:x <http://example.org/knows> _:y .
+_:y <http://example.org/name> "B" .
+_:x <http://example.org/name> "A" .
+`,
+			expected: true,
+		},
+		{
+			a: `_:a <http://example.org/knows> _:b .
+_:b <http://example.org/knows> _:a .
+`,
+			b: `_:x <http://example.org/knows> _:x .
+`,
+			expected: false,
+		},
+		{
+			// Nodes that are told apart only by their neighbours' neighbours
+			a: `_:a <http://example.org/p> _:b .
+_:b <http://example.org/p> _:c .
+_:c <http://example.org/q> "end" .
+`,
+			b: `_:z <http://example.org/q> "end" .
+_:y <http://example.org/p> _:z .
+_:x <http://example.org/p> _:y .
+`,
+			expected: true,
+		},
+		{
+			a: `_:a <http://example.org/p> _:b .
+_:b <http://example.org/p> _:c .
+_:c <http://example.org/q> "end" .
+`,
+			b: `_:x <http://example.org/p> _:y .
+_:y <http://example.org/p> _:z .
+_:x <http://example.org/q> "end" .
+`,
+			expected: false,
+		},
+		{
+			// A cycle of six nodes, which hashing alone cannot pair up
+			a: `_:a <http://example.org/next> _:b .
+_:b <http://example.org/next> _:c .
+_:c <http://example.org/next> _:d .
+_:d <http://example.org/next> _:e .
+_:e <http://example.org/next> _:f .
+_:f <http://example.org/next> _:a .
+`,
+			b: `_:f4 <http://example.org/next> _:f5 .
+_:f2 <http://example.org/next> _:f3 .
+_:f0 <http://example.org/next> _:f1 .
+_:f5 <http://example.org/next> _:f0 .
+_:f3 <http://example.org/next> _:f4 .
+_:f1 <http://example.org/next> _:f2 .
+`,
+			expected: true,
+		},
+		{
+			// Two cycles of three nodes are not one cycle of six
+			a: `_:a <http://example.org/next> _:b .
+_:b <http://example.org/next> _:c .
+_:c <http://example.org/next> _:d .
+_:d <http://example.org/next> _:e .
+_:e <http://example.org/next> _:f .
+_:f <http://example.org/next> _:a .
+`,
+			b: `_:a <http://example.org/next> _:b .
+_:b <http://example.org/next> _:c .
+_:c <http://example.org/next> _:a .
+_:d <http://example.org/next> _:e .
+_:e <http://example.org/next> _:f .
+_:f <http://example.org/next> _:d .
+`,
+			expected: false,
+		},
+		{
+			// Symmetric nodes may be paired either way
+			a: `_:a <http://example.org/p> <http://example.org/o> .
+_:b <http://example.org/p> <http://example.org/o> .
+`,
+			b: `_:x <http://example.org/p> <http://example.org/o> .
+_:y <http://example.org/p> <http://example.org/o> .
+`,
+			expected: true,
+		},
+		{
+			a: `_:a <http://example.org/p> <http://example.org/o> .
+_:b <http://example.org/p> <http://example.org/o> .
+`,
+			b: `_:x <http://example.org/p> <http://example.org/o> .
+`,
+			expected: false,
+		},
+		{
+			a: `<< _:a <http://example.org/p> _:b >> <http://example.org/source> _:a .
+`,
+			b: `<< _:x <http://example.org/p> _:y >> <http://example.org/source> _:x .
+`,
+			expected: true,
+		},
+		{
+			a: `<< _:a <http://example.org/p> _:b >> <http://example.org/source> _:a .
+`,
+			b: `<< _:x <http://example.org/p> _:y >> <http://example.org/source> _:y .
+`,
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		a, b := readTriples(t, tc.a), readTriples(t, tc.b)
+		actual, mapping := Isomorphic(a, b)
+		if actual != tc.expected {
+			t.Errorf("Expected %v for %q and %q but got %v", tc.expected, tc.a, tc.b, actual)
+			continue
+		}
+		if !actual {
+			if mapping != nil {
+				t.Errorf("Expected no mapping but got %v", mapping)
+			}
+			continue
+		}
+
+		// Relabelling a by the mapping must give b
+		relabelled := map[string]bool{}
+		relabel := func(label string) string { return mapping[label] }
+		for _, triple := range a {
+			relabelled[relabelTriple(triple, relabel).CanonicalString()] = true
+		}
+		for _, triple := range b {
+			if !relabelled[triple.CanonicalString()] {
+				t.Errorf("Expected %s in %q relabelled by %v", triple, tc.a, mapping)
+			}
+		}
+		targets := map[string]bool{}
+		for _, label := range mapping {
+			targets[label] = true
+		}
+		if len(targets) != len(mapping) {
+			t.Errorf("Expected a one-to-one mapping but got %v", mapping)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidJSONTerm is returned when unmarshaling JSON that does not
+// describe an RDF term, triple or quad.
+var ErrInvalidJSONTerm = errors.New("JSON value is not an RDF term")
+
+// jsonTerm is the representation of an RDF term used by the SPARQL 1.1 Query
+// Results JSON Format.
+type jsonTerm struct {
+	Type     string          `json:"type"`
+	Value    json.RawMessage `json:"value"`
+	Language string          `json:"xml:lang,omitempty"`
+	DataType string          `json:"datatype,omitempty"`
+}
+
+// Values of the type member of a jsonTerm.
+const (
+	jsonIRI          = "uri"
+	jsonBlank        = "bnode"
+	jsonLiteral      = "literal"
+	jsonTypedLiteral = "typed-literal" // used by the earlier SPARQL Query Results JSON Format
+	jsonTripleTerm   = "triple"        // used by SPARQL 1.2 for quoted triples
+)
+
+// MarshalJSON implements the json.Marshaler interface. The term is encoded as
+// in the SPARQL 1.1 Query Results JSON Format, for example
+// {"type":"uri","value":"http://example.org/"} or
+// {"type":"literal","value":"chat","xml:lang":"en"}. A quoted triple is
+// encoded as in SPARQL 1.2 with the type "triple" and a value encoded as for
+// Triple.MarshalJSON. A term of type RdfUnknown is encoded as null.
+func (t RdfTerm) MarshalJSON() ([]byte, error) {
+	var j jsonTerm
+	var err error
+	switch t.TermType {
+	case RdfUnknown:
+		return []byte("null"), nil
+	case RdfTriple:
+		if t.Triple == nil {
+			return nil, ErrInvalidTriple
+		}
+		j.Type = jsonTripleTerm
+		j.Value, err = t.Triple.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(j)
+	case RdfIri:
+		j.Type = jsonIRI
+	case RdfBlank:
+		j.Type = jsonBlank
+	case RdfLiteral:
+		j.Type = jsonLiteral
+		j.Language = t.Language
+		if t.Language == "" {
+			j.DataType = t.DataType
+		} else if t.DataType != "" && t.DataType != rdfLangString {
+			return nil, ErrInvalidLiteral
+		}
+	default:
+		return nil, ErrUnknownTermType
+	}
+
+	if j.Value, err = json.Marshal(t.Value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The term is decoded
+// from the SPARQL 1.1 Query Results JSON Format, as for MarshalJSON. The
+// "typed-literal" type of the earlier format is also accepted. Unmarshaling
+// null leaves the term unchanged.
+func (t *RdfTerm) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var j jsonTerm
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if len(j.Value) == 0 || string(j.Value) == "null" {
+		return ErrInvalidJSONTerm
+	}
+
+	if j.Type == jsonTripleTerm {
+		var triple Triple
+		if err := triple.UnmarshalJSON(j.Value); err != nil {
+			return err
+		}
+		*t = RdfTerm{TermType: RdfTriple, Triple: &triple}
+		return nil
+	}
+
+	var term RdfTerm
+	if err := json.Unmarshal(j.Value, &term.Value); err != nil {
+		return ErrInvalidJSONTerm
+	}
+	switch j.Type {
+	case jsonIRI:
+		term.TermType = RdfIri
+	case jsonBlank:
+		term.TermType = RdfBlank
+	case jsonLiteral, jsonTypedLiteral:
+		term.TermType = RdfLiteral
+		term.Language = j.Language
+		term.DataType = j.DataType
+		if j.Language != "" && j.DataType != "" {
+			if j.DataType != rdfLangString {
+				return ErrInvalidLiteral
+			}
+			term.DataType = ""
+		}
+	default:
+		return ErrUnknownTermType
+	}
+
+	*t = term
+	return nil
+}
+
+// jsonTriple is the representation of a triple or quad as a JSON object.
+type jsonTriple struct {
+	Subject   RdfTerm  `json:"subject"`
+	Predicate RdfTerm  `json:"predicate"`
+	Object    RdfTerm  `json:"object"`
+	Graph     *RdfTerm `json:"graph,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. The triple is encoded
+// as an object with subject, predicate and object members, each holding a
+// term encoded as for RdfTerm.MarshalJSON.
+func (t Triple) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonTriple{Subject: t.S, Predicate: t.P, Object: t.O})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The triple is
+// decoded from an object as for MarshalJSON. All three terms must be present.
+func (t *Triple) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var j jsonTriple
+	if err := j.unmarshal(data); err != nil {
+		return err
+	}
+	*t = Triple{S: j.Subject, P: j.Predicate, O: j.Object}
+	return nil
+}
+
+// unmarshal decodes data into j, checking that the subject, predicate and
+// object are present.
+func (j *jsonTriple) unmarshal(data []byte) error {
+	if err := json.Unmarshal(data, j); err != nil {
+		return err
+	}
+	if j.Subject.TermType == RdfUnknown || j.Predicate.TermType == RdfUnknown || j.Object.TermType == RdfUnknown {
+		return ErrInvalidJSONTerm
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface. The quad is encoded as
+// for Triple.MarshalJSON with an additional graph member, which is omitted
+// for quads in the default graph.
+func (q Quad) MarshalJSON() ([]byte, error) {
+	j := jsonTriple{Subject: q.S, Predicate: q.P, Object: q.O}
+	if !q.InDefaultGraph() {
+		j.Graph = &q.G
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The quad is
+// decoded from an object as for MarshalJSON. If the graph member is missing
+// the quad is in the default graph.
+func (q *Quad) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var j jsonTriple
+	if err := j.unmarshal(data); err != nil {
+		return err
+	}
+
+	*q = Quad{Triple: Triple{S: j.Subject, P: j.Predicate, O: j.Object}}
+	if j.Graph != nil {
+		q.G = *j.Graph
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestTermMarshalJSON(t *testing.T) {
+	cases := map[string]RdfTerm{
+		`{"type":"uri","value":"http://example.org/"}`:                                                   {Value: "http://example.org/", TermType: RdfIri},
+		`{"type":"bnode","value":"b0"}`:                                                                  {Value: "b0", TermType: RdfBlank},
+		`{"type":"literal","value":""}`:                                                                  {Value: "", TermType: RdfLiteral},
+		`{"type":"literal","value":"chat","xml:lang":"en"}`:                                              {Value: "chat", Language: "en", TermType: RdfLiteral},
+		`{"type":"literal","value":"1","datatype":"http://www.w3.org/2001/XMLSchema#integer"}`:           {Value: "1", DataType: xsdInteger, TermType: RdfLiteral},
+		`{"type":"literal","value":"\"quoted\"\n","datatype":"http://www.w3.org/2001/XMLSchema#string"}`: {Value: "\"quoted\"\n", DataType: xsdString, TermType: RdfLiteral},
+		`null`: {},
+	}
+
+	for expected, term := range cases {
+		data, err := json.Marshal(term)
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+			continue
+		}
+		if string(data) != expected {
+			t.Errorf("Expected %s but got %s", expected, data)
+		}
+
+		var actual RdfTerm
+		if err := json.Unmarshal(data, &actual); err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if actual != term {
+			t.Errorf("Expected %#v but got %#v", term, actual)
+		}
+	}
+
+	errorCases := map[error]RdfTerm{
+		ErrInvalidLiteral:  {Value: "chat", Language: "en", DataType: xsdString, TermType: RdfLiteral},
+		ErrUnknownTermType: {Value: "chat", TermType: 42},
+	}
+	for expected, term := range errorCases {
+		if _, err := term.MarshalJSON(); err != expected {
+			t.Errorf("Expected %v but got %v", expected, err)
+		}
+	}
+}
+
+func TestTermUnmarshalJSON(t *testing.T) {
+	cases := map[string]RdfTerm{
+		`{"type":"typed-literal","value":"1","datatype":"http://www.w3.org/2001/XMLSchema#integer"}`: {Value: "1", DataType: xsdInteger, TermType: RdfLiteral},
+		`{"type":"literal","value":"chat","xml:lang":"en","datatype":"` + rdfLangString + `"}`:       {Value: "chat", Language: "en", TermType: RdfLiteral},
+		`{"value":"http://example.org/","type":"uri","extra":true}`:                                  {Value: "http://example.org/", TermType: RdfIri},
+	}
+
+	for data, expected := range cases {
+		var actual RdfTerm
+		if err := json.Unmarshal([]byte(data), &actual); err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, data)
+		} else if actual != expected {
+			t.Errorf("Expected %#v but got %#v", expected, actual)
+		}
+	}
+
+	errorCases := map[string]error{
+		`{"type":"uri"}`: ErrInvalidJSONTerm,
+		`{"type":"iri","value":"http://example.org/"}`:                                     ErrUnknownTermType,
+		`{"type":"literal","value":"chat","xml:lang":"en","datatype":"` + xsdString + `"}`: ErrInvalidLiteral,
+		`{"subject":{"type":"uri","value":"http://example.org/"}}`:                         ErrInvalidJSONTerm,
+	}
+
+	for data, expected := range errorCases {
+		var actual RdfTerm
+		if err := json.Unmarshal([]byte(data), &actual); err != expected {
+			t.Errorf("Expected %v for %s but got %v", expected, data, err)
+		}
+	}
+
+	var triple Triple
+	if err := json.Unmarshal([]byte(`{"subject":{"type":"bnode","value":"a"},"predicate":{"type":"uri","value":"http://example.org/p"}}`), &triple); err != ErrInvalidJSONTerm {
+		t.Errorf("Expected %v but got %v", ErrInvalidJSONTerm, err)
+	}
+}
+
+func TestTripleMarshalJSON(t *testing.T) {
+	for _, triple := range testCases {
+		data, err := json.Marshal(triple)
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+			continue
+		}
+
+		var actual Triple
+		if err := json.Unmarshal(data, &actual); err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if actual != triple {
+			t.Errorf("Expected %#v but got %#v", triple, actual)
+		}
+	}
+
+	triple := Triple{
+		S: RdfTerm{Value: "a", TermType: RdfBlank},
+		P: RdfTerm{Value: rdfType, TermType: RdfIri},
+		O: RdfTerm{Value: "chat", Language: "en", TermType: RdfLiteral},
+	}
+	expected := `{"subject":{"type":"bnode","value":"a"},"predicate":{"type":"uri","value":"` + rdfType + `"},"object":{"type":"literal","value":"chat","xml:lang":"en"}}`
+	if data, err := json.Marshal(triple); err != nil || string(data) != expected {
+		t.Errorf("Expected %s but got %s, %v", expected, data, err)
+	}
+}
+
+func TestQuadMarshalJSON(t *testing.T) {
+	triple := Triple{
+		S: RdfTerm{Value: "a", TermType: RdfBlank},
+		P: RdfTerm{Value: rdfType, TermType: RdfIri},
+		O: RdfTerm{Value: "b", TermType: RdfBlank},
+	}
+	prefix := `{"subject":{"type":"bnode","value":"a"},"predicate":{"type":"uri","value":"` + rdfType + `"},"object":{"type":"bnode","value":"b"}`
+	cases := map[string]Quad{
+		prefix + `}`: {Triple: triple},
+		prefix + `,"graph":{"type":"uri","value":"http://example.org/g"}}`: {Triple: triple, G: RdfTerm{Value: "http://example.org/g", TermType: RdfIri}},
+	}
+
+	for expected, quad := range cases {
+		data, err := json.Marshal(quad)
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+			continue
+		}
+		if string(data) != expected {
+			t.Errorf("Expected %s but got %s", expected, data)
+		}
+
+		var actual Quad
+		if err := json.Unmarshal(data, &actual); err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if actual != quad {
+			t.Errorf("Expected %#v but got %#v", quad, actual)
+		}
+	}
+}
+
+func TestQuotedTripleMarshalJSON(t *testing.T) {
+	term := quote(
+		RdfTerm{Value: "a", TermType: RdfBlank},
+		RdfTerm{Value: rdfType, TermType: RdfIri},
+		quote(RdfTerm{Value: "b", TermType: RdfBlank}, RdfTerm{Value: rdfType, TermType: RdfIri}, NewLiteral("c")),
+	)
+	expected := `{"type":"triple","value":{"subject":{"type":"bnode","value":"a"},"predicate":{"type":"uri","value":"` + rdfType + `"},` +
+		`"object":{"type":"triple","value":{"subject":{"type":"bnode","value":"b"},"predicate":{"type":"uri","value":"` + rdfType + `"},"object":{"type":"literal","value":"c"}}}}}`
+
+	data, err := json.Marshal(term)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("Expected %s but got %s", expected, data)
+	}
+
+	var actual RdfTerm
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Errorf("Got unexpected error %v", err)
+	} else if !actual.Equal(term) {
+		t.Errorf("Expected %s but got %s", term, actual)
+	}
+
+	if _, err := json.Marshal(RdfTerm{TermType: RdfTriple}); !errors.Is(err, ErrInvalidTriple) {
+		t.Errorf("Expected %v but got %v", ErrInvalidTriple, err)
+	}
+	if err := json.Unmarshal([]byte(`{"type":"triple","value":"a"}`), &actual); err == nil {
+		t.Errorf("Expected an error but got %s", actual)
+	}
+}
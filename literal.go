@@ -0,0 +1,814 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A LiteralError is returned when the value of a literal cannot be converted.
+type LiteralError struct {
+	Value    string // Lexical form of the literal
+	DataType string // Datatype IRI of the literal
+	Err      error  // The actual error
+}
+
+func (e *LiteralError) Error() string {
+	return fmt.Sprintf("literal %q with datatype <%s>: %s", e.Value, e.DataType, e.Err)
+}
+
+// Unwrap returns the underlying error so that LiteralError can be used with errors.Is.
+func (e *LiteralError) Unwrap() error {
+	return e.Err
+}
+
+// These are the errors that can be returned in LiteralError.Err
+var (
+	ErrDatatypeMismatch = errors.New("datatype cannot be converted to the requested type")
+	ErrIllTypedLiteral  = errors.New("lexical form is not valid for the datatype")
+	ErrNotRepresentable = errors.New("value cannot be represented by the requested type")
+)
+
+// Datatypes from XML Schema that can be converted to Go values, in addition
+// to xsdString, xsdBoolean, xsdInteger, xsdDecimal and xsdDouble.
+const (
+	xsdFloat              = xsdNamespace + "float"
+	xsdLong               = xsdNamespace + "long"
+	xsdInt                = xsdNamespace + "int"
+	xsdShort              = xsdNamespace + "short"
+	xsdByte               = xsdNamespace + "byte"
+	xsdNonNegativeInteger = xsdNamespace + "nonNegativeInteger"
+	xsdPositiveInteger    = xsdNamespace + "positiveInteger"
+	xsdNonPositiveInteger = xsdNamespace + "nonPositiveInteger"
+	xsdNegativeInteger    = xsdNamespace + "negativeInteger"
+	xsdUnsignedLong       = xsdNamespace + "unsignedLong"
+	xsdUnsignedInt        = xsdNamespace + "unsignedInt"
+	xsdUnsignedShort      = xsdNamespace + "unsignedShort"
+	xsdUnsignedByte       = xsdNamespace + "unsignedByte"
+	xsdDate               = xsdNamespace + "date"
+	xsdDateTime           = xsdNamespace + "dateTime"
+	xsdDateTimeStamp      = xsdNamespace + "dateTimeStamp"
+	xsdDuration           = xsdNamespace + "duration"
+	xsdDayTimeDuration    = xsdNamespace + "dayTimeDuration"
+	xsdYearMonthDuration  = xsdNamespace + "yearMonthDuration"
+)
+
+// Other built-in datatypes from XML Schema whose lexical forms can be validated.
+const (
+	xsdTime             = xsdNamespace + "time"
+	xsdGYear            = xsdNamespace + "gYear"
+	xsdGYearMonth       = xsdNamespace + "gYearMonth"
+	xsdGMonth           = xsdNamespace + "gMonth"
+	xsdGMonthDay        = xsdNamespace + "gMonthDay"
+	xsdGDay             = xsdNamespace + "gDay"
+	xsdHexBinary        = xsdNamespace + "hexBinary"
+	xsdBase64Binary     = xsdNamespace + "base64Binary"
+	xsdAnyURI           = xsdNamespace + "anyURI"
+	xsdNormalizedString = xsdNamespace + "normalizedString"
+	xsdToken            = xsdNamespace + "token"
+	xsdLanguage         = xsdNamespace + "language"
+	xsdNMTOKEN          = xsdNamespace + "NMTOKEN"
+	xsdName             = xsdNamespace + "Name"
+	xsdNCName           = xsdNamespace + "NCName"
+
+	rdfLangString = rdfNamespace + "langString"
+)
+
+// integerTypes maps xsd:integer and the datatypes derived from it to the
+// range of values they allow. A nil bound means the range is unbounded.
+var integerTypes = map[string][2]*big.Int{
+	xsdInteger:            {nil, nil},
+	xsdLong:               {big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)},
+	xsdInt:                {big.NewInt(math.MinInt32), big.NewInt(math.MaxInt32)},
+	xsdShort:              {big.NewInt(math.MinInt16), big.NewInt(math.MaxInt16)},
+	xsdByte:               {big.NewInt(math.MinInt8), big.NewInt(math.MaxInt8)},
+	xsdNonNegativeInteger: {big.NewInt(0), nil},
+	xsdPositiveInteger:    {big.NewInt(1), nil},
+	xsdNonPositiveInteger: {nil, big.NewInt(0)},
+	xsdNegativeInteger:    {nil, big.NewInt(-1)},
+	xsdUnsignedLong:       {big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)},
+	xsdUnsignedInt:        {big.NewInt(0), big.NewInt(math.MaxUint32)},
+	xsdUnsignedShort:      {big.NewInt(0), big.NewInt(math.MaxUint16)},
+	xsdUnsignedByte:       {big.NewInt(0), big.NewInt(math.MaxUint8)},
+}
+
+// Regular expressions for the lexical spaces of the datatypes that can be converted.
+var (
+	xsdIntegerLexical  = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	xsdDecimalLexical  = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+	xsdDoubleLexical   = regexp.MustCompile(`^([+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?|[+-]?INF|NaN)$`)
+	xsdDateLexical     = regexp.MustCompile(`^(-?[0-9]{4,})-([0-9]{2})-([0-9]{2})(Z|[+-][0-9]{2}:[0-9]{2})?$`)
+	xsdDateTimeLexical = regexp.MustCompile(`^(-?[0-9]{4,})-([0-9]{2})-([0-9]{2})T([0-9]{2}):([0-9]{2}):([0-9]{2})(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})?$`)
+	xsdDurationLexical = regexp.MustCompile(`^(-)?P(?:([0-9]+)Y)?(?:([0-9]+)M)?(?:([0-9]+)D)?(?:T(?:([0-9]+)H)?(?:([0-9]+)M)?(?:([0-9]+(?:\.[0-9]*)?|\.[0-9]+)S)?)?$`)
+)
+
+// A Duration is the value of an xsd:duration literal. The months and the
+// days and time of a duration are kept separately since the length of a
+// month varies. Both have the same sign.
+type Duration struct {
+	Months  int64         // Years and months, as a number of months
+	DayTime time.Duration // Days, hours, minutes and seconds
+}
+
+// literalError creates a new LiteralError for t based on err.
+func (t RdfTerm) literalError(err error) error {
+	return &LiteralError{Value: t.Value, DataType: t.DataType, Err: err}
+}
+
+// BigInt returns the value of a literal whose datatype is xsd:integer or is
+// derived from it, such as xsd:int or xsd:nonNegativeInteger.
+func (t RdfTerm) BigInt() (*big.Int, error) {
+	bounds, ok := integerTypes[t.DataType]
+	if !t.IsLiteral() || !ok {
+		return nil, t.literalError(ErrDatatypeMismatch)
+	}
+
+	i, ok := parseInteger(t.Value)
+	if !ok || (bounds[0] != nil && i.Cmp(bounds[0]) < 0) || (bounds[1] != nil && i.Cmp(bounds[1]) > 0) {
+		return nil, t.literalError(ErrIllTypedLiteral)
+	}
+	return i, nil
+}
+
+// Int64 returns the value of a literal whose datatype is xsd:integer or is
+// derived from it, as for BigInt. ErrNotRepresentable is returned if the
+// value does not fit in an int64.
+func (t RdfTerm) Int64() (int64, error) {
+	i, err := t.BigInt()
+	if err != nil {
+		return 0, err
+	}
+	if !i.IsInt64() {
+		return 0, t.literalError(ErrNotRepresentable)
+	}
+	return i.Int64(), nil
+}
+
+// BigRat returns the exact value of a literal whose datatype is xsd:decimal,
+// xsd:double, xsd:float or an integer datatype. ErrNotRepresentable is
+// returned for the special floating point values INF, -INF and NaN.
+func (t RdfTerm) BigRat() (*big.Rat, error) {
+	if !t.IsLiteral() {
+		return nil, t.literalError(ErrDatatypeMismatch)
+	}
+
+	switch t.DataType {
+	case xsdDecimal:
+		r, ok := parseDecimal(t.Value)
+		if !ok {
+			return nil, t.literalError(ErrIllTypedLiteral)
+		}
+		return r, nil
+	case xsdDouble, xsdFloat:
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, t.literalError(ErrNotRepresentable)
+		}
+		return new(big.Rat).SetFloat64(f), nil
+	}
+
+	i, err := t.BigInt()
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetInt(i), nil
+}
+
+// Float64 returns the value of a literal whose datatype is xsd:double,
+// xsd:float, xsd:decimal or an integer datatype. Values of xsd:float are
+// rounded to single precision. ErrNotRepresentable is returned if a decimal
+// or integer is too large for a float64.
+func (t RdfTerm) Float64() (float64, error) {
+	if !t.IsLiteral() {
+		return 0, t.literalError(ErrDatatypeMismatch)
+	}
+
+	switch t.DataType {
+	case xsdDouble, xsdFloat:
+		if !xsdDoubleLexical.MatchString(t.Value) {
+			return 0, t.literalError(ErrIllTypedLiteral)
+		}
+
+		bitSize := 64
+		if t.DataType == xsdFloat {
+			bitSize = 32
+		}
+
+		switch strings.TrimPrefix(t.Value, "+") {
+		case "INF":
+			return math.Inf(1), nil
+		case "-INF":
+			return math.Inf(-1), nil
+		case "NaN":
+			return math.NaN(), nil
+		}
+
+		// Values too large for the type round to infinity
+		f, _ := strconv.ParseFloat(t.Value, bitSize)
+		return f, nil
+	case xsdDecimal:
+		if !xsdDecimalLexical.MatchString(t.Value) {
+			return 0, t.literalError(ErrIllTypedLiteral)
+		}
+		f, err := strconv.ParseFloat(t.Value, 64)
+		if err != nil {
+			return 0, t.literalError(ErrNotRepresentable)
+		}
+		return f, nil
+	}
+
+	i, err := t.BigInt()
+	if err != nil {
+		return 0, err
+	}
+	f, acc := new(big.Float).SetInt(i).Float64()
+	if math.IsInf(f, 0) && acc != big.Exact {
+		return 0, t.literalError(ErrNotRepresentable)
+	}
+	return f, nil
+}
+
+// Bool returns the value of a literal whose datatype is xsd:boolean.
+func (t RdfTerm) Bool() (bool, error) {
+	if !t.IsLiteral() || t.DataType != xsdBoolean {
+		return false, t.literalError(ErrDatatypeMismatch)
+	}
+
+	switch t.Value {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	}
+	return false, t.literalError(ErrIllTypedLiteral)
+}
+
+// Time returns the value of a literal whose datatype is xsd:dateTime,
+// xsd:dateTimeStamp or xsd:date. Values without a timezone are returned in
+// UTC. A date is returned as the time at the start of the day. Fractional
+// seconds beyond nanosecond precision are truncated.
+func (t RdfTerm) Time() (time.Time, error) {
+	if !t.IsLiteral() {
+		return time.Time{}, t.literalError(ErrDatatypeMismatch)
+	}
+
+	var tm time.Time
+	var ok bool
+	switch t.DataType {
+	case xsdDateTime, xsdDateTimeStamp:
+		var hasZone bool
+		tm, hasZone, ok = parseDateTime(t.Value)
+		if t.DataType == xsdDateTimeStamp && !hasZone {
+			ok = false
+		}
+	case xsdDate:
+		tm, ok = parseDate(t.Value)
+	default:
+		return time.Time{}, t.literalError(ErrDatatypeMismatch)
+	}
+
+	if !ok {
+		return time.Time{}, t.literalError(ErrIllTypedLiteral)
+	}
+	return tm, nil
+}
+
+// Duration returns the value of a literal whose datatype is xsd:duration,
+// xsd:dayTimeDuration or xsd:yearMonthDuration. ErrNotRepresentable is
+// returned if the days and time do not fit in a time.Duration. Fractional
+// seconds beyond nanosecond precision are truncated.
+func (t RdfTerm) Duration() (Duration, error) {
+	if !t.IsLiteral() {
+		return Duration{}, t.literalError(ErrDatatypeMismatch)
+	}
+	switch t.DataType {
+	case xsdDuration, xsdDayTimeDuration, xsdYearMonthDuration:
+	default:
+		return Duration{}, t.literalError(ErrDatatypeMismatch)
+	}
+
+	m := xsdDurationLexical.FindStringSubmatch(t.Value)
+	if m == nil || strings.HasSuffix(t.Value, "P") || strings.HasSuffix(t.Value, "T") {
+		return Duration{}, t.literalError(ErrIllTypedLiteral)
+	}
+
+	hasYearMonth := m[2] != "" || m[3] != ""
+	hasDayTime := m[4] != "" || m[5] != "" || m[6] != "" || m[7] != ""
+	if (t.DataType == xsdDayTimeDuration && hasYearMonth) || (t.DataType == xsdYearMonthDuration && hasDayTime) {
+		return Duration{}, t.literalError(ErrIllTypedLiteral)
+	}
+
+	var d Duration
+	var overflow bool
+	d.Months = addInt64(mulInt64(parseUint(m[2]), 12, &overflow), parseUint(m[3]), &overflow)
+
+	dayTime := parseUint(m[4])
+	dayTime = addInt64(mulInt64(dayTime, 24, &overflow), parseUint(m[5]), &overflow)
+	dayTime = addInt64(mulInt64(dayTime, 60, &overflow), parseUint(m[6]), &overflow)
+
+	seconds, nanos := m[7], int64(0)
+	if i := strings.IndexByte(seconds, '.'); i >= 0 {
+		frac := (seconds[i+1:] + "000000000")[:9]
+		nanos = parseUint(frac)
+		seconds = seconds[:i]
+	}
+	dayTime = addInt64(mulInt64(dayTime, 60, &overflow), parseUint(seconds), &overflow)
+	dayTime = addInt64(mulInt64(dayTime, int64(time.Second), &overflow), nanos, &overflow)
+
+	if overflow {
+		return Duration{}, t.literalError(ErrNotRepresentable)
+	}
+	d.DayTime = time.Duration(dayTime)
+
+	if m[1] == "-" {
+		d.Months, d.DayTime = -d.Months, -d.DayTime
+	}
+	return d, nil
+}
+
+// NewInt64Literal returns an xsd:integer literal with the value i.
+func NewInt64Literal(i int64) RdfTerm {
+	return RdfTerm{Value: strconv.FormatInt(i, 10), DataType: xsdInteger, TermType: RdfLiteral}
+}
+
+// NewBigIntLiteral returns an xsd:integer literal with the value i.
+func NewBigIntLiteral(i *big.Int) RdfTerm {
+	return RdfTerm{Value: i.String(), DataType: xsdInteger, TermType: RdfLiteral}
+}
+
+// NewBigRatLiteral returns an xsd:decimal literal with the value r in
+// canonical form. ErrNotRepresentable is returned if r has no finite
+// decimal representation, such as 1/3.
+func NewBigRatLiteral(r *big.Rat) (RdfTerm, error) {
+	// A fraction has a finite decimal representation only if its denominator
+	// has no prime factors other than 2 and 5.
+	denom := new(big.Int).Set(r.Denom())
+	digits := 0
+	for _, p := range []int64{2, 5} {
+		n := 0
+		for q, m := new(big.Int), new(big.Int); ; n++ {
+			q.QuoRem(denom, big.NewInt(p), m)
+			if m.Sign() != 0 {
+				break
+			}
+			denom.Set(q)
+		}
+		if n > digits {
+			digits = n
+		}
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return RdfTerm{}, &LiteralError{Value: r.String(), DataType: xsdDecimal, Err: ErrNotRepresentable}
+	}
+
+	value := r.FloatString(digits)
+	if strings.Contains(value, ".") {
+		value = strings.TrimRight(value, "0")
+	}
+	return RdfTerm{Value: value, DataType: xsdDecimal, TermType: RdfLiteral}, nil
+}
+
+// NewFloat64Literal returns an xsd:double literal with the value f in canonical form.
+func NewFloat64Literal(f float64) RdfTerm {
+	var value string
+	switch {
+	case math.IsNaN(f):
+		value = "NaN"
+	case math.IsInf(f, 1):
+		value = "INF"
+	case math.IsInf(f, -1):
+		value = "-INF"
+	default:
+		value = strconv.FormatFloat(f, 'E', -1, 64)
+		i := strings.IndexByte(value, 'E')
+		mantissa, exponent := value[:i], value[i+1:]
+		if !strings.Contains(mantissa, ".") {
+			mantissa += ".0"
+		}
+		exponent = strings.TrimPrefix(exponent, "+")
+		if strings.HasPrefix(exponent, "-") {
+			exponent = "-" + strings.TrimLeft(exponent[1:], "0")
+		} else {
+			exponent = strings.TrimLeft(exponent, "0")
+		}
+		if exponent == "" || exponent == "-" {
+			exponent = "0"
+		}
+		value = mantissa + "E" + exponent
+	}
+	return RdfTerm{Value: value, DataType: xsdDouble, TermType: RdfLiteral}
+}
+
+// NewBoolLiteral returns an xsd:boolean literal with the value b.
+func NewBoolLiteral(b bool) RdfTerm {
+	return RdfTerm{Value: strconv.FormatBool(b), DataType: xsdBoolean, TermType: RdfLiteral}
+}
+
+// NewTimeLiteral returns an xsd:dateTime literal with the value tm, including
+// its timezone. Timezones whose offset is not a whole number of minutes
+// cannot be represented so such times are converted to UTC.
+func NewTimeLiteral(tm time.Time) RdfTerm {
+	_, offset := tm.Zone()
+	if offset%60 != 0 {
+		tm, offset = tm.UTC(), 0
+	}
+
+	var b strings.Builder
+	b.WriteString(formatDate(tm))
+	fmt.Fprintf(&b, "T%02d:%02d:%02d", tm.Hour(), tm.Minute(), tm.Second())
+	if ns := tm.Nanosecond(); ns != 0 {
+		b.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", ns), "0"))
+	}
+
+	if offset == 0 {
+		b.WriteByte('Z')
+	} else {
+		sign := '+'
+		if offset < 0 {
+			sign, offset = '-', -offset
+		}
+		fmt.Fprintf(&b, "%c%02d:%02d", sign, offset/3600, offset/60%60)
+	}
+	return RdfTerm{Value: b.String(), DataType: xsdDateTime, TermType: RdfLiteral}
+}
+
+// NewDateLiteral returns an xsd:date literal with the date of tm in its
+// location. The literal has no timezone.
+func NewDateLiteral(tm time.Time) RdfTerm {
+	return RdfTerm{Value: formatDate(tm), DataType: xsdDate, TermType: RdfLiteral}
+}
+
+// NewDurationLiteral returns an xsd:duration literal with the value d in
+// canonical form. ErrNotRepresentable is returned if the months and the
+// days and time of d have different signs.
+func NewDurationLiteral(d Duration) (RdfTerm, error) {
+	months, dayTime := d.Months, d.DayTime
+	if (months < 0 && dayTime > 0) || (months > 0 && dayTime < 0) {
+		return RdfTerm{}, &LiteralError{Value: fmt.Sprintf("%d months %s", d.Months, d.DayTime), DataType: xsdDuration, Err: ErrNotRepresentable}
+	}
+
+	var b strings.Builder
+	if months < 0 || dayTime < 0 {
+		b.WriteByte('-')
+	}
+	b.WriteByte('P')
+
+	// Use unsigned arithmetic so that the most negative values can be negated
+	um, ud := uint64(months), uint64(dayTime)
+	if months < 0 {
+		um = -um
+	}
+	if dayTime < 0 {
+		ud = -ud
+	}
+
+	if y := um / 12; y != 0 {
+		fmt.Fprintf(&b, "%dY", y)
+	}
+	if m := um % 12; m != 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+
+	const day = uint64(24 * time.Hour)
+	if days := ud / day; days != 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	ud %= day
+
+	if ud != 0 || b.Len() == 1 {
+		b.WriteByte('T')
+		if h := ud / uint64(time.Hour); h != 0 {
+			fmt.Fprintf(&b, "%dH", h)
+		}
+		if m := ud / uint64(time.Minute) % 60; m != 0 {
+			fmt.Fprintf(&b, "%dM", m)
+		}
+		if s := ud % uint64(time.Minute); s != 0 || ud == 0 {
+			fmt.Fprintf(&b, "%d", s/uint64(time.Second))
+			if ns := s % uint64(time.Second); ns != 0 {
+				b.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", ns), "0"))
+			}
+			b.WriteByte('S')
+		}
+	}
+	return RdfTerm{Value: b.String(), DataType: xsdDuration, TermType: RdfLiteral}, nil
+}
+
+// parseInteger parses the lexical form of an xsd:integer.
+func parseInteger(s string) (*big.Int, bool) {
+	if !xsdIntegerLexical.MatchString(s) {
+		return nil, false
+	}
+	return new(big.Int).SetString(s, 10)
+}
+
+// parseDecimal parses the lexical form of an xsd:decimal.
+func parseDecimal(s string) (*big.Rat, bool) {
+	if !xsdDecimalLexical.MatchString(s) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// parseDate parses the lexical form of an xsd:date.
+func parseDate(s string) (time.Time, bool) {
+	m := xsdDateLexical.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	year, month, day, ok := parseYearMonthDay(m[1], m[2], m[3])
+	if !ok {
+		return time.Time{}, false
+	}
+	loc, _, ok := parseTimezone(m[4])
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc), true
+}
+
+// parseDateTime parses the lexical form of an xsd:dateTime, also reporting
+// whether it has a timezone.
+func parseDateTime(s string) (time.Time, bool, bool) {
+	m := xsdDateTimeLexical.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, false, false
+	}
+
+	year, month, day, ok := parseYearMonthDay(m[1], m[2], m[3])
+	if !ok {
+		return time.Time{}, false, false
+	}
+
+	hour, minute, second := int(parseUint(m[4])), int(parseUint(m[5])), int(parseUint(m[6]))
+	nanos := 0
+	if m[7] != "" {
+		nanos = int(parseUint((m[7][1:] + "000000000")[:9]))
+	}
+	if minute > 59 || second > 59 || hour > 24 || (hour == 24 && (minute != 0 || second != 0 || nanos != 0)) {
+		return time.Time{}, false, false
+	}
+
+	loc, hasZone, ok := parseTimezone(m[8])
+	if !ok {
+		return time.Time{}, false, false
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, second, nanos, loc), hasZone, true
+}
+
+// parseYearMonthDay parses and checks the date components of an xsd:date or xsd:dateTime.
+func parseYearMonthDay(y, m, d string) (int, int, int, bool) {
+	// Years with more than four digits may not have leading zeros
+	digits := strings.TrimPrefix(y, "-")
+	if len(digits) > 4 && digits[0] == '0' || len(digits) > 9 {
+		return 0, 0, 0, false
+	}
+
+	year, _ := strconv.Atoi(y)
+	month, day := int(parseUint(m)), int(parseUint(d))
+	if month < 1 || month > 12 || day < 1 {
+		return 0, 0, 0, false
+	}
+	if day > time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		return 0, 0, 0, false
+	}
+	return year, month, day, true
+}
+
+// parseTimezone parses the optional timezone of a date or time, returning
+// UTC if there is none.
+func parseTimezone(s string) (*time.Location, bool, bool) {
+	switch s {
+	case "":
+		return time.UTC, false, true
+	case "Z":
+		return time.UTC, true, true
+	}
+
+	hours, minutes := int(parseUint(s[1:3])), int(parseUint(s[4:6]))
+	if minutes > 59 || hours > 14 || (hours == 14 && minutes != 0) {
+		return nil, false, false
+	}
+	offset := hours*3600 + minutes*60
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone("", offset), true, true
+}
+
+// formatDate returns the lexical form of the date of tm.
+func formatDate(tm time.Time) string {
+	year := tm.Year()
+	sign := ""
+	if year < 0 {
+		sign, year = "-", -year
+	}
+	return fmt.Sprintf("%s%04d-%02d-%02d", sign, year, tm.Month(), tm.Day())
+}
+
+// parseUint parses a string of decimal digits, returning 0 for the empty
+// string and math.MaxInt64 if the value is too large.
+func parseUint(s string) int64 {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && s != "" {
+		return math.MaxInt64
+	}
+	return i
+}
+
+// mulInt64 returns a*b for non-negative a and b, setting overflow if the result does not fit.
+func mulInt64(a, b int64, overflow *bool) int64 {
+	if b != 0 && a > math.MaxInt64/b {
+		*overflow = true
+		return 0
+	}
+	return a * b
+}
+
+// addInt64 returns a+b for non-negative a and b, setting overflow if the result does not fit.
+func addInt64(a, b int64, overflow *bool) int64 {
+	if a > math.MaxInt64-b {
+		*overflow = true
+		return 0
+	}
+	return a + b
+}
+
+// Regular expressions for the lexical spaces of datatypes that are only validated.
+var (
+	xsdTimeLexical       = regexp.MustCompile(`^(([01][0-9]|2[0-3]):[0-5][0-9]:[0-5][0-9](\.[0-9]+)?|24:00:00(\.0+)?)` + xsdTimezone + `$`)
+	xsdGYearLexical      = regexp.MustCompile(`^` + xsdYear + xsdTimezone + `$`)
+	xsdGYearMonthLexical = regexp.MustCompile(`^` + xsdYear + `-(0[1-9]|1[0-2])` + xsdTimezone + `$`)
+	xsdGMonthLexical     = regexp.MustCompile(`^--(0[1-9]|1[0-2])` + xsdTimezone + `$`)
+	xsdGMonthDayLexical  = regexp.MustCompile(`^--(0[1-9]|1[0-2])-(0[1-9]|[12][0-9]|3[01])` + xsdTimezone + `$`)
+	xsdGDayLexical       = regexp.MustCompile(`^---(0[1-9]|[12][0-9]|3[01])` + xsdTimezone + `$`)
+	xsdHexBinaryLexical  = regexp.MustCompile(`^([0-9a-fA-F]{2})*$`)
+	xsdLanguageLexical   = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+)
+
+// Fragments of the regular expressions for dates and times.
+const (
+	xsdYear     = `-?([1-9][0-9]{3,}|0[0-9]{3})`
+	xsdTimezone = `(Z|[+-]((0[0-9]|1[0-3]):[0-5][0-9]|14:00))?`
+)
+
+// ValidateLiteral checks that the lexical form of the literal t is valid for
+// its datatype. All of the built-in datatypes of XML Schema that are
+// recommended for use in RDF are checked, as is rdf:langString, which
+// requires a language tag. Literals with other datatypes are only checked
+// for a well-formed language tag. A LiteralError wrapping ErrIllTypedLiteral
+// is returned if t is ill-typed. Terms other than literals are not checked.
+func ValidateLiteral(t RdfTerm) error {
+	if !t.IsLiteral() {
+		return nil
+	}
+
+	if t.Language != "" {
+		if (t.DataType != "" && t.DataType != rdfLangString) || !isLangTag(t.Language) {
+			return t.literalError(ErrIllTypedLiteral)
+		}
+		return nil
+	}
+
+	var err error
+	switch t.DataType {
+	case "", xsdString, xsdAnyURI:
+		if !isXMLString(t.Value) {
+			err = ErrIllTypedLiteral
+		}
+	case rdfLangString:
+		err = ErrIllTypedLiteral
+	case xsdBoolean:
+		_, err = t.Bool()
+	case xsdDecimal:
+		if !xsdDecimalLexical.MatchString(t.Value) {
+			err = ErrIllTypedLiteral
+		}
+	case xsdDouble, xsdFloat:
+		if !xsdDoubleLexical.MatchString(t.Value) {
+			err = ErrIllTypedLiteral
+		}
+	case xsdDate, xsdDateTime, xsdDateTimeStamp:
+		_, err = t.Time()
+	case xsdDuration, xsdDayTimeDuration, xsdYearMonthDuration:
+		_, err = t.Duration()
+	case xsdTime:
+		err = matchLexical(xsdTimeLexical, t.Value)
+	case xsdGYear:
+		err = matchLexical(xsdGYearLexical, t.Value)
+	case xsdGYearMonth:
+		err = matchLexical(xsdGYearMonthLexical, t.Value)
+	case xsdGMonth:
+		err = matchLexical(xsdGMonthLexical, t.Value)
+	case xsdGDay:
+		err = matchLexical(xsdGDayLexical, t.Value)
+	case xsdGMonthDay:
+		if m := xsdGMonthDayLexical.FindStringSubmatch(t.Value); m == nil {
+			err = ErrIllTypedLiteral
+		} else if month, day := parseUint(m[1]), parseUint(m[2]); day > 30 && (month == 4 || month == 6 || month == 9 || month == 11) || month == 2 && day > 29 {
+			err = ErrIllTypedLiteral
+		}
+	case xsdHexBinary:
+		err = matchLexical(xsdHexBinaryLexical, t.Value)
+	case xsdBase64Binary:
+		if !isBase64Binary(t.Value) {
+			err = ErrIllTypedLiteral
+		}
+	case xsdNormalizedString, xsdToken:
+		if !isXMLString(t.Value) || strings.ContainsAny(t.Value, "\t\r\n") {
+			err = ErrIllTypedLiteral
+		} else if t.DataType == xsdToken && (strings.HasPrefix(t.Value, " ") || strings.HasSuffix(t.Value, " ") || strings.Contains(t.Value, "  ")) {
+			err = ErrIllTypedLiteral
+		}
+	case xsdLanguage:
+		err = matchLexical(xsdLanguageLexical, t.Value)
+	case xsdNMTOKEN, xsdName, xsdNCName:
+		if !isXMLName(t.Value, t.DataType) {
+			err = ErrIllTypedLiteral
+		}
+	default:
+		if _, ok := integerTypes[t.DataType]; ok {
+			_, err = t.BigInt()
+		}
+	}
+
+	// Values that are too large for Go types are still valid
+	if err == nil || errors.Is(err, ErrNotRepresentable) {
+		return nil
+	}
+	if _, ok := err.(*LiteralError); ok {
+		return err
+	}
+	return t.literalError(err)
+}
+
+// matchLexical returns ErrIllTypedLiteral unless re matches s.
+func matchLexical(re *regexp.Regexp, s string) error {
+	if !re.MatchString(s) {
+		return ErrIllTypedLiteral
+	}
+	return nil
+}
+
+// isXMLString reports whether s consists only of characters allowed in XML documents.
+func isXMLString(s string) bool {
+	for _, r1 := range s {
+		switch {
+		case r1 == 0x9, r1 == 0xA, r1 == 0xD,
+			r1 >= 0x20 && r1 <= 0xD7FF,
+			r1 >= 0xE000 && r1 <= 0xFFFD,
+			r1 >= 0x10000 && r1 <= 0x10FFFF:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isXMLName reports whether s is in the lexical space of xsd:NMTOKEN,
+// xsd:Name or xsd:NCName, as given by dataType.
+func isXMLName(s, dataType string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r1 := range s {
+		switch {
+		case r1 == ':':
+			if dataType == xsdNCName {
+				return false
+			}
+		case i == 0 && dataType != xsdNMTOKEN:
+			if !isPNCharsU(r1) {
+				return false
+			}
+		case !isPNChars(r1) && r1 != '.':
+			return false
+		}
+	}
+	return true
+}
+
+// isBase64Binary reports whether s is in the lexical space of
+// xsd:base64Binary, which allows single spaces between characters.
+func isBase64Binary(s string) bool {
+	if strings.HasPrefix(s, " ") || strings.HasSuffix(s, " ") || strings.Contains(s, "  ") || strings.ContainsAny(s, "\r\n") {
+		return false
+	}
+	_, err := base64.StdEncoding.Strict().DecodeString(strings.ReplaceAll(s, " ", ""))
+	return err == nil
+}
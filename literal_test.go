@@ -0,0 +1,417 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// typed returns a literal with the given lexical form and datatype.
+func typed(value, dataType string) RdfTerm {
+	return RdfTerm{Value: value, DataType: dataType, TermType: RdfLiteral}
+}
+
+func TestLiteralInt64(t *testing.T) {
+	valid := map[RdfTerm]int64{
+		typed("42", xsdInteger):                       42,
+		typed("-0042", xsdInteger):                    -42,
+		typed("+7", xsdInteger):                       7,
+		typed("127", xsdByte):                         127,
+		typed("0", xsdNonNegativeInteger):             0,
+		typed("-9223372036854775808", xsdLong):        math.MinInt64,
+		typed("4294967295", xsdUnsignedInt):           math.MaxUint32,
+		typed("-1", xsdNegativeInteger):               -1,
+		typed("9223372036854775807", xsdInteger):      math.MaxInt64,
+		typed("-32768", xsdShort):                     math.MinInt16,
+		typed("2147483647", xsdInt):                   math.MaxInt32,
+		typed("255", xsdUnsignedByte):                 255,
+		typed("1", xsdPositiveInteger):                1,
+		typed("0", xsdNonPositiveInteger):             0,
+		typed("65535", xsdUnsignedShort):              65535,
+		typed("9223372036854775807", xsdUnsignedLong): math.MaxInt64,
+	}
+	for term, expected := range valid {
+		actual, err := term.Int64()
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if actual != expected {
+			t.Errorf("Expected %d for %s but got %d", expected, term, actual)
+		}
+	}
+
+	invalid := map[RdfTerm]error{
+		typed("1.0", xsdInteger):                       ErrIllTypedLiteral,
+		typed(" 1", xsdInteger):                        ErrIllTypedLiteral,
+		typed("", xsdInteger):                          ErrIllTypedLiteral,
+		typed("0x10", xsdInteger):                      ErrIllTypedLiteral,
+		typed("128", xsdByte):                          ErrIllTypedLiteral,
+		typed("0", xsdPositiveInteger):                 ErrIllTypedLiteral,
+		typed("-1", xsdUnsignedLong):                   ErrIllTypedLiteral,
+		typed("9223372036854775808", xsdInteger):       ErrNotRepresentable,
+		typed("18446744073709551615", xsdUnsignedLong): ErrNotRepresentable,
+		typed("1", xsdDecimal):                         ErrDatatypeMismatch,
+		{Value: "1", TermType: RdfLiteral}:             ErrDatatypeMismatch,
+		{Value: "1", TermType: RdfIri}:                 ErrDatatypeMismatch,
+	}
+	for term, expected := range invalid {
+		_, err := term.Int64()
+		if !errors.Is(err, expected) {
+			t.Errorf("Expected %v for %s but got %v", expected, term, err)
+		}
+	}
+}
+
+func TestLiteralBigInt(t *testing.T) {
+	i, err := typed("-123456789012345678901234567890", xsdInteger).BigInt()
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	if i.String() != "-123456789012345678901234567890" {
+		t.Errorf("Expected -123456789012345678901234567890 but got %s", i)
+	}
+
+	var lerr *LiteralError
+	_, err = typed("abc", xsdInt).BigInt()
+	if !errors.As(err, &lerr) || lerr.Value != "abc" || lerr.DataType != xsdInt {
+		t.Errorf("Got unexpected error %#v", err)
+	}
+}
+
+func TestLiteralFloat64(t *testing.T) {
+	valid := map[RdfTerm]float64{
+		typed("1.5E3", xsdDouble):     1500,
+		typed("-.5", xsdDouble):       -0.5,
+		typed("5.", xsdDouble):        5,
+		typed("1e-2", xsdDouble):      0.01,
+		typed("INF", xsdDouble):       math.Inf(1),
+		typed("+INF", xsdDouble):      math.Inf(1),
+		typed("-INF", xsdFloat):       math.Inf(-1),
+		typed("1e400", xsdDouble):     math.Inf(1),
+		typed("0.1", xsdFloat):        float64(float32(0.1)),
+		typed("1e39", xsdFloat):       math.Inf(1),
+		typed("-12.25", xsdDecimal):   -12.25,
+		typed("42", xsdInteger):       42,
+		typed("200", xsdUnsignedByte): 200,
+	}
+	for term, expected := range valid {
+		actual, err := term.Float64()
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if actual != expected {
+			t.Errorf("Expected %v for %s but got %v", expected, term, actual)
+		}
+	}
+
+	if f, err := typed("NaN", xsdDouble).Float64(); err != nil || !math.IsNaN(f) {
+		t.Errorf("Expected NaN but got %v, %v", f, err)
+	}
+
+	invalid := map[RdfTerm]error{
+		typed("inf", xsdDouble):   ErrIllTypedLiteral,
+		typed("0x1p3", xsdDouble): ErrIllTypedLiteral,
+		typed("1e3", xsdDecimal):  ErrIllTypedLiteral,
+		typed("1_000", xsdDouble): ErrIllTypedLiteral,
+		typed("1.5", xsdInteger):  ErrIllTypedLiteral,
+		typed("1e", xsdDouble):    ErrIllTypedLiteral,
+		typed("true", xsdBoolean): ErrDatatypeMismatch,
+	}
+	for term, expected := range invalid {
+		_, err := term.Float64()
+		if !errors.Is(err, expected) {
+			t.Errorf("Expected %v for %s but got %v", expected, term, err)
+		}
+	}
+}
+
+func TestLiteralBigRat(t *testing.T) {
+	valid := map[RdfTerm]string{
+		typed("0.1", xsdDecimal):   "1/10",
+		typed("-2.50", xsdDecimal): "-5/2",
+		typed("3", xsdInteger):     "3/1",
+		typed("0.5", xsdDouble):    "1/2",
+	}
+	for term, expected := range valid {
+		actual, err := term.BigRat()
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if actual.String() != expected {
+			t.Errorf("Expected %s for %s but got %s", expected, term, actual)
+		}
+	}
+
+	invalid := map[RdfTerm]error{
+		typed("1/2", xsdDecimal): ErrIllTypedLiteral,
+		typed("NaN", xsdDouble):  ErrNotRepresentable,
+		typed("x", xsdString):    ErrDatatypeMismatch,
+	}
+	for term, expected := range invalid {
+		_, err := term.BigRat()
+		if !errors.Is(err, expected) {
+			t.Errorf("Expected %v for %s but got %v", expected, term, err)
+		}
+	}
+}
+
+func TestLiteralBool(t *testing.T) {
+	valid := map[string]bool{"true": true, "1": true, "false": false, "0": false}
+	for value, expected := range valid {
+		actual, err := typed(value, xsdBoolean).Bool()
+		if err != nil || actual != expected {
+			t.Errorf("Expected %v for %s but got %v, %v", expected, value, actual, err)
+		}
+	}
+
+	for _, value := range []string{"TRUE", "yes", " true", ""} {
+		if _, err := typed(value, xsdBoolean).Bool(); !errors.Is(err, ErrIllTypedLiteral) {
+			t.Errorf("Expected %v for %q but got %v", ErrIllTypedLiteral, value, err)
+		}
+	}
+	if _, err := typed("true", xsdString).Bool(); !errors.Is(err, ErrDatatypeMismatch) {
+		t.Errorf("Expected %v but got %v", ErrDatatypeMismatch, err)
+	}
+}
+
+func TestLiteralTime(t *testing.T) {
+	valid := map[RdfTerm]time.Time{
+		typed("2002-10-10T12:00:00-05:00", xsdDateTime):      time.Date(2002, 10, 10, 17, 0, 0, 0, time.UTC),
+		typed("2002-10-10T12:00:00.25Z", xsdDateTime):        time.Date(2002, 10, 10, 12, 0, 0, 250000000, time.UTC),
+		typed("2002-10-10T12:00:00", xsdDateTime):            time.Date(2002, 10, 10, 12, 0, 0, 0, time.UTC),
+		typed("2002-10-10T24:00:00Z", xsdDateTime):           time.Date(2002, 10, 11, 0, 0, 0, 0, time.UTC),
+		typed("2000-02-29T00:00:00+14:00", xsdDateTimeStamp): time.Date(2000, 2, 28, 10, 0, 0, 0, time.UTC),
+		typed("-0044-03-15T00:00:00Z", xsdDateTime):          time.Date(-44, 3, 15, 0, 0, 0, 0, time.UTC),
+		typed("12345-01-01T00:00:00Z", xsdDateTime):          time.Date(12345, 1, 1, 0, 0, 0, 0, time.UTC),
+		typed("2002-10-10", xsdDate):                         time.Date(2002, 10, 10, 0, 0, 0, 0, time.UTC),
+		typed("2002-10-10+01:00", xsdDate):                   time.Date(2002, 10, 9, 23, 0, 0, 0, time.UTC),
+	}
+	for term, expected := range valid {
+		actual, err := term.Time()
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if !actual.Equal(expected) {
+			t.Errorf("Expected %v for %s but got %v", expected, term, actual)
+		}
+	}
+
+	invalid := map[RdfTerm]error{
+		typed("2002-10-10T12:00", xsdDateTime):          ErrIllTypedLiteral,
+		typed("2002-13-10T12:00:00", xsdDateTime):       ErrIllTypedLiteral,
+		typed("2001-02-29T12:00:00", xsdDateTime):       ErrIllTypedLiteral,
+		typed("2002-10-10T24:00:01", xsdDateTime):       ErrIllTypedLiteral,
+		typed("2002-10-10T12:00:60", xsdDateTime):       ErrIllTypedLiteral,
+		typed("2002-10-10T12:00:00+14:30", xsdDateTime): ErrIllTypedLiteral,
+		typed("02002-10-10T12:00:00", xsdDateTime):      ErrIllTypedLiteral,
+		typed("2002-10-10T12:00:00", xsdDateTimeStamp):  ErrIllTypedLiteral,
+		typed("2002-10-10T12:00:00Z", xsdDate):          ErrIllTypedLiteral,
+		typed("2002-10-10", xsdString):                  ErrDatatypeMismatch,
+	}
+	for term, expected := range invalid {
+		_, err := term.Time()
+		if !errors.Is(err, expected) {
+			t.Errorf("Expected %v for %s but got %v", expected, term, err)
+		}
+	}
+}
+
+func TestLiteralDuration(t *testing.T) {
+	valid := map[RdfTerm]Duration{
+		typed("P1Y2M3DT4H5M6.5S", xsdDuration): {Months: 14, DayTime: 76*time.Hour + 5*time.Minute + 6500*time.Millisecond},
+		typed("-P1M", xsdDuration):             {Months: -1},
+		typed("PT0S", xsdDuration):             {},
+		typed("PT.5S", xsdDuration):            {DayTime: 500 * time.Millisecond},
+		typed("P2D", xsdDayTimeDuration):       {DayTime: 48 * time.Hour},
+		typed("P3Y", xsdYearMonthDuration):     {Months: 36},
+		typed("PT1.0000000019S", xsdDuration):  {DayTime: time.Second + 1},
+	}
+	for term, expected := range valid {
+		actual, err := term.Duration()
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if actual != expected {
+			t.Errorf("Expected %v for %s but got %v", expected, term, actual)
+		}
+	}
+
+	invalid := map[RdfTerm]error{
+		typed("P", xsdDuration):            ErrIllTypedLiteral,
+		typed("-P", xsdDuration):           ErrIllTypedLiteral,
+		typed("P1DT", xsdDuration):         ErrIllTypedLiteral,
+		typed("PT1D", xsdDuration):         ErrIllTypedLiteral,
+		typed("P1M2Y", xsdDuration):        ErrIllTypedLiteral,
+		typed("P-1D", xsdDuration):         ErrIllTypedLiteral,
+		typed("P1Y", xsdDayTimeDuration):   ErrIllTypedLiteral,
+		typed("P1D", xsdYearMonthDuration): ErrIllTypedLiteral,
+		typed("P200000D", xsdDuration):     ErrNotRepresentable,
+		typed("PT1S", xsdDateTime):         ErrDatatypeMismatch,
+	}
+	for term, expected := range invalid {
+		_, err := term.Duration()
+		if !errors.Is(err, expected) {
+			t.Errorf("Expected %v for %s but got %v", expected, term, err)
+		}
+	}
+}
+
+func TestNewTypedLiterals(t *testing.T) {
+	rat := func(s string) *big.Rat {
+		r, _ := new(big.Rat).SetString(s)
+		return r
+	}
+	newRat := func(r *big.Rat) RdfTerm {
+		l, err := NewBigRatLiteral(r)
+		if err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+		return l
+	}
+	newDuration := func(d Duration) RdfTerm {
+		l, err := NewDurationLiteral(d)
+		if err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+		return l
+	}
+
+	cases := map[string]RdfTerm{
+		`"-42"^^<http://www.w3.org/2001/XMLSchema#integer>`:                            NewInt64Literal(-42),
+		`"123456789012345678901234567890"^^<http://www.w3.org/2001/XMLSchema#integer>`: NewBigIntLiteral(rat("123456789012345678901234567890").Num()),
+		`"1.0E3"^^<http://www.w3.org/2001/XMLSchema#double>`:                           NewFloat64Literal(1000),
+		`"-1.25E-5"^^<http://www.w3.org/2001/XMLSchema#double>`:                        NewFloat64Literal(-0.0000125),
+		`"0.0E0"^^<http://www.w3.org/2001/XMLSchema#double>`:                           NewFloat64Literal(0),
+		`"INF"^^<http://www.w3.org/2001/XMLSchema#double>`:                             NewFloat64Literal(math.Inf(1)),
+		`"NaN"^^<http://www.w3.org/2001/XMLSchema#double>`:                             NewFloat64Literal(math.NaN()),
+		`"true"^^<http://www.w3.org/2001/XMLSchema#boolean>`:                           NewBoolLiteral(true),
+		`"-0.125"^^<http://www.w3.org/2001/XMLSchema#decimal>`:                         newRat(rat("-1/8")),
+		`"3"^^<http://www.w3.org/2001/XMLSchema#decimal>`:                              newRat(rat("3")),
+		`"0.01"^^<http://www.w3.org/2001/XMLSchema#decimal>`:                           newRat(rat("1/100")),
+		`"2002-10-10T12:00:00.5-05:00"^^<http://www.w3.org/2001/XMLSchema#dateTime>`:   NewTimeLiteral(time.Date(2002, 10, 10, 12, 0, 0, 500000000, time.FixedZone("", -5*3600))),
+		`"2002-10-10T12:00:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime>`:          NewTimeLiteral(time.Date(2002, 10, 10, 12, 0, 0, 0, time.UTC)),
+		`"-0044-03-15"^^<http://www.w3.org/2001/XMLSchema#date>`:                       NewDateLiteral(time.Date(-44, 3, 15, 12, 0, 0, 0, time.UTC)),
+		`"P1Y2M3DT4H5M6.5S"^^<http://www.w3.org/2001/XMLSchema#duration>`:              newDuration(Duration{Months: 14, DayTime: 76*time.Hour + 5*time.Minute + 6500*time.Millisecond}),
+		`"-P2D"^^<http://www.w3.org/2001/XMLSchema#duration>`:                          newDuration(Duration{DayTime: -48 * time.Hour}),
+		`"PT0S"^^<http://www.w3.org/2001/XMLSchema#duration>`:                          newDuration(Duration{}),
+		`"P1M"^^<http://www.w3.org/2001/XMLSchema#duration>`:                           newDuration(Duration{Months: 1}),
+	}
+	for expected, term := range cases {
+		if actual := term.String(); actual != expected {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+	}
+
+	if _, err := NewBigRatLiteral(big.NewRat(1, 3)); !errors.Is(err, ErrNotRepresentable) {
+		t.Errorf("Expected %v but got %v", ErrNotRepresentable, err)
+	}
+	if _, err := NewDurationLiteral(Duration{Months: 1, DayTime: -time.Hour}); !errors.Is(err, ErrNotRepresentable) {
+		t.Errorf("Expected %v but got %v", ErrNotRepresentable, err)
+	}
+}
+
+func TestTypedLiteralRoundTrip(t *testing.T) {
+	tm := time.Date(2020, 2, 29, 23, 59, 59, 123456789, time.FixedZone("", 9*3600+30*60))
+	if actual, err := NewTimeLiteral(tm).Time(); err != nil || !actual.Equal(tm) {
+		t.Errorf("Expected %v but got %v, %v", tm, actual, err)
+	}
+
+	for _, f := range []float64{0.1, -1e-300, math.MaxFloat64, math.SmallestNonzeroFloat64, 123456789} {
+		if actual, err := NewFloat64Literal(f).Float64(); err != nil || actual != f {
+			t.Errorf("Expected %v but got %v, %v", f, actual, err)
+		}
+	}
+
+	d := Duration{Months: -25, DayTime: -(36*time.Hour + time.Nanosecond)}
+	l, _ := NewDurationLiteral(d)
+	if actual, err := l.Duration(); err != nil || actual != d {
+		t.Errorf("Expected %v but got %v, %v", d, actual, err)
+	}
+}
+
+func TestValidateLiteral(t *testing.T) {
+	valid := []RdfTerm{
+		{Value: "plain", TermType: RdfLiteral},
+		{Value: "chat", Language: "fr", TermType: RdfLiteral},
+		{Value: "chat", Language: "fr", DataType: rdfLangString, TermType: RdfLiteral},
+		{Value: "not a literal", TermType: RdfIri},
+		typed("anything", "http://example.org/datatype"),
+		typed("x", xsdString),
+		typed("http://example.org/ a b", xsdAnyURI),
+		typed("1", xsdBoolean),
+		typed("-.5", xsdDecimal),
+		typed("12", xsdDecimal),
+		typed("-1E4", xsdFloat),
+		typed("NaN", xsdDouble),
+		typed("99999999999999999999999", xsdInteger),
+		typed("255", xsdUnsignedByte),
+		typed("2002-10-10T12:00:00.5+05:30", xsdDateTime),
+		typed("2002-10-10Z", xsdDate),
+		typed("P9999999999DT1S", xsdDuration),
+		typed("13:20:00", xsdTime),
+		typed("24:00:00.000Z", xsdTime),
+		typed("-0045", xsdGYear),
+		typed("2002-10+01:00", xsdGYearMonth),
+		typed("--12", xsdGMonth),
+		typed("--02-29", xsdGMonthDay),
+		typed("---31Z", xsdGDay),
+		typed("0FB7", xsdHexBinary),
+		typed("", xsdHexBinary),
+		typed("SGVsbG8=", xsdBase64Binary),
+		typed("SGVs bG8=", xsdBase64Binary),
+		typed("a b  c", xsdNormalizedString),
+		typed("a b c", xsdToken),
+		typed("en-GB", xsdLanguage),
+		typed("-1.x", xsdNMTOKEN),
+		typed("xs:Name", xsdName),
+		typed("_n.c-1", xsdNCName),
+	}
+	for _, term := range valid {
+		if err := ValidateLiteral(term); err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		}
+	}
+
+	invalid := []RdfTerm{
+		{Value: "chat", Language: "fr", DataType: xsdString, TermType: RdfLiteral},
+		{Value: "chat", Language: "not a tag", TermType: RdfLiteral},
+		typed("chat", rdfLangString),
+		typed("nul\x00", xsdString),
+		typed("yes", xsdBoolean),
+		typed("1e3", xsdDecimal),
+		typed("abc", xsdInteger),
+		typed("256", xsdUnsignedByte),
+		typed("2002-02-30", xsdDate),
+		typed("2002-10-10 12:00:00", xsdDateTime),
+		typed("P1S", xsdDuration),
+		typed("25:00:00", xsdTime),
+		typed("24:00:01", xsdTime),
+		typed("12:00:00+15:00", xsdTime),
+		typed("45", xsdGYear),
+		typed("02002", xsdGYear),
+		typed("2002-13", xsdGYearMonth),
+		typed("-12", xsdGMonth),
+		typed("--02-30", xsdGMonthDay),
+		typed("--04-31", xsdGMonthDay),
+		typed("---32", xsdGDay),
+		typed("0FB", xsdHexBinary),
+		typed("SGVsbG9=", xsdBase64Binary),
+		typed(" SGVsbG8=", xsdBase64Binary),
+		typed("SGVs\nbG8=", xsdBase64Binary),
+		typed("a\tb", xsdNormalizedString),
+		typed(" a", xsdToken),
+		typed("a  b", xsdToken),
+		typed("englishman", xsdLanguage),
+		typed("", xsdNMTOKEN),
+		typed("a b", xsdNMTOKEN),
+		typed("1x", xsdName),
+		typed("xs:Name", xsdNCName),
+	}
+	for _, term := range invalid {
+		if err := ValidateLiteral(term); !errors.Is(err, ErrIllTypedLiteral) {
+			t.Errorf("Expected %v for %s but got %v", ErrIllTypedLiteral, term, err)
+		}
+	}
+}
@@ -3,29 +3,70 @@
   information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
 */
 
-// Package ntriples parses N-Triples
+// Package ntriples reads and writes N-Triples, N-Quads and Turtle
 package ntriples
 
 import (
-	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
-	"unicode"
+	"strings"
 )
 
 // A ParseError is returned for parsing errors.
 // The first line is 1.  The first column is 0.
 // Deprecated: use ParseError from github.com/iand/nquads package instead
 type ParseError struct {
-	Line   int   // Line where the error occurred
-	Column int   // Column (rune index) where the error occurred
-	Err    error // The actual error
+	Line     int    // Line where the error occurred
+	Column   int    // Column (rune index) where the error occurred
+	Offset   int64  // Byte offset from the start of the input where the error occurred
+	Text     string // Text of the line where the error occurred without the line ending, if known
+	Rune     rune   // The unexpected rune, or -1 if the error was not caused by a single rune
+	Expected string // Description of what was expected instead, if known
+	Err      error  // The actual error
 }
 
 func (e *ParseError) Error() string {
-	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
+	msg := fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
+	if e.Rune >= 0 {
+		msg += fmt.Sprintf(" %q", e.Rune)
+	}
+	if e.Expected != "" {
+		msg += ", expected " + e.Expected
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error so that ParseError can be used with errors.Is.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Snippet returns the text of the line where the error occurred followed by a
+// second line with a caret marking the column of the error.
+func (e *ParseError) Snippet() string {
+	var b strings.Builder
+	b.WriteString(e.Text)
+	b.WriteByte('\n')
+
+	n := 0
+	for _, r1 := range e.Text {
+		if n >= e.Column {
+			break
+		}
+		if r1 == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+		n++
+	}
+	for ; n < e.Column; n++ {
+		b.WriteByte(' ')
+	}
+	b.WriteByte('^')
+
+	return b.String()
 }
 
 // These are the errors that can be returned in ParseError.Error
@@ -36,16 +77,52 @@ var (
 	ErrUnterminatedIri     = errors.New("unterminated IRI, expecting '>'")
 	ErrUnterminatedLiteral = errors.New("unterminated literal, expecting '\"'")
 	ErrUnterminatedTriple  = errors.New("unterminated triple, expecting '.'")
+	ErrRelativeIri         = errors.New("relative IRI, expecting absolute IRI")
+	ErrMalformedIri        = errors.New("malformed IRI, expecting RFC 3987 syntax")
 )
 
+// A Reader reads triples from an N-Triples document.
+//
+// The exported fields can be changed to customize the details before the
+// first call to Next.
 // Deprecated: use Reader from github.com/iand/nquads package instead
 type Reader struct {
-	line   int
-	column int
-	r      *bufio.Reader
-	buf    bytes.Buffer
-	err    error
-	t      Triple
+	// Lenient, if true, makes Next skip any line that cannot be parsed
+	// instead of stopping. The ParseError for each skipped line is recorded
+	// and reading resumes at the start of the following line. The recorded
+	// errors are returned by Errors.
+	//
+	// Invalid UTF-8 is an error in IRIs and blank node labels. In literals
+	// it is also an error unless Lenient is set, in which case each invalid
+	// byte is replaced by U+FFFD.
+	Lenient bool
+
+	// MaxErrors, if positive, is the number of errors that are skipped in
+	// lenient mode. Once another error occurs Next returns false and Err
+	// returns that error.
+	MaxErrors int
+
+	// ValidateLiterals, if true, makes Next check that the lexical form of
+	// each typed literal is valid for its datatype, as for ValidateLiteral.
+	// An ill-typed literal is reported as a ParseError at the start of the
+	// literal.
+	ValidateLiterals bool
+
+	// ValidateIRIs, if true, makes Next check that each IRI conforms to the
+	// syntax of RFC 3987. A malformed IRI is reported as a ParseError at the
+	// start of the IRI with ErrMalformedIri.
+	ValidateIRIs bool
+
+	// Base, if set, is the absolute IRI against which relative IRIs are
+	// resolved instead of reporting ErrRelativeIri. N-Triples only allows
+	// absolute IRIs, so this is only needed to read documents that do not
+	// conform to the specification.
+	Base string
+
+	s       *Scanner
+	t       Triple
+	g       RdfTerm
+	strings map[string]string // interned IRIs
 }
 
 // A Triple consists of a subject, predicate and object
@@ -56,37 +133,48 @@ type Triple struct {
 	O RdfTerm
 }
 
+// String returns the N-Triples encoding of the triple. If a term cannot be
+// encoded, because it has an unknown type or is a quoted triple term with no
+// triple, the result is a placeholder such as "%!(unknown term type)" in the
+// style of the fmt package, which is not valid N-Triples. Use MarshalText to
+// detect such errors.
 func (t Triple) String() string {
-	return fmt.Sprintf("%s %s %s .", t.S, t.P, t.O)
+	return formatText(appendTriple(nil, t))
 }
 
-// An RdfTerm represents one of Iri, Blank Node or Literal
+// CanonicalString returns the canonical N-Triples encoding of the triple.
+// Triples that are equal in RDF always have the same canonical encoding.
+// Triples that cannot be encoded give a placeholder, as for String.
+func (t Triple) CanonicalString() string {
+	return formatText(appendTriple(nil, canonicalTriple(t)))
+}
+
+// An RdfTerm represents one of Iri, Blank Node, Literal or quoted Triple
 // Deprecated: use Term from github.com/iand/gordf package instead
 type RdfTerm struct {
 	Value    string
 	Language string
 	DataType string
-	TermType int
+	TermType TermType
+
+	// Triple is the quoted triple of a term of type RdfTriple, as in
+	// RDF-star and RDF 1.2.
+	Triple *Triple
 }
 
+// String returns the N-Triples encoding of the term, escaping any characters
+// as necessary. If the term cannot be encoded, because it has an unknown type
+// or is a quoted triple term with no triple, the result is a placeholder such
+// as "%!(unknown term type)" in the style of the fmt package, which is not
+// valid N-Triples. Use MarshalText to detect such errors.
 func (t RdfTerm) String() string {
-	switch t.TermType {
-	case RdfIri:
-		return fmt.Sprintf("<%s>", t.Value)
-
-	case RdfBlank:
-		return fmt.Sprintf("_:%s", t.Value)
-	case RdfLiteral:
-		if t.Language != "" {
-			return fmt.Sprintf("\"%s\"@%s", t.Value, t.Language)
-		} else if t.DataType != "" {
-			return fmt.Sprintf("\"%s\"^^<%s>", t.Value, t.DataType)
-		} else {
-			return fmt.Sprintf("\"%s\"", t.Value)
-		}
-	}
+	return formatText(appendTerm(nil, t))
+}
 
-	return "[unknown type]"
+// CanonicalString returns the canonical N-Triples encoding of the term. Terms
+// that cannot be encoded give a placeholder, as for String.
+func (t RdfTerm) CanonicalString() string {
+	return formatText(appendTerm(nil, canonicalTerm(t)))
 }
 
 func (t RdfTerm) IsIRI() bool {
@@ -109,34 +197,112 @@ func (t RdfTerm) IsLanguageLiteral() bool {
 	return t.TermType == RdfLiteral && t.Language != ""
 }
 
+func (t RdfTerm) IsTriple() bool {
+	return t.TermType == RdfTriple
+}
+
+// IRIs from the RDF and XML Schema vocabularies that have special meaning in
+// the syntaxes handled by this package.
+const (
+	rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rdfType      = rdfNamespace + "type"
+	rdfFirst     = rdfNamespace + "first"
+	rdfRest      = rdfNamespace + "rest"
+	rdfNil       = rdfNamespace + "nil"
+
+	xsdNamespace = "http://www.w3.org/2001/XMLSchema#"
+	xsdString    = xsdNamespace + "string"
+	xsdBoolean   = xsdNamespace + "boolean"
+	xsdInteger   = xsdNamespace + "integer"
+	xsdDecimal   = xsdNamespace + "decimal"
+	xsdDouble    = xsdNamespace + "double"
+)
+
+// A TermType identifies the kind of an RdfTerm.
+type TermType int
+
 // Constants for types of RdfTerm
 const (
-	RdfUnknown = iota
+	RdfUnknown TermType = iota
 	RdfIri
 	RdfBlank
 	RdfLiteral
+	RdfTriple
 )
 
+// termTypeNames holds the names used by TermType.MarshalText, indexed by
+// TermType.
+var termTypeNames = [...]string{
+	RdfUnknown: "unknown",
+	RdfIri:     "iri",
+	RdfBlank:   "blank",
+	RdfLiteral: "literal",
+	RdfTriple:  "triple",
+}
+
+// IsValid reports whether tt is one of the defined term types. RdfUnknown is
+// valid, since it represents a missing term such as the default graph.
+func (tt TermType) IsValid() bool {
+	return tt >= RdfUnknown && tt <= RdfTriple
+}
+
+// String returns the name of the constant for tt, such as "RdfIri", or
+// "TermType(n)" if tt is not valid.
+func (tt TermType) String() string {
+	switch tt {
+	case RdfUnknown:
+		return "RdfUnknown"
+	case RdfIri:
+		return "RdfIri"
+	case RdfBlank:
+		return "RdfBlank"
+	case RdfLiteral:
+		return "RdfLiteral"
+	case RdfTriple:
+		return "RdfTriple"
+	}
+	return fmt.Sprintf("TermType(%d)", int(tt))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The term type
+// is encoded as one of "unknown", "iri", "blank", "literal" or "triple". It returns
+// ErrUnknownTermType if tt is not valid.
+func (tt TermType) MarshalText() ([]byte, error) {
+	if !tt.IsValid() {
+		return nil, ErrUnknownTermType
+	}
+	return []byte(termTypeNames[tt]), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The term
+// type is decoded from one of the names used by MarshalText. It returns
+// ErrUnknownTermType for any other text.
+func (tt *TermType) UnmarshalText(text []byte) error {
+	for i, name := range termTypeNames {
+		if string(text) == name {
+			*tt = TermType(i)
+			return nil
+		}
+	}
+	return ErrUnknownTermType
+}
+
 // NewReader returns a new Reader that reads from r.
 // Deprecated: use NewReader from github.com/iand/nquads package instead
 func NewReader(r io.Reader) *Reader {
 	return &Reader{
-		r: bufio.NewReader(r),
-	}
-}
-
-// error creates a new ParseError based on err.
-func (r *Reader) error(err error) error {
-	return &ParseError{
-		Line:   r.line,
-		Column: r.column,
-		Err:    err,
+		s: NewScanner(r),
 	}
 }
 
 // Err returns any error encountered while reading. If Err is non-nil then Next will always return false.
 func (r *Reader) Err() error {
-	return r.err
+	return r.s.Err()
+}
+
+// Errors returns the errors for the lines that have been skipped in lenient mode.
+func (r *Reader) Errors() []*ParseError {
+	return r.s.Errors()
 }
 
 // Triple returns the last triple read
@@ -147,414 +313,150 @@ func (r *Reader) Triple() Triple {
 // Next attempts to read the next triple from the underlying reader. It returns false if no triple could be read which
 // may indicate an error has occurred or the end of the input stream has been reached.
 func (r *Reader) Next() bool {
-	if r.err != nil {
+	r.s.Lenient = r.Lenient
+	r.s.MaxErrors = r.MaxErrors
+	r.s.ValidateLiterals = r.ValidateLiterals
+	r.s.ValidateIRIs = r.ValidateIRIs
+	r.s.Base = r.Base
+
+	if !r.s.Scan() {
+		r.t = Triple{}
+		r.g = RdfTerm{}
 		return false
 	}
 
-	r.t = Triple{}
-	r.line++
-	r.column = -1
-
-	r1, err := r.skipWhitespace()
-	if err != nil {
-		if err == io.EOF {
-			return false
-		}
-		r.err = err
-		return false
+	o := r.s.Object()
+	r.t = Triple{
+		S: r.s.Subject().Term(),
+		P: RdfTerm{Value: r.intern(r.s.Predicate().Value), TermType: RdfIri},
+		O: RdfTerm{
+			Value:    string(o.Value),
+			Language: string(o.Language),
+			TermType: o.TermType,
+		},
 	}
-
-	for r1 == '#' {
-		for {
-			r1, err = r.readRune()
-			if err != nil {
-				r.err = err
-				return false
-			}
-			if r1 == '\n' {
-				break
-			}
-		}
-		r1, err = r.skipWhitespace()
-		if err != nil {
-			r.err = err
-			return false
-		}
-
+	if o.DataType != nil {
+		r.t.O.DataType = r.intern(o.DataType)
 	}
-
-	if err := r.r.UnreadRune(); err != nil {
-		r.err = err
-		return false
+	if o.Triple != nil {
+		r.t.O.Triple = o.Term().Triple
 	}
+	r.g = r.s.Graph().Term()
+	return true
+}
 
-	termCount := 0
-	for {
-		haveTerm, term, err := r.parseTerm()
-		if haveTerm {
-			termCount++
-			switch termCount {
-			case 1:
-				r.t.S = term
-				err = r.expectWhitespace()
-				if err != nil {
-					r.err = err
-					return false
-				}
-			case 2:
-				r.t.P = term
-				err = r.expectWhitespace()
-				if err != nil {
-					r.err = err
-					return false
-				}
-			case 3:
-				r.t.O = term
-
-				err = r.readEndTriple()
-				if err != nil {
-					r.err = err
-					return false
-				}
-
-				return true
-			default:
-				r.err = &ParseError{
-					Line:   r.line,
-					Column: r.column,
-					Err:    ErrTermCount,
-				}
-				return false
-			}
+// maxInterned is the number of distinct strings a Reader will reuse.
+const maxInterned = 1024
 
-		}
-		if err != nil {
-			r.err = err
-			return false
-		}
+// intern returns b as a string, reusing a previous string with the same value
+// if possible. It is used for predicates and datatypes, which are usually drawn
+// from a small vocabulary.
+func (r *Reader) intern(b []byte) string {
+	if s, ok := r.strings[string(b)]; ok {
+		return s
 	}
-}
-
-// readRune reads one rune from r, folding \r\n to \n and keeping track
-// of how far into the line we have read.  r.column will point to the start
-// of this rune, not the end of this rune.
-func (r *Reader) readRune() (rune, error) {
-	r1, _, err := r.r.ReadRune()
-
-	// Handle \r\n here.  We make the simplifying assumption that
-	// anytime \r is followed by \n that it can be folded to \n.
-	// We will not detect files which contain both \r\n and bare \n.
-	if r1 == '\r' {
-		r1, _, err = r.r.ReadRune()
-		if err == nil {
-			if r1 != '\n' {
-				if err := r.r.UnreadRune(); err != nil {
-					return r1, err
-				}
-				r1 = '\r'
-			}
+	s := string(b)
+	if len(r.strings) < maxInterned {
+		if r.strings == nil {
+			r.strings = map[string]string{}
 		}
+		r.strings[s] = s
 	}
-	r.column++
-	return r1, err
-}
-
-// unreadRune puts the last rune read from r back.
-func (r *Reader) unreadRune() error {
-	if err := r.r.UnreadRune(); err != nil {
-		return err
-	}
-	r.column--
-	return nil
+	return s
 }
 
-func (r *Reader) parseTerm() (haveField bool, term RdfTerm, err error) {
-	r.buf.Reset()
-
-	r1, err := r.skipWhitespace()
-	if err != nil {
-		return false, term, err
+// isBlankNodeLabel reports whether s is a valid blank node label, excluding the '_:' prefix.
+func isBlankNodeLabel(s string) bool {
+	if s == "" || s[len(s)-1] == '.' {
+		return false
 	}
-	switch r1 {
-	case '<':
-		// Read an IRI
-		for {
-			r1, err = r.readRune()
-			if err != nil {
-				if err == io.EOF {
-					return false, term, r.error(ErrUnexpectedEOF)
-				}
-				return false, term, err
-			}
-			if r1 == '>' {
-				if r.buf.Len() == 0 {
-					return false, term, r.error(ErrUnexpectedCharacter)
-				}
-				return true, RdfTerm{Value: r.buf.String(), TermType: RdfIri}, nil
-			} else if r1 < 0x20 || r1 > 0x7E || r1 == ' ' || r1 == '<' || r1 == '"' {
-				return false, term, r.error(ErrUnexpectedCharacter)
-			}
-			r.buf.WriteRune(r1)
-		}
-	case '_':
-		// Read a blank node
-		r1, err = r.readRune()
-		if err != nil {
-			if err == io.EOF {
-				return false, term, r.error(ErrUnexpectedEOF)
-			}
-			return false, term, err
-		}
-
-		if r1 != ':' {
-			return false, term, r.error(ErrUnexpectedCharacter)
-		}
-
-		r1, err = r.readRune()
-		if err != nil {
-			if err == io.EOF {
-				return false, term, r.error(ErrUnexpectedEOF)
-			}
-			return false, term, err
-		}
-		if !((r1 >= 'a' && r1 <= 'z') || (r1 >= 'A' && r1 <= 'Z')) {
-			return false, term, r.error(ErrUnexpectedCharacter)
-		}
-		r.buf.WriteRune(r1)
-
-		for {
-			r1, err = r.readRune()
-			if err != nil {
-				if err == io.EOF {
-					return false, term, r.error(ErrUnexpectedEOF)
-				}
-				return false, term, err
-			}
-			if !((r1 >= 'a' && r1 <= 'z') || (r1 >= 'A' && r1 <= 'Z') || (r1 >= '0' && r1 <= '9')) {
-				if r1 == '.' || unicode.IsSpace(r1) {
-					if err := r.unreadRune(); err != nil {
-						return false, term, r.error(err)
-					}
-					return true, RdfTerm{Value: r.buf.String(), TermType: RdfBlank}, nil
-				}
-				return false, term, r.error(ErrUnexpectedCharacter)
-			}
-			r.buf.WriteRune(r1)
-		}
-
-	case '"':
-		// Read a literal
-		for {
-			r1, err = r.readRune()
-			if err != nil {
-				if err == io.EOF {
-					return false, term, r.error(ErrUnexpectedEOF)
-				}
-				return false, term, err
-			}
-			switch r1 {
-			case '"':
-				r1, err = r.readRune()
-				if err != nil {
-					if err == io.EOF {
-						return false, term, r.error(ErrUnexpectedEOF)
-					}
-					return false, term, err
-				}
-
-				switch r1 {
-
-				case '.', ' ', '\t':
-					if err := r.unreadRune(); err != nil {
-						return false, term, r.error(err)
-					}
-					return true, RdfTerm{Value: r.buf.String(), TermType: RdfLiteral}, nil
-				case '@':
-					tmpterm := RdfTerm{Value: r.buf.String(), TermType: RdfLiteral}
-					r.buf.Reset()
-
-					for {
-						r1, err = r.readRune()
-						if err != nil {
-							if err == io.EOF {
-								return false, term, r.error(ErrUnexpectedEOF)
-							}
-							return false, term, err
-						}
-						if r1 == '.' || r1 == ' ' || r1 == '\t' {
-							if r.buf.Len() == 0 {
-								return false, term, r.error(ErrUnexpectedCharacter)
-							}
-							tmpterm.Language = r.buf.String()
-							return true, tmpterm, nil
-						}
-						if r1 == '-' || (r1 >= 'a' && r1 <= 'z') || (r1 >= '0' && r1 <= '9') {
-							r.buf.WriteRune(r1)
-						} else {
-							return false, term, r.error(ErrUnexpectedCharacter)
-						}
-					}
-				case '^':
-					tmpterm := RdfTerm{Value: r.buf.String(), TermType: RdfLiteral}
-					r.buf.Reset()
-
-					r1, err = r.readRune()
-					if err != nil {
-						if err == io.EOF {
-							return false, term, r.error(ErrUnexpectedEOF)
-						}
-						return false, term, err
-					}
-					if r1 != '^' {
-						return false, term, r.error(ErrUnexpectedCharacter)
-					}
-
-					r1, err = r.readRune()
-					if err != nil {
-						if err == io.EOF {
-							return false, term, r.error(ErrUnexpectedEOF)
-						}
-						return false, term, err
-					}
-					if r1 != '<' {
-						return false, term, r.error(ErrUnexpectedCharacter)
-					}
-
-					// Read an IRI
-					for {
-						r1, err = r.readRune()
-						if err != nil {
-							if err == io.EOF {
-								return false, term, r.error(ErrUnexpectedEOF)
-							}
-							return false, term, err
-						}
-						if r1 == '>' {
-							if r.buf.Len() == 0 {
-								return false, term, r.error(ErrUnexpectedCharacter)
-							}
-							tmpterm.DataType = r.buf.String()
-							return true, tmpterm, nil
-						} else if r1 < 0x20 || r1 > 0x7E || r1 == ' ' || r1 == '<' || r1 == '"' {
-							return false, term, r.error(ErrUnexpectedCharacter)
-						}
-						r.buf.WriteRune(r1)
-					}
-
-				}
-				return false, term, r.error(ErrUnexpectedCharacter)
-
-			case '\\':
-				r1, err = r.readRune()
-				if err != nil {
-					if err == io.EOF {
-						return false, term, r.error(ErrUnexpectedEOF)
-					}
-					return false, term, err
-				}
-				switch r1 {
-				case '\\', '"':
-				case 't':
-					r1 = '\t'
-				case 'r':
-					r1 = '\r'
-				case 'n':
-					r1 = '\n'
-				case 'u', 'U':
-
-					codepoint := rune(0)
-
-					for i := 3; i >= 0; i-- {
-						r1, err = r.readRune()
-
-						if err != nil {
-							if err == io.EOF {
-								return false, term, r.error(ErrUnexpectedEOF)
-							}
-							return false, term, err
-						}
-
-						if r1 >= '0' && r1 <= '9' {
-							codepoint += (1 << uint32(4*i)) * (r1 - '0')
-						} else if r1 >= 'a' && r1 <= 'f' {
-							codepoint += (1 << uint32(4*i)) * (r1 - 'a' + 10)
-						} else if r1 >= 'A' && r1 <= 'F' {
-							codepoint += (1 << uint32(4*i)) * (r1 - 'A' + 10)
-						} else {
-							return false, term, r.error(ErrUnexpectedCharacter)
-						}
-
-					}
-					r1 = codepoint
-
-				default:
-					return false, term, r.error(ErrUnexpectedCharacter)
-				}
+	for i, r1 := range s {
+		if i == 0 {
+			if !isPNCharsU(r1) && r1 != ':' && !isDigit(r1) {
+				return false
 			}
-			r.buf.WriteRune(r1)
+		} else if !isPNChars(r1) && r1 != ':' && r1 != '.' {
+			return false
 		}
-	default:
-		// TODO: raise error, unexpected character
-		return false, term, r.error(ErrUnexpectedCharacter)
-
 	}
+	return true
 }
 
-func (r *Reader) readEndTriple() (err error) {
-	r1, err := r.skipWhitespace()
-	if err != nil {
-		if err == io.EOF {
-			return r.error(ErrUnterminatedTriple)
-		}
-		return err
+// isIRIChar reports whether r1 may appear unescaped in an IRIREF.
+func isIRIChar(r1 rune) bool {
+	if r1 <= 0x20 {
+		return false
 	}
-
-	if r1 != '.' {
-		return r.error(ErrUnexpectedCharacter)
+	switch r1 {
+	case '<', '>', '"', '{', '}', '|', '^', '`', '\\':
+		return false
 	}
+	return true
+}
 
-	r1, err = r.skipWhitespace()
-	if err != nil {
-		if err == io.EOF {
-			return nil
-		}
-		return err
-	}
+// isLangTag reports whether s matches [a-zA-Z]+ ('-' [a-zA-Z0-9]+)*
+func isLangTag(s string) bool {
+	return isLangTagBytes([]byte(s))
+}
 
-	if r1 != '\n' {
-		return r.error(ErrUnexpectedCharacter)
+// hexValue returns the value of the hex digit r1.
+func hexValue(r1 rune) (rune, bool) {
+	switch {
+	case r1 >= '0' && r1 <= '9':
+		return r1 - '0', true
+	case r1 >= 'a' && r1 <= 'f':
+		return r1 - 'a' + 10, true
+	case r1 >= 'A' && r1 <= 'F':
+		return r1 - 'A' + 10, true
 	}
+	return 0, false
+}
 
-	return nil
+func isAlpha(r1 rune) bool {
+	return (r1 >= 'a' && r1 <= 'z') || (r1 >= 'A' && r1 <= 'Z')
 }
 
-func (r *Reader) skipWhitespace() (r1 rune, err error) {
-	r1, err = r.readRune()
-	if err != nil {
-		return r1, err
-	}
+func isDigit(r1 rune) bool {
+	return r1 >= '0' && r1 <= '9'
+}
 
-	for r1 == ' ' || r1 == '\t' {
-		r1, err = r.readRune()
-		if err != nil {
-			return r1, err
-		}
+// isPNCharsBase reports whether r1 matches the PN_CHARS_BASE production.
+func isPNCharsBase(r1 rune) bool {
+	switch {
+	case isAlpha(r1),
+		r1 >= 0x00C0 && r1 <= 0x00D6,
+		r1 >= 0x00D8 && r1 <= 0x00F6,
+		r1 >= 0x00F8 && r1 <= 0x02FF,
+		r1 >= 0x0370 && r1 <= 0x037D,
+		r1 >= 0x037F && r1 <= 0x1FFF,
+		r1 >= 0x200C && r1 <= 0x200D,
+		r1 >= 0x2070 && r1 <= 0x218F,
+		r1 >= 0x2C00 && r1 <= 0x2FEF,
+		r1 >= 0x3001 && r1 <= 0xD7FF,
+		r1 >= 0xF900 && r1 <= 0xFDCF,
+		r1 >= 0xFDF0 && r1 <= 0xFFFD,
+		r1 >= 0x10000 && r1 <= 0xEFFFF:
+		return true
 	}
+	return false
+}
 
-	return r1, nil
+// isPNCharsU reports whether r1 matches PN_CHARS_BASE or '_'. N-Triples
+// additionally allows ':' in blank node labels.
+func isPNCharsU(r1 rune) bool {
+	return r1 == '_' || isPNCharsBase(r1)
 }
 
-func (r *Reader) expectWhitespace() (err error) {
-	r1, err := r.readRune()
-	if err != nil {
-		if err == io.EOF {
-			return r.error(ErrUnexpectedEOF)
-		}
-		return err
-	}
-	if r1 != ' ' && r1 != '\t' {
-		return r.error(ErrUnexpectedCharacter)
+// isPNChars reports whether r1 matches the PN_CHARS production.
+func isPNChars(r1 rune) bool {
+	switch {
+	case isPNCharsU(r1),
+		isDigit(r1),
+		r1 == '-',
+		r1 == 0x00B7,
+		r1 >= 0x0300 && r1 <= 0x036F,
+		r1 >= 0x203F && r1 <= 0x2040:
+		return true
 	}
-
-	return nil
+	return false
 }
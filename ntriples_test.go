@@ -7,6 +7,9 @@ package ntriples
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -106,6 +109,113 @@ var testCases = map[string]Triple{
 		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
 		O: RdfTerm{Value: "typed literal", DataType: "http://example.org/DataType1", TermType: RdfLiteral},
 	},
+	// IRIREF
+	`<http://example.org/r\u00E9sum\u00E9> <http://example.org/property> <http://example.org/resource2> .`: {
+		S: RdfTerm{Value: "http://example.org/résumé", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+	},
+
+	`<http://example.org/resource1> <http://example.org/property> <http://example.org/\U0001F600> .`: {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "http://example.org/\U0001F600", TermType: RdfIri},
+	},
+
+	"<http://example.org/résumé> <http://example.org/property> <urn:x-local:#~%20!$&'()*+,;=?> .": {
+		S: RdfTerm{Value: "http://example.org/résumé", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "urn:x-local:#~%20!$&'()*+,;=?", TermType: RdfIri},
+	},
+
+	// BLANK_NODE_LABEL
+	"_:0abc <http://example.org/property> <http://example.org/resource2>.": {
+		S: RdfTerm{Value: "0abc", TermType: RdfBlank},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+	},
+
+	"_:a-b_c.d <http://example.org/property> _:a.b.": {
+		S: RdfTerm{Value: "a-b_c.d", TermType: RdfBlank},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "a.b", TermType: RdfBlank},
+	},
+
+	"_:_x:y\u00B7 <http://example.org/property> _:é\u0301 .": {
+		S: RdfTerm{Value: "_x:y\u00B7", TermType: RdfBlank},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "é\u0301", TermType: RdfBlank},
+	},
+
+	"_:a..b <http://example.org/property> _:c .": {
+		S: RdfTerm{Value: "a..b", TermType: RdfBlank},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "c", TermType: RdfBlank},
+	},
+
+	// STRING_LITERAL_QUOTE
+	`<http://example.org/resource13> <http://example.org/property> "\b\f\'" .`: {
+		S: RdfTerm{Value: "http://example.org/resource13", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "\b\f'", TermType: RdfLiteral},
+	},
+
+	`<http://example.org/resource17> <http://example.org/property> "\U0001F600" .`: {
+		S: RdfTerm{Value: "http://example.org/resource17", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "\U0001F600", TermType: RdfLiteral},
+	},
+
+	"<http://example.org/resource18> <http://example.org/property> \"caf\u00e9 \U0001F600 'x' # not a comment\" .": {
+		S: RdfTerm{Value: "http://example.org/resource18", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "caf\u00e9 \U0001F600 'x' # not a comment", TermType: RdfLiteral},
+	},
+
+	// LANGTAG
+	`<http://example.org/resource32> <http://example.org/property> "colour"@en-GB .`: {
+		S: RdfTerm{Value: "http://example.org/resource32", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "colour", Language: "en-GB", TermType: RdfLiteral},
+	},
+
+	`<http://example.org/resource33> <http://example.org/property> "chat"@EN-latn-1996 .`: {
+		S: RdfTerm{Value: "http://example.org/resource33", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "chat", Language: "EN-latn-1996", TermType: RdfLiteral},
+	},
+
+	// Minimal whitespace
+	`<http://example.org/resource1><http://example.org/property><http://example.org/resource2>.`: {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+	},
+
+	`_:abc<http://example.org/property>"chat"@fr.`: {
+		S: RdfTerm{Value: "abc", TermType: RdfBlank},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "chat", Language: "fr", TermType: RdfLiteral},
+	},
+
+	`_:abc<http://example.org/property>"typed"^^<http://example.org/DataType1>.`: {
+		S: RdfTerm{Value: "abc", TermType: RdfBlank},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "typed", DataType: "http://example.org/DataType1", TermType: RdfLiteral},
+	},
+
+	// Comments and line endings
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> . # a trailing comment": {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+	},
+
+	"\r\n\n# comment\r<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> .\r\n": {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+	},
 }
 
 var negativeCases = map[string]error{
@@ -114,24 +224,56 @@ var negativeCases = map[string]error{
 	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ..": ErrUnexpectedCharacter,
 	"http://example.org/resource1> <http://example.org/property> <http://example.org/resource2>.":    ErrUnexpectedCharacter,
 	"<http://example.org/resource1 <http://example.org/property> <http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1><http://example.org/property> <http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1> <http://example.org/property><http://example.org/resource2>.":    ErrUnexpectedCharacter,
 	"<http://example.org/resource1> http://example.org/property> <http://example.org/resource2>.":    ErrUnexpectedCharacter,
 	"<http://example.org/resource1> <http://example.org/property <http://example.org/resource2>.":    ErrUnexpectedCharacter,
 	"<http://example.org/resource1> <http://example.org/property> http://example.org/resource2>.":    ErrUnexpectedCharacter,
 	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2.":    ErrUnexpectedEOF,
 	"<http://example.org/resource1> \n<http://example.org/property> <http://example.org/resource2>.": ErrUnexpectedCharacter,
 	"_:foo\n <http://example.org/property> <http://example.org/resource2>.":                          ErrUnexpectedCharacter,
-	"_:0abc <http://example.org/property> <http://example.org/resource2>.":                           ErrUnexpectedCharacter,
 	"_abc <http://example.org/property> <http://example.org/resource2>.":                             ErrUnexpectedCharacter,
-	"_:a-bc <http://example.org/property> <http://example.org/resource2>.":                           ErrUnexpectedCharacter,
-	"_:abc<http://example.org/property> <http://example.org/resource2>.":                             ErrUnexpectedCharacter,
 	"_:abc <http://example.org/property> \"foo\"@ .":                                                 ErrUnexpectedCharacter,
 	"_:abc <http://example.org/property> \"foo\"^ .":                                                 ErrUnexpectedCharacter,
 	"_:abc <http://example.org/property> \"foo\"^^< .":                                               ErrUnexpectedCharacter,
 	"_:abc <http://example.org/property> \"foo\"^^<> .":                                              ErrUnexpectedCharacter,
 	"_:abc <> _:abc .":  ErrUnexpectedCharacter,
 	"_:abc < > _:abc .": ErrUnexpectedCharacter,
+
+	// IRIREF
+	"<http://example.org/{x}> <http://example.org/property> <http://example.org/resource2> .":    ErrUnexpectedCharacter,
+	"<http://example.org/a|b> <http://example.org/property> <http://example.org/resource2> .":    ErrUnexpectedCharacter,
+	`<http://example.org/\n> <http://example.org/property> <http://example.org/resource2> .`:     ErrUnexpectedCharacter,
+	`<http://example.org/\u00ZZ> <http://example.org/property> <http://example.org/resource2> .`: ErrUnexpectedCharacter,
+	`<http://example.org/\U00E9> <http://example.org/property> <http://example.org/resource2> .`: ErrUnexpectedCharacter,
+	"<resource1> <http://example.org/property> <http://example.org/resource2> .":                 ErrRelativeIri,
+	"<http://example.org/resource1> <property> <http://example.org/resource2> .":                 ErrRelativeIri,
+	"<http://example.org/resource1> <http://example.org/property> <#resource2> .":                ErrRelativeIri,
+	"<http://example.org/resource1> <http://example.org/property> \"foo\"^^<DataType1> .":        ErrRelativeIri,
+
+	// BLANK_NODE_LABEL
+	"_:-abc <http://example.org/property> <http://example.org/resource2> .": ErrUnexpectedCharacter,
+	"_:.abc <http://example.org/property> <http://example.org/resource2> .": ErrUnexpectedCharacter,
+	"_: <http://example.org/property> <http://example.org/resource2> .":     ErrUnexpectedCharacter,
+	"<http://example.org/resource1> _:abc <http://example.org/resource2> .": ErrUnexpectedCharacter,
+
+	// STRING_LITERAL_QUOTE
+	"\"foo\" <http://example.org/property> <http://example.org/resource2> .":         ErrUnexpectedCharacter,
+	"<http://example.org/resource1> \"foo\" <http://example.org/resource2> .":        ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> \"a\nb\" .":        ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> \"\\a\" .":         ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> \"\\uD800\" .":     ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> 'foo' .":           ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> \"\"\"foo\"\"\" .": ErrTermCount,
+
+	// LANGTAG
+	"<http://example.org/resource1> <http://example.org/property> \"foo\"@1 .":      ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> \"foo\"@en- .":    ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> \"foo\"@en--gb .": ErrUnexpectedCharacter,
+
+	// Triple structure
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> <http://example.org/resource3> .": ErrTermCount,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ; .":                              ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> . <http://example.org/resource3>": ErrUnexpectedCharacter,
+	"@prefix ex: <http://example.org/> .": ErrUnexpectedCharacter,
 }
 
 func TestRead(t *testing.T) {
@@ -201,3 +343,377 @@ func TestReadErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestReadErrorText(t *testing.T) {
+	r := NewReader(strings.NewReader("<http://example.org/s> <http://example.org/p> <http://example.org/o> .\n<http://example.org/s> <p> <http://example.org/o> .\r\n<http://example.org/s> <http://example.org/p> <http://example.org/o> ."))
+	for r.Next() {
+	}
+
+	err, ok := r.Err().(*ParseError)
+	if !ok {
+		t.Fatalf("Expected a ParseError but got %v", r.Err())
+	}
+	if err.Line != 2 || err.Column != 25 || err.Text != "<http://example.org/s> <p> <http://example.org/o> ." {
+		t.Errorf("Got unexpected error %#v", err)
+	}
+}
+
+func TestReadLenient(t *testing.T) {
+	valid := "<http://example.org/s> <http://example.org/p> <http://example.org/o> ."
+
+	for ntriple, expected := range negativeCases {
+		// Errors at the end of the input are reported differently when a line follows
+		if strings.ContainsAny(ntriple, "\r\n") || expected == ErrUnexpectedEOF || expected == ErrUnterminatedTriple {
+			continue
+		}
+
+		t.Run("", func(t *testing.T) {
+			r := NewReader(strings.NewReader(valid + "\n" + ntriple + "\n" + valid + "\n"))
+			r.Lenient = true
+
+			count := 0
+			for r.Next() {
+				count++
+			}
+
+			if r.Err() != nil {
+				t.Fatalf("Got unexpected error %v for %s", r.Err(), ntriple)
+			}
+			if count != 2 {
+				t.Errorf("Expected 2 but parsed %d triples for %s", count, ntriple)
+			}
+
+			errs := r.Errors()
+			if len(errs) != 1 {
+				t.Fatalf("Expected 1 error but got %v for %s", errs, ntriple)
+			}
+			if errs[0].Err != expected || errs[0].Line != 2 || errs[0].Text != ntriple {
+				t.Errorf("Expected %s on line 2 for %s but got %#v", expected, ntriple, errs[0])
+			}
+		})
+	}
+}
+
+func TestReadLenientMaxErrors(t *testing.T) {
+	doc := `<http://example.org/s> <http://example.org/p> <http://example.org/o> .
+<http://example.org/s> <http://example.org/p> <o> .
+<http://example.org/s> <http://example.org/p> "unterminated
+<http://example.org/s> <http://example.org/p> <http://example.org/o> .
+<http://example.org/s> <http://example.org/p> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o> .`
+
+	r := NewReader(strings.NewReader(doc))
+	r.Lenient = true
+	r.MaxErrors = 2
+
+	count := 0
+	for r.Next() {
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 but parsed %d triples", count)
+	}
+
+	errs := r.Errors()
+	if len(errs) != 2 || errs[0].Line != 2 || errs[0].Err != ErrRelativeIri || errs[1].Line != 3 || errs[1].Err != ErrUnexpectedCharacter {
+		t.Errorf("Got unexpected errors %v", errs)
+	}
+
+	err, ok := r.Err().(*ParseError)
+	if !ok || err.Line != 5 || err.Err != ErrUnexpectedCharacter {
+		t.Errorf("Got unexpected error %v", r.Err())
+	}
+}
+
+func TestParseErrorDetails(t *testing.T) {
+	doc := "<http://example.org/s> <http://example.org/p> <http://example.org/o> .\r\n# é\n\t<http://example.org/é> <http://example.org/p> <http://exa mple.org/o> .\n"
+
+	r := NewReader(strings.NewReader(doc))
+	for r.Next() {
+	}
+
+	err, ok := r.Err().(*ParseError)
+	if !ok {
+		t.Fatalf("Expected a ParseError but got %v", r.Err())
+	}
+
+	expected := &ParseError{
+		Line:     3,
+		Column:   58,
+		Offset:   136,
+		Text:     "\t<http://example.org/é> <http://example.org/p> <http://exa mple.org/o> .",
+		Rune:     ' ',
+		Expected: "'>' or IRI character",
+		Err:      ErrUnexpectedCharacter,
+	}
+	if *err != *expected {
+		t.Errorf("Expected %#v but got %#v", expected, err)
+	}
+	if doc[err.Offset] != ' ' {
+		t.Errorf("Expected offset %d to point at the unexpected character", err.Offset)
+	}
+
+	if msg := "line 3, column 58: unexpected character ' ', expected '>' or IRI character"; err.Error() != msg {
+		t.Errorf("Expected %q but got %q", msg, err.Error())
+	}
+
+	snippet := "\t<http://example.org/é> <http://example.org/p> <http://exa mple.org/o> .\n\t" + strings.Repeat(" ", 57) + "^"
+	if err.Snippet() != snippet {
+		t.Errorf("Expected snippet:\n%s\nbut got:\n%s", snippet, err.Snippet())
+	}
+
+	if !errors.Is(r.Err(), ErrUnexpectedCharacter) {
+		t.Errorf("Expected errors.Is to match %v", ErrUnexpectedCharacter)
+	}
+}
+
+func TestReadValidateLiterals(t *testing.T) {
+	doc := "<http://example.org/s> <http://example.org/p> \"1\"^^<http://www.w3.org/2001/XMLSchema#integer> .\n" +
+		"<http://example.org/s> <http://example.org/p> \"abc\"^^<http://www.w3.org/2001/XMLSchema#integer> .\n" +
+		"<http://example.org/s> <http://example.org/p> \"2\"^^<http://www.w3.org/2001/XMLSchema#integer> .\n"
+
+	r := NewReader(strings.NewReader(doc))
+	count := 0
+	for r.Next() {
+		count++
+	}
+	if r.Err() != nil || count != 3 {
+		t.Fatalf("Expected 3 triples without validation but got %d, %v", count, r.Err())
+	}
+
+	r = NewReader(strings.NewReader(doc))
+	r.ValidateLiterals = true
+	r.Lenient = true
+	count = 0
+	for r.Next() {
+		count++
+	}
+	if r.Err() != nil || count != 2 {
+		t.Fatalf("Expected 2 triples with validation but got %d, %v", count, r.Err())
+	}
+
+	errs := r.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("Expected one error but got %v", errs)
+	}
+	if errs[0].Line != 2 || errs[0].Column != 46 || !errors.Is(errs[0], ErrIllTypedLiteral) {
+		t.Errorf("Got unexpected error %#v", errs[0])
+	}
+	if msg := `line 2, column 46: literal "abc" with datatype <http://www.w3.org/2001/XMLSchema#integer>: lexical form is not valid for the datatype`; errs[0].Error() != msg {
+		t.Errorf("Expected %q but got %q", msg, errs[0].Error())
+	}
+}
+
+func TestReadValidateIRIs(t *testing.T) {
+	doc := "<http://example.org/s> <http://example.org/p> <http://example.org/\\u0020o> .\n"
+
+	r := NewReader(strings.NewReader(doc))
+	if !r.Next() {
+		t.Fatalf("Got unexpected error %v", r.Err())
+	}
+
+	r = NewReader(strings.NewReader(doc))
+	r.ValidateIRIs = true
+	if r.Next() {
+		t.Fatalf("Expected an error but got %s", r.Triple())
+	}
+	err, ok := r.Err().(*ParseError)
+	if !ok || err.Line != 1 || err.Column != 46 || err.Err != ErrMalformedIri {
+		t.Errorf("Got unexpected error %#v", r.Err())
+	}
+}
+
+func TestReadBase(t *testing.T) {
+	doc := "<s> <#p> \"o\"^^<../dt> <g> .\n"
+
+	r := NewQuadReader(strings.NewReader(doc))
+	r.Base = "http://example.org/a/b"
+	if !r.Next() {
+		t.Fatalf("Got unexpected error %v", r.Err())
+	}
+
+	expected := "<http://example.org/a/s> <http://example.org/a/b#p> \"o\"^^<http://example.org/dt> <http://example.org/a/g> ."
+	if actual := r.Quad().String(); actual != expected {
+		t.Errorf("Expected %s but got %s", expected, actual)
+	}
+}
+
+// benchmarkDocument returns an N-Triples document with a representative mix of terms.
+func benchmarkDocument(lines int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < lines; i++ {
+		switch i % 5 {
+		case 0:
+			fmt.Fprintf(&buf, "<http://example.org/resource/%d> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/vocab/Thing> .\n", i)
+		case 1:
+			fmt.Fprintf(&buf, "<http://example.org/resource/%d> <http://www.w3.org/2000/01/rdf-schema#label> \"Resource number %d, with a longer label\"@en-GB .\n", i, i)
+		case 2:
+			fmt.Fprintf(&buf, "<http://example.org/resource/%d> <http://example.org/vocab/count> \"%d\"^^<http://www.w3.org/2001/XMLSchema#integer> .\n", i, i)
+		case 3:
+			fmt.Fprintf(&buf, "_:b%d <http://example.org/vocab/related> <http://example.org/resource/%d> .\n", i, i-1)
+		case 4:
+			fmt.Fprintf(&buf, "<http://example.org/resource/%d> <http://example.org/vocab/note> \"Caf\\u00E9 \\\"quoted\\\" text\\nwith escapes and ünïcödé\" .\n", i)
+		}
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkReader(b *testing.B) {
+	doc := benchmarkDocument(10000)
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(doc))
+		for r.Next() {
+		}
+		if r.Err() != nil {
+			b.Fatal(r.Err())
+		}
+	}
+}
+
+func TestTermType(t *testing.T) {
+	cases := map[TermType]string{
+		RdfUnknown: "RdfUnknown",
+		RdfIri:     "RdfIri",
+		RdfBlank:   "RdfBlank",
+		RdfLiteral: "RdfLiteral",
+		RdfTriple:  "RdfTriple",
+		5:          "TermType(5)",
+		-1:         "TermType(-1)",
+		42:         "TermType(42)",
+	}
+
+	for tt, expected := range cases {
+		if actual := tt.String(); actual != expected {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+
+		text, err := tt.MarshalText()
+		if !tt.IsValid() {
+			if err != ErrUnknownTermType {
+				t.Errorf("Expected %v but got %v", ErrUnknownTermType, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+
+		var actual TermType = -1
+		if err := actual.UnmarshalText(text); err != nil {
+			t.Errorf("Got unexpected error %v", err)
+		} else if actual != tt {
+			t.Errorf("Expected %v but got %v", tt, actual)
+		}
+	}
+
+	data, err := json.Marshal([]TermType{RdfIri, RdfBlank, RdfLiteral})
+	if err != nil || string(data) != `["iri","blank","literal"]` {
+		t.Errorf("Expected term types to be encoded as names but got %s, %v", data, err)
+	}
+
+	var tt TermType
+	if err := tt.UnmarshalText([]byte("IRI")); err != ErrUnknownTermType {
+		t.Errorf("Expected %v but got %v", ErrUnknownTermType, err)
+	}
+}
+
+func TestStringUnknownTermType(t *testing.T) {
+	term := RdfTerm{Value: "chat", TermType: 42}
+	triple := Triple{S: term, P: term, O: term}
+	quoted := RdfTerm{TermType: RdfTriple}
+	iri := RdfTerm{Value: "http://example.org/", TermType: RdfIri}
+
+	cases := []struct {
+		text     string
+		expected string
+		marshal  func() ([]byte, error)
+		err      error
+	}{
+		{term.String(), "%!(unknown term type)", term.MarshalText, ErrUnknownTermType},
+		{term.CanonicalString(), "%!(unknown term type)", term.MarshalText, ErrUnknownTermType},
+		{triple.String(), "%!(unknown term type)", triple.MarshalText, ErrInvalidSubject},
+		{triple.CanonicalString(), "%!(unknown term type)", triple.MarshalText, ErrInvalidSubject},
+		{quoted.String(), "%!(quoted triple term has no triple)", quoted.MarshalText, ErrInvalidTriple},
+		{Quad{Triple: Triple{S: iri, P: iri, O: term}}.String(), "%!(unknown term type)", Quad{Triple: Triple{S: iri, P: iri, O: term}}.MarshalText, ErrUnknownTermType},
+	}
+	for _, tc := range cases {
+		if tc.text != tc.expected {
+			t.Errorf("Expected %s but got %s", tc.expected, tc.text)
+		}
+
+		// MarshalText reports the error instead of returning a placeholder
+		if text, err := tc.marshal(); err != tc.err || text != nil {
+			t.Errorf("Expected %v but got %q, %v", tc.err, text, err)
+		}
+	}
+}
+
+func TestReadQuotedTriples(t *testing.T) {
+	s := RdfTerm{Value: "http://example.org/s", TermType: RdfIri}
+	p := RdfTerm{Value: "http://example.org/p", TermType: RdfIri}
+	b := RdfTerm{Value: "b0", TermType: RdfBlank}
+	lit := RdfTerm{Value: "chat", Language: "en", TermType: RdfLiteral}
+	quoted := RdfTerm{TermType: RdfTriple, Triple: &Triple{S: s, P: p, O: lit}}
+	nested := RdfTerm{TermType: RdfTriple, Triple: &Triple{S: quoted, P: p, O: b}}
+
+	cases := map[string]Triple{
+		`<< <http://example.org/s> <http://example.org/p> "chat"@en >> <http://example.org/p> _:b0 .`:                                                          {S: quoted, P: p, O: b},
+		`<<<http://example.org/s><http://example.org/p>"chat"@en>><http://example.org/p>_:b0.`:                                                                 {S: quoted, P: p, O: b},
+		`<http://example.org/s> <http://example.org/p> << <http://example.org/s> <http://example.org/p> "chat"@en >> .`:                                        {S: s, P: p, O: quoted},
+		`<http://example.org/s> <http://example.org/p> <<( <http://example.org/s> <http://example.org/p> "chat"@en )>> .`:                                      {S: s, P: p, O: quoted},
+		`<< << <http://example.org/s> <http://example.org/p> "chat"@en >> <http://example.org/p> _:b0 >> <http://example.org/p> "chat"@en .`:                   {S: nested, P: p, O: lit},
+		`_:b0 <http://example.org/p> <<( << <http://example.org/s> <http://example.org/p> "chat"@en >> <http://example.org/p> _:b0 )>> .`:                      {S: b, P: p, O: nested},
+		`<< <http://example.org/s> <http://example.org/p> "chat"@en >> <http://example.org/p> << <http://example.org/s> <http://example.org/p> "chat"@en >> .`: {S: quoted, P: p, O: quoted},
+	}
+
+	for ntriple, expected := range cases {
+		r := NewReader(strings.NewReader(ntriple))
+		if !r.Next() {
+			t.Errorf("Got unexpected error %v for %s", r.Err(), ntriple)
+			continue
+		}
+		if !r.Triple().Equal(expected) {
+			t.Errorf("Expected %s but got %s", expected, r.Triple())
+		}
+
+		actual, err := ParseTriple(r.Triple().String())
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, r.Triple())
+		} else if !actual.Equal(expected) {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+	}
+
+	errorCases := map[string]string{
+		`<<( <http://example.org/s> <http://example.org/p> <http://example.org/o> )>> <http://example.org/p> _:b0 .`:            "'<', '_:' or '<<'",
+		`<http://example.org/s> << <http://example.org/s> <http://example.org/p> <http://example.org/o> >> _:b0 .`:              "'<'",
+		`<http://example.org/s> <http://example.org/p> << "s" <http://example.org/p> <http://example.org/o> >> .`:               "'<', '_:' or '<<'",
+		`<http://example.org/s> <http://example.org/p> << <http://example.org/s> <http://example.org/p> >> .`:                   "'<', '_:', '\"' or '<<'",
+		`<http://example.org/s> <http://example.org/p> << <http://example.org/s> <http://example.org/p> _:b0 )>> .`:             "'>>'",
+		`<http://example.org/s> <http://example.org/p> <<( <http://example.org/s> <http://example.org/p> _:b0 >> .`:             "')>>'",
+		`<http://example.org/s> <http://example.org/p> << <http://example.org/s> <http://example.org/p> _:b0 _:b1 >> .`:         "'>>'",
+		`<http://example.org/s> <http://example.org/p> << <http://example.org/s> <http://example.org/p> <http://example.org/o>`: "'>>'",
+	}
+
+	for ntriple, expected := range errorCases {
+		r := NewReader(strings.NewReader(ntriple))
+		if r.Next() {
+			t.Errorf("Expected an error for %s but got %s", ntriple, r.Triple())
+			continue
+		}
+		err, ok := r.Err().(*ParseError)
+		if !ok || err.Expected != expected {
+			t.Errorf("Expected %s for %s but got %#v", expected, ntriple, r.Err())
+		}
+	}
+
+	r := NewQuadReader(strings.NewReader(`<http://example.org/s> <http://example.org/p> _:b0 << <http://example.org/s> <http://example.org/p> _:b0 >> .`))
+	if r.Next() {
+		t.Errorf("Expected an error but got %s", r.Quad())
+	}
+}
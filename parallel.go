@@ -0,0 +1,437 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"io"
+	"os"
+	"runtime"
+)
+
+// DefaultChunkSize is the number of bytes of input parsed by each task of a
+// ParallelReader unless ChunkSize is set.
+const DefaultChunkSize = 4 << 20
+
+// A ParallelReader reads triples from an N-Triples document, or quads from an
+// N-Quads document, using several goroutines.
+//
+// The input is divided into chunks of roughly equal size that are aligned to
+// the start of lines and parsed concurrently. By default triples are
+// returned in the order they appear in the input. If Unordered is set they
+// are returned in the order that chunks finish parsing, which avoids
+// waiting for slow chunks.
+//
+// The line numbers of errors are counted from the start of the input, as for
+// Reader. Reporting an error may require waiting for all the chunks
+// that precede it to be parsed. If an error stops reading in unordered mode,
+// it is the first error in the input and any triples that had not yet been
+// returned are discarded.
+//
+// The exported fields can be changed to customize the details before the
+// first call to Next.
+type ParallelReader struct {
+	// Unordered, if true, returns triples in the order that chunks are
+	// parsed instead of the order they appear in the input.
+	Unordered bool
+
+	// ChunkSize is the approximate number of bytes in each chunk. If it is
+	// not positive DefaultChunkSize is used.
+	ChunkSize int64
+
+	// Lenient, if true, makes Next skip any line that cannot be parsed
+	// instead of stopping, as for Reader.
+	Lenient bool
+
+	// MaxErrors, if positive, is the number of errors that are skipped in
+	// lenient mode, as for Reader.
+	MaxErrors int
+
+	// ValidateLiterals, if true, makes Next check the lexical form of each
+	// typed literal, as for Reader.
+	ValidateLiterals bool
+
+	// ValidateIRIs, if true, makes Next check the syntax of each IRI, as
+	// for Reader.
+	ValidateIRIs bool
+
+	// Base, if set, is the IRI against which relative IRIs are resolved, as
+	// for Reader.
+	Base string
+
+	r       io.ReaderAt
+	size    int64
+	workers int
+	quads   bool
+	closer  io.Closer
+
+	started bool
+	chunks  int         // number of chunks in the input
+	results chan *chunk // chunks that have been parsed
+	tokens  chan bool   // limits the number of chunks parsed but not yet returned
+	done    chan bool   // closed to stop parsing
+	stopped bool
+
+	received int            // number of chunks received
+	pending  map[int]*chunk // chunks received but not yet returned, in ordered mode
+	queue    []*chunk       // chunks received but not yet returned, in unordered mode
+	next     int            // index of the next chunk to return, in ordered mode
+	lines    []int          // number of lines in each chunk, or -1 if not yet known
+	bases    []int          // number of lines preceding each of the first len(bases) chunks
+	failed   []*chunk       // chunks that ended with an error, in strict mode
+
+	cur  *chunk // the chunk being returned
+	i    int    // index in cur of the next quad to return
+	e    int    // index in cur of the next error to handle
+	q    Quad
+	err  error
+	errs []*ParseError
+}
+
+// A chunk holds the result of parsing one chunk of input.
+type chunk struct {
+	index int
+	quads []Quad
+	errs  []chunkError
+	lines int // number of lines in the chunk
+}
+
+// A chunkError is an error that occurred while parsing a chunk. The line
+// numbers of any ParseError are relative to the start of the chunk.
+type chunkError struct {
+	at  int // number of quads in the chunk that precede the error
+	err error
+}
+
+// NewParallelReader returns a new ParallelReader that reads N-Triples from
+// the first size bytes of r using the given number of goroutines. If workers
+// is not positive then runtime.GOMAXPROCS(0) goroutines are used.
+func NewParallelReader(r io.ReaderAt, size int64, workers int) *ParallelReader {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &ParallelReader{
+		r:       r,
+		size:    size,
+		workers: workers,
+	}
+}
+
+// NewParallelQuadReader returns a new ParallelReader that reads N-Quads from
+// the first size bytes of r, as for NewParallelReader.
+func NewParallelQuadReader(r io.ReaderAt, size int64, workers int) *ParallelReader {
+	pr := NewParallelReader(r, size, workers)
+	pr.quads = true
+	return pr
+}
+
+// OpenParallelReader opens the named N-Triples file and returns a new
+// ParallelReader that reads from it, as for NewParallelReader. The caller
+// must call Close when done.
+func OpenParallelReader(name string, workers int) (*ParallelReader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	pr := NewParallelReader(f, fi.Size(), workers)
+	pr.closer = f
+	return pr, nil
+}
+
+// Close stops any goroutines that are still parsing and closes the file
+// opened by OpenParallelReader.
+func (r *ParallelReader) Close() error {
+	r.stop()
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// Err returns any error encountered while reading. If Err is non-nil then Next will always return false.
+func (r *ParallelReader) Err() error {
+	return r.err
+}
+
+// Errors returns the errors for the lines that have been skipped in lenient mode.
+func (r *ParallelReader) Errors() []*ParseError {
+	return r.errs
+}
+
+// Triple returns the last triple read.
+func (r *ParallelReader) Triple() Triple {
+	return r.q.Triple
+}
+
+// Quad returns the last quad read. The graph label is only set when the
+// ParallelReader was created by NewParallelQuadReader.
+func (r *ParallelReader) Quad() Quad {
+	return r.q
+}
+
+// Next attempts to read the next triple. It returns false if no triple could be read which
+// may indicate an error has occurred or the end of the input has been reached.
+func (r *ParallelReader) Next() bool {
+	if !r.started {
+		r.start()
+	}
+
+	for r.err == nil {
+		if r.cur != nil {
+			if r.e < len(r.cur.errs) && r.cur.errs[r.e].at == r.i {
+				r.handle(r.cur, r.cur.errs[r.e].err)
+				r.e++
+				continue
+			}
+			if r.i < len(r.cur.quads) {
+				r.q = r.cur.quads[r.i]
+				r.i++
+				return true
+			}
+
+			// Allow another chunk to be parsed
+			<-r.tokens
+		}
+
+		r.cur, r.i, r.e = r.nextChunk(), 0, 0
+		if r.cur == nil {
+			break
+		}
+	}
+
+	r.q = Quad{}
+	r.stop()
+	return false
+}
+
+// start divides the input into chunks and starts the goroutines that parse them.
+func (r *ParallelReader) start() {
+	r.started = true
+	if r.ChunkSize <= 0 {
+		r.ChunkSize = DefaultChunkSize
+	}
+
+	r.chunks = int((r.size + r.ChunkSize - 1) / r.ChunkSize)
+	r.lines = make([]int, r.chunks)
+	for i := range r.lines {
+		r.lines[i] = -1
+	}
+	r.bases = append(make([]int, 0, r.chunks), 0)
+	r.pending = map[int]*chunk{}
+
+	r.results = make(chan *chunk, r.workers)
+	r.tokens = make(chan bool, 2*r.workers)
+	r.done = make(chan bool)
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < r.chunks; i++ {
+			select {
+			case r.tokens <- true:
+			case <-r.done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-r.done:
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < r.workers; i++ {
+		go func() {
+			for i := range jobs {
+				c := r.parse(i)
+				select {
+				case r.results <- c:
+				case <-r.done:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// stop stops any goroutines that are still parsing.
+func (r *ParallelReader) stop() {
+	if r.started && !r.stopped {
+		r.stopped = true
+		close(r.done)
+	}
+}
+
+// parse parses the chunk with index i, which contains the lines that start
+// within its bytes.
+func (r *ParallelReader) parse(i int) *chunk {
+	c := &chunk{index: i}
+
+	start := int64(i) * r.ChunkSize
+	end := start + r.ChunkSize
+	if end > r.size {
+		end = r.size
+	}
+
+	begin, err := lineStart(r.r, start, r.size)
+	if err != nil {
+		c.errs = append(c.errs, chunkError{err: err})
+		return c
+	}
+
+	rd := NewReader(io.NewSectionReader(r.r, begin, r.size-begin))
+	rd.Lenient = r.Lenient
+	rd.ValidateLiterals = r.ValidateLiterals
+	rd.ValidateIRIs = r.ValidateIRIs
+	rd.Base = r.Base
+	rd.s.quads = r.quads
+	rd.s.offset = begin
+	rd.s.limit = end
+
+	for {
+		ok := rd.Next()
+		for _, err := range rd.Errors()[len(c.errs):] {
+			c.errs = append(c.errs, chunkError{at: len(c.quads), err: err})
+		}
+		if !ok {
+			break
+		}
+		c.quads = append(c.quads, rd.Quad())
+	}
+	if rd.Err() != nil {
+		c.errs = append(c.errs, chunkError{at: len(c.quads), err: rd.Err()})
+	}
+
+	c.lines = rd.s.line
+	return c
+}
+
+// receive waits for the next chunk to be parsed.
+func (r *ParallelReader) receive() {
+	c := <-r.results
+	r.received++
+
+	r.lines[c.index] = c.lines
+	for n := len(r.bases); n < r.chunks && r.lines[n-1] >= 0; n++ {
+		r.bases = append(r.bases, r.bases[n-1]+r.lines[n-1])
+	}
+
+	if !r.Lenient && len(c.errs) > 0 {
+		r.failed = append(r.failed, c)
+	}
+
+	if r.Unordered {
+		r.queue = append(r.queue, c)
+	} else {
+		r.pending[c.index] = c
+	}
+}
+
+// nextChunk returns the next chunk to return triples from, or nil if there are no more.
+func (r *ParallelReader) nextChunk() *chunk {
+	if r.Unordered {
+		for len(r.queue) == 0 {
+			if r.received == r.chunks {
+				return nil
+			}
+			r.receive()
+		}
+		c := r.queue[0]
+		r.queue = r.queue[1:]
+		return c
+	}
+
+	if r.next == r.chunks {
+		return nil
+	}
+	for r.pending[r.next] == nil {
+		r.receive()
+	}
+	c := r.pending[r.next]
+	delete(r.pending, r.next)
+	r.next++
+	return c
+}
+
+// handle deals with an error that occurred while parsing the chunk c.
+func (r *ParallelReader) handle(c *chunk, err error) {
+	perr, ok := err.(*ParseError)
+	if !ok {
+		r.err = err
+		return
+	}
+
+	// Wait until the number of lines preceding the chunk is known
+	for len(r.bases) <= c.index {
+		r.receive()
+	}
+
+	if !r.Lenient {
+		// Report the first error in the input, which may be in an earlier
+		// chunk when returning chunks unordered.
+		for _, f := range r.failed {
+			if f.index < c.index {
+				c = f
+				perr, ok = f.errs[len(f.errs)-1].err.(*ParseError)
+				if !ok {
+					r.err = f.errs[len(f.errs)-1].err
+					return
+				}
+			}
+		}
+	}
+	perr.Line += r.bases[c.index]
+
+	if !r.Lenient || (r.MaxErrors > 0 && len(r.errs) >= r.MaxErrors) {
+		r.err = perr
+		return
+	}
+	r.errs = append(r.errs, perr)
+}
+
+// lineStart returns the byte offset of the first line that starts at or after
+// pos in the first size bytes of r. Any of \r\n, \r or \n ends a line.
+func lineStart(r io.ReaderAt, pos, size int64) (int64, error) {
+	if pos == 0 {
+		return 0, nil
+	}
+
+	var buf [4096]byte
+	for off := pos - 1; off < size; {
+		n, err := r.ReadAt(buf[:], off)
+		if n == 0 {
+			if err == io.EOF {
+				break
+			}
+			return 0, err
+		}
+
+		for i, c := range buf[:n] {
+			if c != '\n' && c != '\r' {
+				continue
+			}
+			start := off + int64(i) + 1
+			if c == '\r' && start < size {
+				var next [1]byte
+				if _, err := r.ReadAt(next[:], start); err != nil && err != io.EOF {
+					return 0, err
+				}
+				if next[0] == '\n' {
+					start++
+				}
+			}
+			return start, nil
+		}
+		off += int64(n)
+	}
+
+	return size, nil
+}
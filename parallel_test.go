@@ -0,0 +1,199 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// readSequential reads doc with a Reader, returning the triples and any errors.
+func readSequential(doc []byte, lenient bool) ([]string, []*ParseError, error) {
+	r := NewReader(bytes.NewReader(doc))
+	r.Lenient = lenient
+
+	var triples []string
+	for r.Next() {
+		triples = append(triples, r.Triple().String())
+	}
+	return triples, r.Errors(), r.Err()
+}
+
+// readParallel reads doc with a ParallelReader, returning the triples and any errors.
+func readParallel(doc []byte, unordered, lenient bool) ([]string, []*ParseError, error) {
+	r := NewParallelReader(bytes.NewReader(doc), int64(len(doc)), 4)
+	r.ChunkSize = 997
+	r.Unordered = unordered
+	r.Lenient = lenient
+	defer r.Close()
+
+	var triples []string
+	for r.Next() {
+		triples = append(triples, r.Triple().String())
+	}
+	return triples, r.Errors(), r.Err()
+}
+
+// parallelDocuments returns documents that exercise chunk boundaries.
+func parallelDocuments() map[string][]byte {
+	doc := benchmarkDocument(500)
+	return map[string][]byte{
+		"LF":    doc,
+		"CRLF":  bytes.ReplaceAll(doc, []byte("\n"), []byte("\r\n")),
+		"CR":    bytes.ReplaceAll(doc, []byte("\n"), []byte("\r")),
+		"blank": bytes.ReplaceAll(doc, []byte(" .\n"), []byte(" .\n\n# comment\n")),
+		"small": []byte("<http://example.org/s> <http://example.org/p> <http://example.org/o> ."),
+		"empty": {},
+	}
+}
+
+func TestReadParallel(t *testing.T) {
+	for name, doc := range parallelDocuments() {
+		t.Run(name, func(t *testing.T) {
+			expected, _, err := readSequential(doc, false)
+			if err != nil {
+				t.Fatalf("Got unexpected error %v", err)
+			}
+
+			actual, _, err := readParallel(doc, false, false)
+			if err != nil {
+				t.Fatalf("Got unexpected error %v", err)
+			}
+			if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("Expected %d triples in order but got %d", len(expected), len(actual))
+			}
+
+			actual, _, err = readParallel(doc, true, false)
+			if err != nil {
+				t.Fatalf("Got unexpected error %v", err)
+			}
+			sort.Strings(actual)
+			sort.Strings(expected)
+			if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("Expected %d triples but got %d", len(expected), len(actual))
+			}
+		})
+	}
+}
+
+func TestReadParallelErrors(t *testing.T) {
+	for name, doc := range parallelDocuments() {
+		lines := strings.SplitAfter(string(doc), "\n")
+		if len(lines) < 100 {
+			continue
+		}
+		lines[40] = "<http://example.org/s> <p> <http://example.org/o> .\n"
+		lines[77] = "<http://example.org/s> <http://example.org/p> \"unterminated\n"
+		doc = []byte(strings.Join(lines, ""))
+
+		t.Run(name, func(t *testing.T) {
+			expected, _, expectedErr := readSequential(doc, false)
+
+			for _, unordered := range []bool{false, true} {
+				actual, _, err := readParallel(doc, unordered, false)
+				if !unordered && strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+					t.Errorf("Expected %d triples before the error but got %d", len(expected), len(actual))
+				}
+				if *err.(*ParseError) != *expectedErr.(*ParseError) {
+					t.Errorf("Expected %#v but got %#v", expectedErr, err)
+				}
+			}
+		})
+
+		t.Run(name+" lenient", func(t *testing.T) {
+			expected, expectedErrs, _ := readSequential(doc, true)
+			if len(expectedErrs) != 2 {
+				t.Fatalf("Expected 2 errors but got %v", expectedErrs)
+			}
+
+			for _, unordered := range []bool{false, true} {
+				actual, errs, err := readParallel(doc, unordered, true)
+				if err != nil {
+					t.Fatalf("Got unexpected error %v", err)
+				}
+				if len(actual) != len(expected) {
+					t.Errorf("Expected %d triples but got %d", len(expected), len(actual))
+				}
+
+				sort.Slice(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+				if len(errs) != len(expectedErrs) {
+					t.Fatalf("Expected %v but got %v", expectedErrs, errs)
+				}
+				for i := range errs {
+					if *errs[i] != *expectedErrs[i] {
+						t.Errorf("Expected %#v but got %#v", expectedErrs[i], errs[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOpenParallelReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ntriples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	doc := benchmarkDocument(100)
+	name := filepath.Join(dir, "test.nt")
+	if err := ioutil.WriteFile(name, doc, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenParallelReader(name, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for r.Next() {
+		count++
+	}
+	if r.Err() != nil {
+		t.Errorf("Got unexpected error %v", r.Err())
+	}
+	if count != 100 {
+		t.Errorf("Expected 100 but parsed %d triples", count)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Got unexpected error %v", err)
+	}
+}
+
+func TestReadParallelQuads(t *testing.T) {
+	doc := []byte("<http://example.org/s> <http://example.org/p> <http://example.org/o> <http://example.org/g> .\n")
+	r := NewParallelQuadReader(bytes.NewReader(doc), int64(len(doc)), 1)
+	if !r.Next() {
+		t.Fatalf("Got unexpected error %v", r.Err())
+	}
+	if r.Quad().G.Value != "http://example.org/g" {
+		t.Errorf("Expected graph label but got %s", r.Quad())
+	}
+}
+
+func BenchmarkParallelReader(b *testing.B) {
+	doc := benchmarkDocument(100000)
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r := NewParallelReader(bytes.NewReader(doc), int64(len(doc)), 0)
+		r.ChunkSize = 1 << 20
+		r.Unordered = true
+		for r.Next() {
+		}
+		if r.Err() != nil {
+			b.Fatal(r.Err())
+		}
+	}
+}
@@ -0,0 +1,170 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidGraph is returned by Writer.WriteQuad when the graph label of a
+// quad is not an IRI or blank node.
+var ErrInvalidGraph = errors.New("graph label must be an IRI or blank node")
+
+// A Quad is a triple with an optional graph label, as found in N-Quads.
+type Quad struct {
+	Triple
+	G RdfTerm // The graph label, or a term of type RdfUnknown for the default graph
+}
+
+// String returns the N-Quads encoding of the quad. Quads that cannot be
+// encoded give a placeholder, as for Triple.String.
+func (q Quad) String() string {
+	return formatText(appendQuad(nil, q))
+}
+
+// CanonicalString returns the canonical N-Quads encoding of the quad. Quads
+// that cannot be encoded give a placeholder, as for Triple.String.
+func (q Quad) CanonicalString() string {
+	return formatText(appendQuad(nil, canonicalQuad(q)))
+}
+
+// InDefaultGraph reports whether the quad has no graph label.
+func (q Quad) InDefaultGraph() bool {
+	return q.G.TermType == RdfUnknown
+}
+
+// Equal reports whether q and o have Equal terms and graph labels.
+func (q Quad) Equal(o Quad) bool {
+	return q.Triple.Equal(o.Triple) && q.G.Equal(o.G)
+}
+
+// Compare returns an integer comparing q and o by graph label, then as for
+// Triple.Compare. Quads in the default graph sort first.
+func (q Quad) Compare(o Quad) int {
+	if c := q.G.Compare(o.G); c != 0 {
+		return c
+	}
+	return q.Triple.Compare(o.Triple)
+}
+
+// Hash returns a 64-bit hash of q that is the same for quads that are Equal,
+// as for RdfTerm.Hash.
+func (q Quad) Hash() uint64 {
+	return q.G.hash(q.Triple.hash(fnvOffset64))
+}
+
+// ParseQuad parses a single quad written as a line of N-Quads, including the
+// final '.', such as the result of Quad.String. The graph label is
+// optional. Errors are reported as a ParseError on line 1.
+func ParseQuad(text string) (Quad, error) {
+	s := newTextScanner(text)
+	s.quads = true
+	if err := s.scanLineTerms(); err != nil {
+		return Quad{}, err
+	}
+	return Quad{
+		Triple: Triple{S: s.Subject().Term(), P: s.Predicate().Term(), O: s.Object().Term()},
+		G:      s.Graph().Term(),
+	}, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The quad is
+// encoded as a line of N-Quads without a line ending, as for String. An error
+// is returned if the quad is not valid, as for Validate.
+func (q Quad) MarshalText() ([]byte, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return appendQuad(nil, q)
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The quad
+// is parsed as for ParseQuad.
+func (q *Quad) UnmarshalText(text []byte) error {
+	quad, err := ParseQuad(string(text))
+	if err != nil {
+		return err
+	}
+	*q = quad
+	return nil
+}
+
+// NewQuadReader returns a new Reader that reads N-Quads from r. Each line
+// may contain an optional graph label following the object, which is
+// available from Quad. Lines without a graph label are in the default graph.
+func NewQuadReader(r io.Reader) *Reader {
+	nr := NewReader(r)
+	nr.s.quads = true
+	return nr
+}
+
+// Quad returns the last quad read. The graph label is only set when the
+// Reader was created by NewQuadReader.
+func (r *Reader) Quad() Quad {
+	return Quad{Triple: r.t, G: r.g}
+}
+
+// WriteQuad writes a single quad to w in N-Quads encoding. Quads in the
+// default graph are written without a graph label, as for Write.
+func (w *Writer) WriteQuad(q Quad) error {
+	if err := validateQuad(q); err != nil {
+		return err
+	}
+
+	if w.Canonical {
+		q = canonicalQuad(q)
+	}
+
+	var err error
+	w.b, err = appendQuad(w.b[:0], q)
+	if err != nil {
+		return err
+	}
+	w.b = append(w.b, '\n')
+	_, err = w.w.Write(w.b)
+	return err
+}
+
+// Validate checks that q is a well-formed RDF quad, as for Triple.Validate.
+// The graph label must be an IRI or blank node, or a term of type RdfUnknown
+// for the default graph. It returns one of the errors returned by
+// Writer.WriteQuad.
+func (q Quad) Validate() error {
+	return validateQuad(q)
+}
+
+// validateQuad checks that each term of q is valid for its position.
+func validateQuad(q Quad) error {
+	if err := validateTriple(q.Triple); err != nil {
+		return err
+	}
+
+	switch q.G.TermType {
+	case RdfUnknown:
+		return nil
+	case RdfIri, RdfBlank:
+		return validateTerm(q.G)
+	}
+	return ErrInvalidGraph
+}
+
+// canonicalQuad returns q with each term in canonical form.
+func canonicalQuad(q Quad) Quad {
+	return Quad{
+		Triple: canonicalTriple(q.Triple),
+		G:      q.G,
+	}
+}
+
+// appendQuad appends the N-Quads encoding of q, without a trailing newline, to dst.
+func appendQuad(dst []byte, q Quad) ([]byte, error) {
+	if q.InDefaultGraph() {
+		return appendTriple(dst, q.Triple)
+	}
+	dst, err := appendTerms(dst, q.S, q.P, q.O, q.G)
+	return append(dst, ' ', '.'), err
+}
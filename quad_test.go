@@ -0,0 +1,171 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var quadCases = map[string]Quad{
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> .": {
+		Triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+		},
+	},
+
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> <http://example.org/graph1> .": {
+		Triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+		},
+		G: RdfTerm{Value: "http://example.org/graph1", TermType: RdfIri},
+	},
+
+	"_:anon <http://example.org/property> \"chat\"@fr _:g1 .": {
+		Triple: Triple{
+			S: RdfTerm{Value: "anon", TermType: RdfBlank},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "chat", Language: "fr", TermType: RdfLiteral},
+		},
+		G: RdfTerm{Value: "g1", TermType: RdfBlank},
+	},
+
+	"_:anon<http://example.org/property>\"1\"^^<http://www.w3.org/2001/XMLSchema#integer><http://example.org/graph1>. # comment": {
+		Triple: Triple{
+			S: RdfTerm{Value: "anon", TermType: RdfBlank},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "1", DataType: "http://www.w3.org/2001/XMLSchema#integer", TermType: RdfLiteral},
+		},
+		G: RdfTerm{Value: "http://example.org/graph1", TermType: RdfIri},
+	},
+
+	"<http://example.org/resource1> <http://example.org/property> _:o _:g.": {
+		Triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "o", TermType: RdfBlank},
+		},
+		G: RdfTerm{Value: "g", TermType: RdfBlank},
+	},
+}
+
+var negativeQuadCases = map[string]error{
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> \"graph\" .":                                     ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> <graph1> .":                                      ErrRelativeIri,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> <http://example.org/graph1> _:g .":               ErrTermCount,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> <http://example.org/graph1>":                     ErrUnterminatedTriple,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> <http://example.org/graph1> , <http://e.org/> .": ErrUnexpectedCharacter,
+}
+
+func TestReadQuads(t *testing.T) {
+	for nquad, expected := range quadCases {
+		t.Run("", func(t *testing.T) {
+			r := NewQuadReader(strings.NewReader(nquad))
+			if !r.Next() {
+				t.Fatalf("Expected %s but got error %s", expected, r.Err())
+			}
+
+			if r.Quad() != expected {
+				t.Errorf("Expected %s but got %s", expected, r.Quad())
+			}
+			if r.Triple() != expected.Triple {
+				t.Errorf("Expected %s but got %s", expected.Triple, r.Triple())
+			}
+		})
+	}
+}
+
+func TestReadQuadErrors(t *testing.T) {
+	for nquad, expected := range negativeQuadCases {
+		t.Run("", func(t *testing.T) {
+			r := NewQuadReader(strings.NewReader(nquad))
+			r.Next()
+			err := r.Err()
+			if err == nil {
+				t.Errorf("Expected %s for %s but no error reported", expected, nquad)
+			} else if err.(*ParseError).Err != expected {
+				t.Errorf("Expected %s for %s but got error %s", expected, nquad, err.(*ParseError).Err)
+			}
+		})
+	}
+}
+
+func TestParseQuad(t *testing.T) {
+	for nquad, expected := range quadCases {
+		actual, err := ParseQuad(nquad)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, nquad)
+		} else if actual != expected {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+	}
+
+	for nquad, expected := range negativeQuadCases {
+		_, err := ParseQuad(nquad)
+		if perr, ok := err.(*ParseError); !ok || perr.Err != expected {
+			t.Errorf("Expected %s for %s but got %v", expected, nquad, err)
+		}
+	}
+}
+
+func TestReadTriplesRejectsGraphLabel(t *testing.T) {
+	r := NewReader(strings.NewReader("<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> <http://example.org/graph1> ."))
+	if r.Next() {
+		t.Fatalf("Expected error but read %s", r.Triple())
+	}
+	if err, ok := r.Err().(*ParseError); !ok || err.Err != ErrTermCount {
+		t.Errorf("Expected %s but got %v", ErrTermCount, r.Err())
+	}
+}
+
+func TestWriteQuadRoundTrip(t *testing.T) {
+	var quads []Quad
+	for _, quad := range quadCases {
+		quads = append(quads, quad)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, quad := range quads {
+		if err := w.WriteQuad(quad); err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+	}
+	w.Flush()
+
+	count := 0
+	r := NewQuadReader(strings.NewReader(buf.String()))
+	for r.Next() {
+		if r.Quad() != quads[count] {
+			t.Errorf("Expected %s but got %s", quads[count], r.Quad())
+		}
+		count++
+	}
+
+	if r.Err() != nil {
+		t.Fatalf("Got unexpected error %v", r.Err())
+	}
+
+	if count != len(quads) {
+		t.Errorf("Expected %d but only parsed %d quads", len(quads), count)
+	}
+}
+
+func TestWriteQuadErrors(t *testing.T) {
+	q := quadCases["<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> <http://example.org/graph1> ."]
+	q.G = RdfTerm{Value: "graph", TermType: RdfLiteral}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteQuad(q); err != ErrInvalidGraph {
+		t.Errorf("Expected %v but got %v", ErrInvalidGraph, err)
+	}
+}
@@ -0,0 +1,320 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"errors"
+)
+
+// These are the errors that can be returned when building a Query or
+// evaluating an Expression.
+var (
+	ErrInvalidVariable   = errors.New("invalid variable name")
+	ErrInvalidExpression = errors.New("invalid filter expression")
+	ErrUnboundVariable   = errors.New("variable is not bound")
+	ErrTypeMismatch      = errors.New("operands have incompatible types")
+	ErrDivisionByZero    = errors.New("division by zero")
+)
+
+// A TripleStore is a set of triples that can be searched by pattern, such as
+// a Graph.
+type TripleStore interface {
+	// Match returns the triples that match a pattern, as for Graph.Match. A
+	// nil subject, predicate or object matches any term.
+	Match(s, p, o *RdfTerm) TripleSource
+
+	// Count returns the number of triples that match a pattern, as for
+	// Match, or an estimate of it. It is used to plan the order in which
+	// the patterns of a Query are matched.
+	Count(s, p, o *RdfTerm) int
+}
+
+// A PatternTerm is a term of a TriplePattern: either a variable or a constant
+// RDF term.
+type PatternTerm struct {
+	// Variable is the name of the variable, excluding the leading '?', or
+	// "" if the term is a constant.
+	Variable string
+
+	// Term is the constant term. It is ignored for a variable.
+	Term RdfTerm
+}
+
+// NewVariable returns a variable for use in the patterns of a Query. The name
+// excludes the leading '?' and must be a valid SPARQL variable name. It
+// returns ErrInvalidVariable if the name is not valid.
+func NewVariable(name string) (PatternTerm, error) {
+	if !isVarName(name) {
+		return PatternTerm{}, ErrInvalidVariable
+	}
+	return PatternTerm{Variable: name}, nil
+}
+
+// IsVariable reports whether t is a variable.
+func (t PatternTerm) IsVariable() bool {
+	return t.Variable != ""
+}
+
+// String returns the variable name preceded by '?', or the N-Triples
+// encoding of a constant term.
+func (t PatternTerm) String() string {
+	if t.IsVariable() {
+		return "?" + t.Variable
+	}
+	return t.Term.String()
+}
+
+// A TriplePattern is a triple whose terms may be variables.
+type TriplePattern struct {
+	S PatternTerm
+	P PatternTerm
+	O PatternTerm
+}
+
+// A Query is a basic graph pattern, as in SPARQL: a set of triple patterns
+// that must all match the data, with filters that restrict the solutions.
+type Query struct {
+	// Patterns are the triple patterns to match. A variable, such as one
+	// returned by NewVariable, matches any term but every occurrence of a
+	// variable must match the same term. Constant terms, including blank
+	// nodes, must be Equal to the terms they match. Variables within quoted
+	// triples are not supported.
+	Patterns []TriplePattern
+
+	// Filters are expressions that must hold for every solution, as for
+	// Expression.Holds.
+	Filters []Expression
+}
+
+// Execute returns the solutions of q in store. Each solution maps the name of
+// every variable in the patterns of q to the term it matches.
+//
+// The patterns are matched one at a time, in an order chosen to keep the
+// number of partial solutions small. The next pattern is always one that
+// shares a variable with the patterns already matched, if there is one, and
+// of those the one that matches the fewest triples in store considering only
+// its constant terms. Each filter is applied as soon as all of its variables
+// are bound.
+func (q Query) Execute(store TripleStore) *Solutions {
+	s := &Solutions{
+		store:   store,
+		plan:    planPatterns(store, q.Patterns),
+		binding: map[string]RdfTerm{},
+	}
+	s.bound = make([][]string, len(s.plan))
+
+	// Find the pattern that first binds each variable
+	levels := map[string]int{}
+	for level, p := range s.plan {
+		for _, t := range [...]PatternTerm{p.S, p.P, p.O} {
+			if _, ok := levels[t.Variable]; t.IsVariable() && !ok {
+				levels[t.Variable] = level
+			}
+		}
+	}
+
+	s.filters = make([][]Expression, len(s.plan)+1)
+	for _, e := range q.Filters {
+		level := 0
+		for _, name := range e.Variables() {
+			l, ok := levels[name]
+			if !ok {
+				l = len(s.plan) - 1
+			}
+			if l > level {
+				level = l
+			}
+		}
+		s.filters[level] = append(s.filters[level], e)
+	}
+	return s
+}
+
+// planPatterns returns patterns in the order they should be matched, as
+// described for Query.Execute.
+func planPatterns(store TripleStore, patterns []TriplePattern) []TriplePattern {
+	counts := make([]int, len(patterns))
+	for i, p := range patterns {
+		counts[i] = store.Count(constantTerm(p.S), constantTerm(p.P), constantTerm(p.O))
+	}
+
+	plan := make([]TriplePattern, 0, len(patterns))
+	used := make([]bool, len(patterns))
+	bound := map[string]bool{}
+	for len(plan) < len(patterns) {
+		best, bestConnected := -1, false
+		for i, p := range patterns {
+			if used[i] {
+				continue
+			}
+
+			// A pattern is connected if it shares a variable with the plan
+			// or has no variables to multiply the solutions
+			connected, vars := len(bound) == 0, false
+			for _, t := range [...]PatternTerm{p.S, p.P, p.O} {
+				if t.IsVariable() {
+					vars = true
+					connected = connected || bound[t.Variable]
+				}
+			}
+			connected = connected || !vars
+
+			if best < 0 || connected && !bestConnected || connected == bestConnected && counts[i] < counts[best] {
+				best, bestConnected = i, connected
+			}
+		}
+
+		p := patterns[best]
+		used[best] = true
+		plan = append(plan, p)
+		for _, t := range [...]PatternTerm{p.S, p.P, p.O} {
+			if t.IsVariable() {
+				bound[t.Variable] = true
+			}
+		}
+	}
+	return plan
+}
+
+// constantTerm returns a pointer to the term of t, or nil if t is a
+// variable.
+func constantTerm(t PatternTerm) *RdfTerm {
+	if t.IsVariable() {
+		return nil
+	}
+	return &t.Term
+}
+
+// Solutions iterates over the solutions of a Query. Use Next to advance from
+// one solution to the next and Solution to read the current solution.
+type Solutions struct {
+	store   TripleStore
+	plan    []TriplePattern
+	filters [][]Expression // filters to apply after matching each pattern of plan, and for an empty plan
+	its     []TripleSource // triples matching each pattern of plan that has been reached
+	bound   [][]string     // variables bound by the current triple matching each pattern of plan
+
+	binding  map[string]RdfTerm
+	solution map[string]RdfTerm
+	started  bool
+	err      error
+}
+
+// Next advances to the next solution. It returns false when there are no more
+// solutions or an error occurs.
+func (s *Solutions) Next() bool {
+	s.solution = nil
+	if !s.started {
+		s.started = true
+		if len(s.plan) == 0 {
+			// The empty pattern has a single solution with no variables
+			if s.filter(len(s.plan)) {
+				s.solution = map[string]RdfTerm{}
+				return true
+			}
+			return false
+		}
+		s.push()
+	}
+
+	for len(s.its) > 0 {
+		level := len(s.its) - 1
+		s.unbind(level)
+
+		it := s.its[level]
+		if !it.Next() {
+			if err := it.Err(); err != nil {
+				s.err = err
+				s.its = nil
+				return false
+			}
+			s.its = s.its[:level]
+			continue
+		}
+
+		if !s.bind(level, it.Triple()) || !s.filter(level) {
+			continue
+		}
+		if level < len(s.plan)-1 {
+			s.push()
+			continue
+		}
+
+		s.solution = make(map[string]RdfTerm, len(s.binding))
+		for name, t := range s.binding {
+			s.solution[name] = t
+		}
+		return true
+	}
+	return false
+}
+
+// Solution returns the current solution. The map belongs to the caller.
+func (s *Solutions) Solution() map[string]RdfTerm {
+	return s.solution
+}
+
+// Err returns any error reported by the TripleStore while matching patterns.
+func (s *Solutions) Err() error {
+	return s.err
+}
+
+// push starts matching the next pattern of the plan, using the terms bound to
+// any of its variables.
+func (s *Solutions) push() {
+	p := s.plan[len(s.its)]
+	s.its = append(s.its, s.store.Match(s.resolve(p.S), s.resolve(p.P), s.resolve(p.O)))
+}
+
+// resolve returns the term that t matches, or nil if t is an unbound variable.
+func (s *Solutions) resolve(t PatternTerm) *RdfTerm {
+	if !t.IsVariable() {
+		return &t.Term
+	}
+	if b, ok := s.binding[t.Variable]; ok {
+		return &b
+	}
+	return nil
+}
+
+// bind binds the variables of the pattern at level of the plan to the terms
+// of t. It returns false if a variable is already bound to a different term.
+func (s *Solutions) bind(level int, t Triple) bool {
+	p := s.plan[level]
+	terms := [...]RdfTerm{t.S, t.P, t.O}
+	for i, v := range [...]PatternTerm{p.S, p.P, p.O} {
+		if !v.IsVariable() {
+			continue
+		}
+		if b, ok := s.binding[v.Variable]; ok {
+			if !b.Equal(terms[i]) {
+				return false
+			}
+			continue
+		}
+		s.binding[v.Variable] = terms[i]
+		s.bound[level] = append(s.bound[level], v.Variable)
+	}
+	return true
+}
+
+// unbind removes the bindings made by the pattern at level of the plan.
+func (s *Solutions) unbind(level int) {
+	for _, name := range s.bound[level] {
+		delete(s.binding, name)
+	}
+	s.bound[level] = s.bound[level][:0]
+}
+
+// filter reports whether all the filters to be applied at level hold.
+func (s *Solutions) filter(level int) bool {
+	for _, e := range s.filters[level] {
+		if !e.Holds(s.binding) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,333 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const queryDocument = `<http://example.org/alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Person> .
+<http://example.org/alice> <http://xmlns.com/foaf/0.1/name> "Alice"@en .
+<http://example.org/alice> <http://xmlns.com/foaf/0.1/age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/alice> <http://xmlns.com/foaf/0.1/knows> <http://example.org/bob> .
+<http://example.org/bob> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Person> .
+<http://example.org/bob> <http://xmlns.com/foaf/0.1/name> "Bob" .
+<http://example.org/bob> <http://xmlns.com/foaf/0.1/name> "Robert"@en-GB .
+<http://example.org/bob> <http://xmlns.com/foaf/0.1/age> "7.5"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<http://example.org/bob> <http://xmlns.com/foaf/0.1/knows> <http://example.org/bob> .
+<http://example.org/rex> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Dog> .
+<http://example.org/rex> <http://xmlns.com/foaf/0.1/name> "Rex"@en .
+`
+
+// pattern returns a triple pattern from terms written as in N-Triples or as
+// variables starting with '?'.
+func pattern(t *testing.T, terms ...string) TriplePattern {
+	var parsed [3]PatternTerm
+	for i, text := range terms {
+		var err error
+		if strings.HasPrefix(text, "?") {
+			parsed[i], err = NewVariable(text[1:])
+		} else {
+			parsed[i].Term, err = ParseTerm(text)
+		}
+		if err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+	}
+	return TriplePattern{S: parsed[0], P: parsed[1], O: parsed[2]}
+}
+
+// variable returns an expression for the variable with the given name.
+func variable(name string) Expression {
+	return Expression{Variable: name}
+}
+
+// constant returns an expression for a term written as in N-Triples.
+func constant(text string) Expression {
+	t, err := ParseTerm(text)
+	if err != nil {
+		panic(err)
+	}
+	return Expression{Term: t}
+}
+
+// sortedSolutions returns each solution as a line of sorted bindings, in
+// sorted order.
+func sortedSolutions(t *testing.T, s *Solutions) []string {
+	lines := []string{}
+	for s.Next() {
+		var bindings []string
+		for name, term := range s.Solution() {
+			bindings = append(bindings, "?"+name+"="+term.String())
+		}
+		sort.Strings(bindings)
+		lines = append(lines, strings.Join(bindings, " "))
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Got unexpected error %v", err)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestNewVariable(t *testing.T) {
+	for _, name := range []string{"name", "1st", "_x"} {
+		v, err := NewVariable(name)
+		if err != nil {
+			t.Errorf("Got unexpected error %v", err)
+			continue
+		}
+		if !v.IsVariable() || v.Variable != name {
+			t.Errorf("Expected variable %s but got %v", name, v)
+		}
+		if expected := "?" + name; v.String() != expected {
+			t.Errorf("Expected %s but got %s", expected, v.String())
+		}
+	}
+
+	for _, name := range []string{"", "first-name", "?x", "a b"} {
+		if _, err := NewVariable(name); err != ErrInvalidVariable {
+			t.Errorf("Expected %v but got %v", ErrInvalidVariable, err)
+		}
+	}
+
+	constant := PatternTerm{Term: NewLiteral("chat")}
+	if constant.IsVariable() {
+		t.Errorf("Expected %v not to be a variable", constant)
+	}
+	if expected := `"chat"`; constant.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, constant.String())
+	}
+}
+
+func TestQueryExecute(t *testing.T) {
+	var g Graph
+	if err := g.AddFrom(NewReader(strings.NewReader(queryDocument))); err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+
+	const (
+		rdfType = "<http://www.w3.org/1999/02/22-rdf-syntax-ns#type>"
+		name    = "<http://xmlns.com/foaf/0.1/name>"
+		age     = "<http://xmlns.com/foaf/0.1/age>"
+		knows   = "<http://xmlns.com/foaf/0.1/knows>"
+		person  = "<http://example.org/Person>"
+	)
+
+	cases := []struct {
+		query    Query
+		expected []string
+	}{
+		{
+			query: Query{Patterns: []TriplePattern{
+				pattern(t, "?x", rdfType, person),
+				pattern(t, "?x", name, "?n"),
+			}},
+			expected: []string{
+				`?n="Alice"@en ?x=<http://example.org/alice>`,
+				`?n="Bob" ?x=<http://example.org/bob>`,
+				`?n="Robert"@en-GB ?x=<http://example.org/bob>`,
+			},
+		},
+		{
+			// A variable that appears twice must match the same term
+			query: Query{Patterns: []TriplePattern{
+				pattern(t, "?x", knows, "?x"),
+			}},
+			expected: []string{`?x=<http://example.org/bob>`},
+		},
+		{
+			query: Query{Patterns: []TriplePattern{
+				pattern(t, "?x", knows, "?y"),
+				pattern(t, "?y", name, "?n"),
+			}},
+			expected: []string{
+				`?n="Bob" ?x=<http://example.org/alice> ?y=<http://example.org/bob>`,
+				`?n="Bob" ?x=<http://example.org/bob> ?y=<http://example.org/bob>`,
+				`?n="Robert"@en-GB ?x=<http://example.org/alice> ?y=<http://example.org/bob>`,
+				`?n="Robert"@en-GB ?x=<http://example.org/bob> ?y=<http://example.org/bob>`,
+			},
+		},
+		{
+			query: Query{
+				Patterns: []TriplePattern{pattern(t, "?x", name, "?n")},
+				Filters: []Expression{{Op: "langmatches", Args: []Expression{
+					{Op: "lang", Args: []Expression{variable("n")}},
+					constant(`"en"`),
+				}}},
+			},
+			expected: []string{
+				`?n="Alice"@en ?x=<http://example.org/alice>`,
+				`?n="Rex"@en ?x=<http://example.org/rex>`,
+				`?n="Robert"@en-GB ?x=<http://example.org/bob>`,
+			},
+		},
+		{
+			query: Query{
+				Patterns: []TriplePattern{pattern(t, "?x", name, "?n")},
+				Filters: []Expression{{Op: "=", Args: []Expression{
+					{Op: "lang", Args: []Expression{variable("n")}},
+					constant(`""`),
+				}}},
+			},
+			expected: []string{`?n="Bob" ?x=<http://example.org/bob>`},
+		},
+		{
+			query: Query{
+				Patterns: []TriplePattern{
+					pattern(t, "?x", age, "?a"),
+					pattern(t, "?x", name, "?n"),
+				},
+				Filters: []Expression{
+					{Op: ">", Args: []Expression{variable("a"), constant(`"10.0"^^<http://www.w3.org/2001/XMLSchema#double>`)}},
+				},
+			},
+			expected: []string{`?a="42"^^<http://www.w3.org/2001/XMLSchema#integer> ?n="Alice"@en ?x=<http://example.org/alice>`},
+		},
+		{
+			query: Query{
+				Patterns: []TriplePattern{pattern(t, "?x", rdfType, "?type")},
+				Filters: []Expression{
+					{Op: "=", Args: []Expression{variable("x"), constant("<http://example.org/rex>")}},
+				},
+			},
+			expected: []string{`?type=<http://example.org/Dog> ?x=<http://example.org/rex>`},
+		},
+		{
+			// A filter on a variable that is never bound rejects every solution
+			query: Query{
+				Patterns: []TriplePattern{pattern(t, "?x", rdfType, "?type")},
+				Filters:  []Expression{{Op: "=", Args: []Expression{variable("y"), constant(`"1"`)}}},
+			},
+			expected: []string{},
+		},
+		{
+			query: Query{
+				Patterns: []TriplePattern{pattern(t, "?x", rdfType, "?type")},
+				Filters: []Expression{{Op: "!", Args: []Expression{
+					{Op: "bound", Args: []Expression{variable("y")}},
+				}}},
+			},
+			expected: []string{
+				`?type=<http://example.org/Dog> ?x=<http://example.org/rex>`,
+				`?type=<http://example.org/Person> ?x=<http://example.org/alice>`,
+				`?type=<http://example.org/Person> ?x=<http://example.org/bob>`,
+			},
+		},
+		{
+			query: Query{Patterns: []TriplePattern{
+				pattern(t, "<http://example.org/alice>", knows, "<http://example.org/bob>"),
+			}},
+			expected: []string{""},
+		},
+		{
+			query: Query{Patterns: []TriplePattern{
+				pattern(t, "?x", rdfType, person),
+				pattern(t, "<http://example.org/alice>", knows, "<http://example.org/alice>"),
+			}},
+			expected: []string{},
+		},
+		{
+			query: Query{Patterns: []TriplePattern{
+				pattern(t, "?x", "<http://example.org/missing>", "?y"),
+			}},
+			expected: []string{},
+		},
+		{
+			// The empty pattern has one solution
+			query:    Query{},
+			expected: []string{""},
+		},
+		{
+			query:    Query{Filters: []Expression{constant(`"false"^^<http://www.w3.org/2001/XMLSchema#boolean>`)}},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		actual := sortedSolutions(t, tc.query.Execute(&g))
+		if strings.Join(actual, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("Expected %q but got %q", tc.expected, actual)
+		}
+	}
+}
+
+// countingStore is a TripleStore that wraps a Graph, recording the patterns
+// passed to Match and failing if fail is set.
+type countingStore struct {
+	*Graph
+	matched []string
+	fail    error
+}
+
+func (s *countingStore) Match(subj, pred, obj *RdfTerm) TripleSource {
+	var terms []string
+	for _, t := range []*RdfTerm{subj, pred, obj} {
+		if t == nil {
+			terms = append(terms, "_")
+		} else {
+			terms = append(terms, t.String())
+		}
+	}
+	s.matched = append(s.matched, strings.Join(terms, " "))
+	if s.fail != nil {
+		return &failingSource{err: s.fail}
+	}
+	return s.Graph.Match(subj, pred, obj)
+}
+
+// failingSource is a TripleSource that fails immediately.
+type failingSource struct {
+	err error
+}
+
+func (s *failingSource) Next() bool     { return false }
+func (s *failingSource) Triple() Triple { return Triple{} }
+func (s *failingSource) Err() error     { return s.err }
+
+func TestQueryPlan(t *testing.T) {
+	var g Graph
+	if err := g.AddFrom(NewReader(strings.NewReader(queryDocument))); err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	store := &countingStore{Graph: &g}
+
+	// The most selective pattern is matched first, then the patterns
+	// connected to it
+	q := Query{Patterns: []TriplePattern{
+		pattern(t, "?x", "<http://www.w3.org/1999/02/22-rdf-syntax-ns#type>", "?type"),
+		pattern(t, "?y", "<http://xmlns.com/foaf/0.1/name>", "?n"),
+		pattern(t, "?x", "<http://xmlns.com/foaf/0.1/knows>", "?y"),
+		pattern(t, "?x", "<http://xmlns.com/foaf/0.1/age>", `"42"^^<http://www.w3.org/2001/XMLSchema#integer>`),
+	}}
+	expected := []string{
+		`_ <http://xmlns.com/foaf/0.1/age> "42"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+		`<http://example.org/alice> <http://xmlns.com/foaf/0.1/knows> _`,
+		`<http://example.org/alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> _`,
+		`<http://example.org/bob> <http://xmlns.com/foaf/0.1/name> _`,
+	}
+
+	actual := sortedSolutions(t, q.Execute(store))
+	if len(actual) != 2 {
+		t.Errorf("Expected 2 solutions but got %q", actual)
+	}
+	if strings.Join(store.matched, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %q but got %q", expected, store.matched)
+	}
+
+	errFailed := errors.New("failed")
+	store = &countingStore{Graph: &g, fail: errFailed}
+	s := q.Execute(store)
+	if s.Next() {
+		t.Errorf("Expected no solutions but got %v", s.Solution())
+	}
+	if s.Err() != errFailed {
+		t.Errorf("Expected %v but got %v", errFailed, s.Err())
+	}
+}
@@ -0,0 +1,757 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// A RawTerm is an RdfTerm whose values refer to the internal buffers of a
+// Scanner. It is only valid until the next call to Scan.
+type RawTerm struct {
+	Value    []byte
+	Language []byte
+	DataType []byte
+	TermType TermType
+
+	// Triple holds the subject, predicate and object of a term of type
+	// RdfTriple.
+	Triple []RawTerm
+}
+
+// Term returns a copy of t as an RdfTerm.
+func (t RawTerm) Term() RdfTerm {
+	term := RdfTerm{
+		Value:    string(t.Value),
+		Language: string(t.Language),
+		DataType: string(t.DataType),
+		TermType: t.TermType,
+	}
+	if len(t.Triple) == 3 {
+		term.Triple = &Triple{S: t.Triple[0].Term(), P: t.Triple[1].Term(), O: t.Triple[2].Term()}
+	}
+	return term
+}
+
+// A Scanner reads triples from an N-Triples document, or quads from an
+// N-Quads document, one line at a time.
+//
+// A Scanner works directly on the bytes of each line and only decodes UTF-8
+// where the grammar requires it. The terms it returns refer to its internal
+// buffers, so scanning a line does not allocate unless a term contains
+// escapes or quoted triples. Use Reader when the terms must outlive the next
+// call to Scan.
+//
+// Quoted triples are accepted as in N-Triples-star, written << s p o >> in
+// the subject or object of a triple, as are RDF 1.2 triple terms, written
+// <<( s p o )>> in the object. Both are scanned as terms of type RdfTriple
+// and may be nested.
+//
+// The exported fields can be changed to customize the details before the
+// first call to Scan.
+type Scanner struct {
+	// Lenient, if true, makes Scan skip any line that cannot be parsed
+	// instead of stopping. The ParseError for each skipped line is recorded
+	// and scanning resumes at the start of the following line. The recorded
+	// errors are returned by Errors.
+	//
+	// Invalid UTF-8 is an error in IRIs and blank node labels. In literals
+	// it is also an error unless Lenient is set, in which case each invalid
+	// byte is replaced by U+FFFD.
+	Lenient bool
+
+	// MaxErrors, if positive, is the number of errors that are skipped in
+	// lenient mode. Once another error occurs Scan returns false and Err
+	// returns that error.
+	MaxErrors int
+
+	// ValidateLiterals, if true, makes Scan check that the lexical form of
+	// each typed literal is valid for its datatype, as for ValidateLiteral.
+	// An ill-typed literal is reported as a ParseError at the start of the
+	// literal.
+	ValidateLiterals bool
+
+	// ValidateIRIs, if true, makes Scan check that each IRI conforms to the
+	// syntax of RFC 3987. A malformed IRI is reported as a ParseError at the
+	// start of the IRI with ErrMalformedIri.
+	ValidateIRIs bool
+
+	// Base, if set, is the absolute IRI against which relative IRIs are
+	// resolved instead of reporting ErrRelativeIri. N-Triples only allows
+	// absolute IRIs, so this is only needed to read documents that do not
+	// conform to the specification.
+	Base string
+
+	r     *bufio.Reader
+	quads bool // whether an optional graph label may follow the object
+
+	line       int
+	limit      int64  // if positive, no line starting at or after this byte offset is read
+	offset     int64  // byte offset of the start of the next line
+	lineOffset int64  // byte offset of the start of the current line
+	data       []byte // the current line without its line ending
+	eol        bool   // whether the current line ended with a line ending rather than the end of input
+	pos        int    // index in data of the next byte to scan
+	rest       []byte // input following a bare '\r' that has not been scanned yet
+	long       []byte // holds lines that do not fit in the buffer of r
+	scratch    []byte // holds the values of terms that had to be decoded
+
+	terms [4]RawTerm
+	err   error
+	errs  []*ParseError
+}
+
+// NewScanner returns a new Scanner that reads N-Triples from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r: bufio.NewReaderSize(r, 64*1024),
+	}
+}
+
+// NewQuadScanner returns a new Scanner that reads N-Quads from r. Each line
+// may contain an optional graph label following the object, which is
+// available from Graph.
+func NewQuadScanner(r io.Reader) *Scanner {
+	s := NewScanner(r)
+	s.quads = true
+	return s
+}
+
+// newTextScanner returns a Scanner whose current line is text, for parsing
+// terms and triples outside of a document.
+func newTextScanner(text string) *Scanner {
+	return &Scanner{
+		line: 1,
+		data: []byte(text),
+	}
+}
+
+// Err returns any error encountered while scanning. If Err is non-nil then Scan will always return false.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Errors returns the errors for the lines that have been skipped in lenient mode.
+func (s *Scanner) Errors() []*ParseError {
+	return s.errs
+}
+
+// Subject returns the subject of the last triple scanned.
+func (s *Scanner) Subject() RawTerm {
+	return s.terms[posSubject]
+}
+
+// Predicate returns the predicate of the last triple scanned.
+func (s *Scanner) Predicate() RawTerm {
+	return s.terms[posPredicate]
+}
+
+// Object returns the object of the last triple scanned.
+func (s *Scanner) Object() RawTerm {
+	return s.terms[posObject]
+}
+
+// Graph returns the graph label of the last quad scanned, or a term of type
+// RdfUnknown if it is in the default graph.
+func (s *Scanner) Graph() RawTerm {
+	return s.terms[posGraph]
+}
+
+// Scan attempts to read the next triple from the underlying reader. It returns false if no triple could be read which
+// may indicate an error has occurred or the end of the input stream has been reached.
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	for {
+		err := s.scanLine()
+		if err == nil {
+			return true
+		}
+		if err == io.EOF {
+			return false
+		}
+
+		perr, ok := err.(*ParseError)
+		if !ok || !s.Lenient || (s.MaxErrors > 0 && len(s.errs) >= s.MaxErrors) {
+			s.err = err
+			return false
+		}
+		s.errs = append(s.errs, perr)
+	}
+}
+
+// scanLine scans the next triple, skipping any blank lines and comment lines.
+// It returns io.EOF if the end of the input has been reached.
+func (s *Scanner) scanLine() error {
+	s.terms = [4]RawTerm{}
+
+	// Skip blank lines and comment lines
+	for {
+		if err := s.readLine(); err != nil {
+			return err
+		}
+		s.scratch = s.scratch[:0]
+		s.skipSpace()
+		if s.pos < len(s.data) && s.data[s.pos] != '#' {
+			break
+		}
+	}
+
+	return s.scanLineTerms()
+}
+
+// scanLineTerms scans the terms of a triple or quad from the current
+// position to the end of the line.
+func (s *Scanner) scanLineTerms() error {
+	for pos := posSubject; pos <= posObject; pos++ {
+		if err := s.scanTerm(pos, &s.terms[pos]); err != nil {
+			return err
+		}
+	}
+
+	if s.quads {
+		s.skipSpace()
+		if s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case '<', '_', '"':
+				if err := s.scanTerm(posGraph, &s.terms[posGraph]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return s.scanEnd()
+}
+
+// readLine makes the next line of input current. Any of \r\n, \r or \n ends a line.
+func (s *Scanner) readLine() error {
+	if s.limit > 0 && s.offset >= s.limit {
+		return io.EOF
+	}
+
+	line := s.rest
+	s.rest = nil
+
+	if len(line) == 0 {
+		var err error
+		line, err = s.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			s.long = append(s.long[:0], line...)
+			for err == bufio.ErrBufferFull {
+				line, err = s.r.ReadSlice('\n')
+				s.long = append(s.long, line...)
+			}
+			line = s.long
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) == 0 {
+			return io.EOF
+		}
+	}
+
+	n, end := len(line), len(line)
+	if i := bytes.IndexByte(line, '\r'); i >= 0 {
+		n, end = i, i+1
+		if end < len(line) && line[end] == '\n' {
+			end++
+		}
+		s.rest = line[end:]
+	} else if line[n-1] == '\n' {
+		n--
+	}
+
+	s.line++
+	s.lineOffset = s.offset
+	s.offset += int64(end)
+	s.data = line[:n]
+	s.eol = n < end
+	s.pos = 0
+	return nil
+}
+
+// error creates a new ParseError based on err at index pos of the current line.
+func (s *Scanner) error(pos int, err error) *ParseError {
+	return &ParseError{
+		Line:   s.line,
+		Column: utf8.RuneCount(s.data[:pos]),
+		Offset: s.lineOffset + int64(pos),
+		Text:   string(s.data),
+		Rune:   -1,
+		Err:    err,
+	}
+}
+
+// unexpected creates a new ParseError for the rune at index pos of the
+// current line, describing what was expected instead. The end of the line
+// is reported as an unexpected '\n' and the end of the input as ErrUnexpectedEOF.
+func (s *Scanner) unexpected(pos int, expected string) *ParseError {
+	var e *ParseError
+	switch {
+	case pos < len(s.data):
+		e = s.error(pos, ErrUnexpectedCharacter)
+		e.Rune, _ = utf8.DecodeRune(s.data[pos:])
+	case s.eol:
+		e = s.error(pos, ErrUnexpectedCharacter)
+		e.Rune = '\n'
+	default:
+		e = s.error(pos, ErrUnexpectedEOF)
+	}
+	e.Expected = expected
+	return e
+}
+
+// skipSpace advances past any spaces and tabs.
+func (s *Scanner) skipSpace() {
+	for s.pos < len(s.data) && (s.data[s.pos] == ' ' || s.data[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+// Positions of a term within a triple, used to restrict the kinds of term accepted.
+const (
+	posSubject = iota
+	posPredicate
+	posObject
+	posGraph
+)
+
+// termExpected describes the terms that may begin at each position.
+var termExpected = [...]string{
+	posSubject:   "'<', '_:' or '<<'",
+	posPredicate: "'<'",
+	posObject:    "'<', '_:', '\"' or '<<'",
+	posGraph:     "'<' or '_:'",
+}
+
+// scanTerm scans a single term that is valid for the position pos into t.
+// Leading whitespace is skipped.
+func (s *Scanner) scanTerm(pos int, t *RawTerm) error {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return s.unexpected(s.pos, termExpected[pos])
+	}
+
+	var err error
+	switch c := s.data[s.pos]; {
+	case c == '<' && s.pos+1 < len(s.data) && s.data[s.pos+1] == '<':
+		if pos != posSubject && pos != posObject {
+			return s.unexpected(s.pos, termExpected[pos])
+		}
+		t.TermType = RdfTriple
+		err = s.scanQuotedTriple(pos, t)
+	case c == '<':
+		t.TermType = RdfIri
+		t.Value, err = s.scanIRI()
+	case c == '_' && pos != posPredicate:
+		t.TermType = RdfBlank
+		t.Value, err = s.scanBlankNodeLabel()
+	case c == '"' && pos == posObject:
+		start := s.pos
+		t.TermType = RdfLiteral
+		err = s.scanLiteral(t)
+		if err == nil && s.ValidateLiterals && t.DataType != nil {
+			if lerr := ValidateLiteral(t.Term()); lerr != nil {
+				err = s.error(start, lerr)
+			}
+		}
+	default:
+		err = s.unexpected(s.pos, termExpected[pos])
+	}
+	return err
+}
+
+// scanQuotedTriple scans a quoted triple starting at the opening '<<', or a
+// triple term starting at the opening '<<(' if pos is posObject, including
+// the closing '>>' or ')>>', into t.
+func (s *Scanner) scanQuotedTriple(pos int, t *RawTerm) error {
+	s.pos += 2
+	end := ">>"
+	if s.pos < len(s.data) && s.data[s.pos] == '(' {
+		if pos != posObject {
+			return s.unexpected(s.pos, termExpected[posSubject])
+		}
+		s.pos++
+		end = ")>>"
+	}
+
+	t.Triple = make([]RawTerm, 3)
+	for p := posSubject; p <= posObject; p++ {
+		if err := s.scanTerm(p, &t.Triple[p]); err != nil {
+			return err
+		}
+	}
+
+	s.skipSpace()
+	if !bytes.HasPrefix(s.data[s.pos:], []byte(end)) {
+		return s.unexpected(s.pos, "'"+end+"'")
+	}
+	s.pos += len(end)
+	return nil
+}
+
+// A decoder accumulates the value of a term as it is scanned. The value is a
+// slice of the current line until an escape sequence or invalid UTF-8 is
+// found, after which it is copied into the scratch buffer of the Scanner.
+type decoder struct {
+	s       *Scanner
+	start   int // index in the current line where the value starts
+	copied  int // index in the scratch buffer where the value starts, or -1
+	escaped int // index in the current line up to which the value has been copied
+}
+
+func (s *Scanner) decoder(start int) decoder {
+	return decoder{s: s, start: start, copied: -1}
+}
+
+// write appends r1 to the value in place of the input between the last
+// write and the index i, which must not be part of the value.
+func (d *decoder) write(i int, r1 rune) {
+	s := d.s
+	if d.copied < 0 {
+		d.copied = len(s.scratch)
+		d.escaped = d.start
+	}
+	s.scratch = append(s.scratch, s.data[d.escaped:i]...)
+	var b [utf8.UTFMax]byte
+	s.scratch = append(s.scratch, b[:utf8.EncodeRune(b[:], r1)]...)
+}
+
+// skip marks the input up to index i as consumed by the last write.
+func (d *decoder) skip(i int) {
+	d.escaped = i
+}
+
+// value returns the value that ends at index end of the current line.
+func (d *decoder) value(end int) []byte {
+	s := d.s
+	if d.copied < 0 {
+		return s.data[d.start:end]
+	}
+	s.scratch = append(s.scratch, s.data[d.escaped:end]...)
+	return s.scratch[d.copied:len(s.scratch):len(s.scratch)]
+}
+
+// decodeRune decodes the non-ASCII rune at index i of the current line. It
+// returns the rune and its length in bytes. Invalid UTF-8 is reported as an
+// error unless replace is true, when U+FFFD is written to d in its place.
+func (s *Scanner) decodeRune(d *decoder, i int, replace bool) (rune, int, error) {
+	r1, size := utf8.DecodeRune(s.data[i:])
+	if r1 == utf8.RuneError && size == 1 {
+		if !replace {
+			return 0, 0, s.unexpected(i, "valid UTF-8")
+		}
+		d.write(i, r1)
+		d.skip(i + 1)
+	}
+	return r1, size, nil
+}
+
+// scanIRI scans an IRIREF starting at the opening '<', including the closing
+// '>', and returns the IRI with any escapes decoded.
+func (s *Scanner) scanIRI() ([]byte, error) {
+	d := s.decoder(s.pos + 1)
+	for i := d.start; i < len(s.data); {
+		c := s.data[i]
+		switch {
+		case c == '>':
+			if i == d.start {
+				return nil, s.unexpected(i, "IRI character")
+			}
+			iri := d.value(i)
+			if !isAbsoluteIRIBytes(iri) && s.Base != "" {
+				iri = s.resolve(iri)
+			}
+			if !isAbsoluteIRIBytes(iri) {
+				return nil, s.error(i, ErrRelativeIri)
+			}
+			if s.ValidateIRIs && !isIRIReference(string(iri)) {
+				return nil, s.error(d.start-1, ErrMalformedIri)
+			}
+			s.pos = i + 1
+			return iri, nil
+		case c == '\\':
+			if i+1 >= len(s.data) || (s.data[i+1] != 'u' && s.data[i+1] != 'U') {
+				return nil, s.unexpected(i+1, "'u' or 'U'")
+			}
+			r1, n, err := s.scanUchar(i + 1)
+			if err != nil {
+				return nil, err
+			}
+			d.write(i, r1)
+			i += 1 + n
+			d.skip(i)
+		case c >= utf8.RuneSelf:
+			_, size, err := s.decodeRune(&d, i, false)
+			if err != nil {
+				return nil, err
+			}
+			i += size
+		case !isIRIChar(rune(c)):
+			return nil, s.unexpected(i, "'>' or IRI character")
+		default:
+			i++
+		}
+	}
+	return nil, s.unexpected(len(s.data), "'>' or IRI character")
+}
+
+// resolve resolves the relative IRI iri against Base, storing the result in
+// the scratch buffer.
+func (s *Scanner) resolve(iri []byte) []byte {
+	start := len(s.scratch)
+	s.scratch = append(s.scratch, resolveIRI(s.Base, string(iri))...)
+	return s.scratch[start:]
+}
+
+// scanUchar scans the UCHAR escape whose 'u' or 'U' is at index i and
+// returns the rune it encodes and the length of the escape excluding the '\'.
+func (s *Scanner) scanUchar(i int) (rune, int, error) {
+	n := 4
+	if s.data[i] == 'U' {
+		n = 8
+	}
+
+	var codepoint rune
+	for j := i + 1; j <= i+n; j++ {
+		if j >= len(s.data) {
+			return 0, 0, s.unexpected(j, "hex digit")
+		}
+		d, ok := hexValue(rune(s.data[j]))
+		if !ok {
+			return 0, 0, s.unexpected(j, "hex digit")
+		}
+		codepoint = codepoint<<4 | d
+	}
+
+	if !utf8.ValidRune(codepoint) {
+		e := s.error(i+n, ErrUnexpectedCharacter)
+		e.Expected = "Unicode scalar value"
+		return 0, 0, e
+	}
+	return codepoint, n + 1, nil
+}
+
+// scanBlankNodeLabel scans a BLANK_NODE_LABEL starting at the leading '_'
+// and returns the label without its '_:' prefix.
+func (s *Scanner) scanBlankNodeLabel() ([]byte, error) {
+	i := s.pos + 1
+	if i >= len(s.data) || s.data[i] != ':' {
+		return nil, s.unexpected(i, "':'")
+	}
+	i++
+
+	d := s.decoder(i)
+	end := i
+	for i < len(s.data) {
+		r1, size := rune(s.data[i]), 1
+		if r1 >= utf8.RuneSelf {
+			var err error
+			if r1, size, err = s.decodeRune(&d, i, false); err != nil {
+				return nil, err
+			}
+		}
+
+		if i == d.start {
+			if !isPNCharsU(r1) && r1 != ':' && !isDigit(r1) {
+				return nil, s.unexpected(i, "blank node label character")
+			}
+		} else if !isPNChars(r1) && r1 != ':' && r1 != '.' {
+			break
+		}
+
+		i += size
+		if r1 != '.' {
+			end = i
+		}
+	}
+
+	if end == d.start {
+		return nil, s.unexpected(end, "blank node label character")
+	}
+
+	// A label may not end with '.' so any trailing dots belong to whatever follows
+	s.pos = end
+	return d.value(end), nil
+}
+
+// scanLiteral scans a literal starting at the opening '"', including any
+// language tag or datatype IRI, into t.
+func (s *Scanner) scanLiteral(t *RawTerm) error {
+	d := s.decoder(s.pos + 1)
+	i := d.start
+	for {
+		if i >= len(s.data) {
+			return s.unexpected(i, "'\"' or string character")
+		}
+
+		c := s.data[i]
+		if c == '"' {
+			break
+		}
+
+		switch {
+		case c == '\\':
+			r1, n, err := s.scanEscape(i + 1)
+			if err != nil {
+				return err
+			}
+			d.write(i, r1)
+			i += 1 + n
+			d.skip(i)
+		case c >= utf8.RuneSelf:
+			_, size, err := s.decodeRune(&d, i, s.Lenient)
+			if err != nil {
+				return err
+			}
+			i += size
+		default:
+			i++
+		}
+	}
+
+	t.Value = d.value(i)
+	i++
+
+	if i < len(s.data) {
+		switch s.data[i] {
+		case '@':
+			start := i + 1
+			for i = start; i < len(s.data); i++ {
+				c := rune(s.data[i])
+				if c != '-' && !isAlpha(c) && !isDigit(c) {
+					break
+				}
+			}
+			t.Language = s.data[start:i]
+			if !isLangTagBytes(t.Language) {
+				e := s.error(start, ErrUnexpectedCharacter)
+				e.Expected = "language tag"
+				return e
+			}
+		case '^':
+			i++
+			if i >= len(s.data) || s.data[i] != '^' {
+				return s.unexpected(i, "'^'")
+			}
+			i++
+			if i >= len(s.data) || s.data[i] != '<' {
+				return s.unexpected(i, "'<'")
+			}
+
+			s.pos = i
+			var err error
+			t.DataType, err = s.scanIRI()
+			return err
+		}
+	}
+
+	s.pos = i
+	return nil
+}
+
+// scanEscape scans the ECHAR or UCHAR escape following the '\' at index i-1
+// and returns the rune it encodes and the length of the escape excluding the '\'.
+func (s *Scanner) scanEscape(i int) (rune, int, error) {
+	if i >= len(s.data) {
+		return 0, 0, s.unexpected(i, "escape character")
+	}
+
+	switch c := s.data[i]; c {
+	case '\\', '"', '\'':
+		return rune(c), 1, nil
+	case 't':
+		return '\t', 1, nil
+	case 'b':
+		return '\b', 1, nil
+	case 'n':
+		return '\n', 1, nil
+	case 'r':
+		return '\r', 1, nil
+	case 'f':
+		return '\f', 1, nil
+	case 'u', 'U':
+		return s.scanUchar(i)
+	}
+	return 0, 0, s.unexpected(i, "escape character")
+}
+
+// scanEnd scans the '.' that terminates a triple and the rest of the line,
+// which may only contain whitespace and a comment.
+func (s *Scanner) scanEnd() error {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		if !s.eol {
+			e := s.error(s.pos, ErrUnterminatedTriple)
+			e.Expected = "'.'"
+			return e
+		}
+		return s.unexpected(s.pos, "'.'")
+	}
+
+	switch c := s.data[s.pos]; c {
+	case '.':
+	case '<', '_', '"':
+		e := s.error(s.pos, ErrTermCount)
+		e.Rune, e.Expected = rune(c), "'.'"
+		return e
+	default:
+		return s.unexpected(s.pos, "'.'")
+	}
+
+	s.pos++
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] != '#' {
+		return s.unexpected(s.pos, "end of line or comment")
+	}
+	return nil
+}
+
+// isAbsoluteIRIBytes reports whether b begins with a scheme followed by ':'.
+func isAbsoluteIRIBytes(b []byte) bool {
+	for i, c := range b {
+		switch {
+		case c == ':':
+			return i > 0
+		case isAlpha(rune(c)):
+		case i > 0 && (isDigit(rune(c)) || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// isLangTagBytes reports whether b matches [a-zA-Z]+ ('-' [a-zA-Z0-9]+)*
+func isLangTagBytes(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	primary := true
+	subtagLen := 0
+	for _, c := range b {
+		switch r1 := rune(c); {
+		case r1 == '-':
+			if subtagLen == 0 {
+				return false
+			}
+			primary = false
+			subtagLen = 0
+		case isAlpha(r1), isDigit(r1) && !primary:
+			subtagLen++
+		default:
+			return false
+		}
+	}
+	return subtagLen > 0
+}
@@ -0,0 +1,169 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	for ntriple, expected := range testCases {
+		t.Run("", func(t *testing.T) {
+			s := NewScanner(strings.NewReader(ntriple))
+			if !s.Scan() {
+				t.Fatalf("Expected %s but got error %s", expected, s.Err())
+			}
+
+			actual := Triple{S: s.Subject().Term(), P: s.Predicate().Term(), O: s.Object().Term()}
+			if actual != expected {
+				t.Errorf("Expected %s but got %s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	for ntriple, expected := range negativeCases {
+		t.Run("", func(t *testing.T) {
+			s := NewScanner(strings.NewReader(ntriple))
+			for s.Scan() {
+			}
+			err, ok := s.Err().(*ParseError)
+			if !ok {
+				t.Fatalf("Expected %s for %s but got %v", expected, ntriple, s.Err())
+			}
+			if err.Err != expected {
+				t.Errorf("Expected %s for %s but got error %s", expected, ntriple, err.Err)
+			}
+		})
+	}
+}
+
+func TestScanLineEndings(t *testing.T) {
+	doc := "<http://example.org/s> <http://example.org/p> \"1\" .\r" +
+		"<http://example.org/s> <http://example.org/p> \"2\" .\r\n" +
+		"\r" +
+		"<http://example.org/s> <http://example.org/p> \"3\" .\n" +
+		"<http://example.org/s> <http://example.org/p> \"4\" .\r" +
+		"<http://example.org/s> <http://example.org/p> \"5\" <http://example.org/s> .\r"
+
+	s := NewScanner(strings.NewReader(doc))
+	var values []string
+	for s.Scan() {
+		values = append(values, string(s.Object().Value))
+	}
+
+	if strings.Join(values, ",") != "1,2,3,4" {
+		t.Errorf("Expected 1,2,3,4 but got %v", values)
+	}
+
+	err, ok := s.Err().(*ParseError)
+	if !ok || err.Line != 6 || err.Err != ErrTermCount || err.Offset != int64(strings.LastIndex(doc, "<")) {
+		t.Errorf("Got unexpected error %#v", s.Err())
+	}
+}
+
+func TestScanLongLine(t *testing.T) {
+	long := strings.Repeat("long value ", 20000)
+	doc := "<http://example.org/s> <http://example.org/p> \"" + long + "\" .\n" +
+		"<http://example.org/s> <http://example.org/p> \"short\" .\n"
+
+	s := NewScanner(strings.NewReader(doc))
+	if !s.Scan() || string(s.Object().Value) != long {
+		t.Fatalf("Expected long literal but got error %v", s.Err())
+	}
+	if !s.Scan() || string(s.Object().Value) != "short" {
+		t.Fatalf("Expected short literal but got error %v", s.Err())
+	}
+	if s.Scan() || s.Err() != nil {
+		t.Errorf("Expected end of input but got error %v", s.Err())
+	}
+}
+
+func TestScanInvalidUTF8(t *testing.T) {
+	cases := []struct {
+		doc    string
+		column int
+		offset int64
+	}{
+		{"<http://example.org/\xff> <http://example.org/p> \"a\" .\n", 20, 20},
+		{"_:a\xc3 <http://example.org/p> \"a\" .\n", 3, 3},
+		{"<http://example.org/s> <http://example.org/p> \"a\xffb\" .\n", 48, 48},
+		{"<http://example.org/s> <http://example.org/p> \"\u00e9\xc3\" .\n", 48, 49},
+	}
+	for _, tc := range cases {
+		s := NewScanner(strings.NewReader(tc.doc))
+		if s.Scan() {
+			t.Errorf("Expected an error for %q", tc.doc)
+			continue
+		}
+		err, ok := s.Err().(*ParseError)
+		if !ok || err.Err != ErrUnexpectedCharacter || err.Column != tc.column || err.Offset != tc.offset {
+			t.Errorf("Expected %v at column %d, offset %d for %q but got %#v", ErrUnexpectedCharacter, tc.column, tc.offset, tc.doc, s.Err())
+		}
+	}
+
+	// Literals are repaired in lenient mode
+	doc := "<http://example.org/\xff> <http://example.org/p> \"a\" .\n<http://example.org/s> <http://example.org/p> \"a\xffb\\u0020c\xc3\" .\n"
+	s := NewScanner(strings.NewReader(doc))
+	s.Lenient = true
+	if !s.Scan() {
+		t.Fatalf("Got unexpected error %v", s.Err())
+	}
+	if v := string(s.Object().Value); v != "a\ufffdb c\ufffd" {
+		t.Errorf("Expected invalid UTF-8 to be replaced but got %q", v)
+	}
+	if errs := s.Errors(); len(errs) != 1 || errs[0].Line != 1 {
+		t.Errorf("Expected an error on line 1 but got %v", errs)
+	}
+}
+
+func TestScanQuads(t *testing.T) {
+	for nquad, expected := range quadCases {
+		t.Run("", func(t *testing.T) {
+			s := NewQuadScanner(strings.NewReader(nquad))
+			if !s.Scan() {
+				t.Fatalf("Expected %s but got error %s", expected, s.Err())
+			}
+			if g := s.Graph().Term(); g != expected.G {
+				t.Errorf("Expected %s but got %s", expected.G, g)
+			}
+		})
+	}
+}
+
+func TestScanAllocations(t *testing.T) {
+	doc := benchmarkDocument(100)
+	s := NewScanner(bytes.NewReader(doc))
+	s.Scan()
+
+	allocs := testing.AllocsPerRun(50, func() {
+		if !s.Scan() {
+			t.Fatalf("Got unexpected error %v", s.Err())
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations but got %v per line", allocs)
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	doc := benchmarkDocument(10000)
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s := NewScanner(bytes.NewReader(doc))
+		for s.Scan() {
+		}
+		if s.Err() != nil {
+			b.Fatal(s.Err())
+		}
+	}
+}
@@ -0,0 +1,1477 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// These are the errors specific to SPARQL that can be returned in a
+// ParseError by ParseSPARQL. ErrUnsupportedSPARQL is returned when a query
+// uses a part of SPARQL that ParseSPARQL does not support.
+var (
+	ErrUnsupportedSPARQL = errors.New("unsupported SPARQL feature")
+	ErrDuplicateLimit    = errors.New("LIMIT clause appears more than once")
+	ErrDuplicateOffset   = errors.New("OFFSET clause appears more than once")
+	ErrModifierRange     = errors.New("LIMIT or OFFSET value out of range")
+)
+
+// Forms of SPARQLQuery
+const (
+	SPARQLSelect    = "SELECT"
+	SPARQLAsk       = "ASK"
+	SPARQLConstruct = "CONSTRUCT"
+)
+
+// A SPARQLQuery is a query written in the SPARQL 1.1 query language.
+//
+// The queries that can be parsed are SELECT, ASK and CONSTRUCT queries with
+// a prologue of PREFIX and BASE declarations, a WHERE clause made of triple
+// patterns, groups, OPTIONAL, UNION and FILTER, and the solution modifiers
+// DISTINCT, REDUCED, ORDER BY, LIMIT and OFFSET. Triple patterns are written
+// as in Turtle, with variables in place of terms. Blank nodes in patterns act
+// as variables that are not selected. The operators and functions that may be
+// used in FILTER and ORDER BY are those of Expression, with functions named in
+// any case, together with IN and NOT IN.
+//
+// Datasets, property paths, subqueries, aggregates, BIND, VALUES, MINUS,
+// EXISTS, GRAPH and SERVICE are not supported.
+type SPARQLQuery struct {
+	// Form is the form of the query, one of SPARQLSelect, SPARQLAsk or
+	// SPARQLConstruct.
+	Form string
+
+	// Variables are the names of the variables selected by a SELECT query,
+	// in order. For SELECT * they are the variables in the WHERE clause in
+	// the order they first appear.
+	Variables []string
+
+	// Distinct is true if duplicate solutions are removed, as for SELECT
+	// DISTINCT or SELECT REDUCED.
+	Distinct bool
+
+	// Limit is the maximum number of solutions, or -1 if there is no limit.
+	Limit int
+
+	// Offset is the number of solutions that are skipped.
+	Offset int
+
+	template []TriplePattern
+	where    *sparqlGroup
+	order    []sparqlOrder
+}
+
+// A SPARQLResult is the result of executing a SPARQLQuery. Only the fields
+// for the form of the query are set.
+type SPARQLResult struct {
+	// Variables are the names of the variables selected by a SELECT query.
+	Variables []string
+
+	// Solutions are the solutions of a SELECT query, in order. Each maps the
+	// selected variables to the terms bound to them. Variables that are not
+	// bound in a solution, such as those in an OPTIONAL pattern that did not
+	// match, are missing from its map.
+	Solutions []map[string]RdfTerm
+
+	// Boolean is the result of an ASK query: whether the pattern has any
+	// solution.
+	Boolean bool
+
+	// Triples are the triples built by a CONSTRUCT query, without
+	// duplicates. Blank nodes in the template are given new labels for each
+	// solution, which differ from the labels of blank nodes in the solutions.
+	Triples []Triple
+}
+
+// A sparqlGroup is a group graph pattern, enclosed in braces.
+type sparqlGroup struct {
+	elements []sparqlElement
+	filters  []Expression // filters that apply to the whole group
+}
+
+// Kinds of sparqlElement
+const (
+	elemTriples  = iota // triple patterns
+	elemGroup           // a nested group
+	elemUnion           // alternative groups
+	elemOptional        // an OPTIONAL group
+)
+
+// A sparqlElement is one of the patterns that are joined to make a group.
+type sparqlElement struct {
+	kind    int
+	triples []TriplePattern
+	groups  []*sparqlGroup
+}
+
+// A sparqlOrder is a condition of an ORDER BY clause.
+type sparqlOrder struct {
+	expression Expression
+	descending bool
+}
+
+// ParseSPARQL parses a SPARQL query. Errors are reported as a ParseError,
+// which holds ErrUnsupportedSPARQL if the query is valid SPARQL that uses
+// features that are not supported.
+func ParseSPARQL(query string) (*SPARQLQuery, error) {
+	p := &sparqlParser{
+		r:    NewTurtleReader(strings.NewReader(query)),
+		seen: map[string]bool{},
+	}
+	return p.parseQuery()
+}
+
+// Execute runs q against the triples in store, such as a Graph.
+func (q *SPARQLQuery) Execute(store TripleStore) (*SPARQLResult, error) {
+	solutions, err := q.where.evaluate(store, []map[string]RdfTerm{{}}, true)
+	if err != nil {
+		return nil, err
+	}
+	if q.Form == SPARQLAsk {
+		return &SPARQLResult{Boolean: len(solutions) > 0}, nil
+	}
+
+	if len(q.order) > 0 {
+		keys := make([][]RdfTerm, len(solutions))
+		for i, solution := range solutions {
+			keys[i] = make([]RdfTerm, len(q.order))
+			for j, o := range q.order {
+				// Errors sort first, along with unbound variables
+				keys[i][j], _ = o.expression.Evaluate(solution)
+			}
+		}
+		index := make([]int, len(solutions))
+		for i := range index {
+			index[i] = i
+		}
+		sort.SliceStable(index, func(a, b int) bool {
+			for j, o := range q.order {
+				c := keys[index[a]][j].Compare(keys[index[b]][j])
+				if o.descending {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+		sorted := make([]map[string]RdfTerm, len(solutions))
+		for i, j := range index {
+			sorted[i] = solutions[j]
+		}
+		solutions = sorted
+	}
+
+	result := &SPARQLResult{}
+	if q.Form == SPARQLSelect {
+		result.Variables = q.Variables
+		seen := map[string]bool{}
+		projected := make([]map[string]RdfTerm, 0, len(solutions))
+		for _, solution := range solutions {
+			row := map[string]RdfTerm{}
+			var key strings.Builder
+			for _, name := range q.Variables {
+				if t, ok := solution[name]; ok {
+					row[name] = t
+					key.WriteString(t.CanonicalString())
+				}
+				key.WriteByte('\n')
+			}
+			if q.Distinct {
+				if seen[key.String()] {
+					continue
+				}
+				seen[key.String()] = true
+			}
+			projected = append(projected, row)
+		}
+		solutions = projected
+	}
+
+	if q.Offset >= len(solutions) {
+		solutions = nil
+	} else {
+		solutions = solutions[q.Offset:]
+	}
+	if q.Limit >= 0 && q.Limit < len(solutions) {
+		solutions = solutions[:q.Limit]
+	}
+
+	if q.Form == SPARQLSelect {
+		result.Solutions = solutions
+		return result, nil
+	}
+
+	// New blank nodes must not take the label of a blank node that is bound
+	// in a solution
+	used := map[string]bool{}
+	for _, solution := range solutions {
+		for _, t := range solution {
+			for _, label := range termBlankLabels(nil, t) {
+				used[label] = true
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	labels := 0
+	for _, solution := range solutions {
+		blanks := map[string]RdfTerm{}
+		instantiate := func(t PatternTerm) RdfTerm {
+			switch {
+			case t.IsVariable():
+				return solution[t.Variable]
+			case t.Term.IsBlank():
+				b, ok := blanks[t.Term.Value]
+				if !ok {
+					for used["b"+strconv.Itoa(labels)] {
+						labels++
+					}
+					b = RdfTerm{Value: "b" + strconv.Itoa(labels), TermType: RdfBlank}
+					labels++
+					blanks[t.Term.Value] = b
+				}
+				return b
+			}
+			return t.Term
+		}
+
+		for _, p := range q.template {
+			t := Triple{S: instantiate(p.S), P: instantiate(p.P), O: instantiate(p.O)}
+			// Triples with unbound variables or that are not valid RDF are left out
+			if validateTriple(t) != nil {
+				continue
+			}
+			if key := t.CanonicalString(); !seen[key] {
+				seen[key] = true
+				result.Triples = append(result.Triples, t)
+			}
+		}
+	}
+	return result, nil
+}
+
+// evaluate returns the solutions of g joined with each of the solutions in
+// input. The filters of g are applied only if filter is true, so that they
+// can be applied by the caller as the condition of an OPTIONAL.
+func (g *sparqlGroup) evaluate(store TripleStore, input []map[string]RdfTerm, filter bool) ([]map[string]RdfTerm, error) {
+	solutions := input
+	for _, e := range g.elements {
+		var next []map[string]RdfTerm
+		switch e.kind {
+		case elemTriples:
+			for _, solution := range solutions {
+				var err error
+				if next, err = joinPatterns(store, next, solution, e.triples); err != nil {
+					return nil, err
+				}
+			}
+
+		case elemOptional:
+			optional := e.groups[0]
+			for _, solution := range solutions {
+				matched, err := optional.evaluate(store, []map[string]RdfTerm{solution}, false)
+				if err != nil {
+					return nil, err
+				}
+				n := len(next)
+				for _, m := range matched {
+					if holdAll(optional.filters, m) {
+						next = append(next, m)
+					}
+				}
+				if len(next) == n {
+					next = append(next, solution)
+				}
+			}
+
+		default:
+			// Groups are evaluated on their own so that their filters only
+			// see their own variables
+			var alternatives []map[string]RdfTerm
+			for _, group := range e.groups {
+				matched, err := group.evaluate(store, []map[string]RdfTerm{{}}, true)
+				if err != nil {
+					return nil, err
+				}
+				alternatives = append(alternatives, matched...)
+			}
+			for _, solution := range solutions {
+				for _, a := range alternatives {
+					if merged, ok := merge(solution, a); ok {
+						next = append(next, merged)
+					}
+				}
+			}
+		}
+		solutions = next
+	}
+
+	if !filter || len(g.filters) == 0 {
+		return solutions, nil
+	}
+	var filtered []map[string]RdfTerm
+	for _, solution := range solutions {
+		if holdAll(g.filters, solution) {
+			filtered = append(filtered, solution)
+		}
+	}
+	return filtered, nil
+}
+
+// joinPatterns appends to dst the solutions of the triple patterns that are
+// compatible with solution, merged with it.
+func joinPatterns(store TripleStore, dst []map[string]RdfTerm, solution map[string]RdfTerm, patterns []TriplePattern) ([]map[string]RdfTerm, error) {
+	bind := func(t PatternTerm) PatternTerm {
+		if b, ok := solution[t.Variable]; ok && t.IsVariable() {
+			return PatternTerm{Term: b}
+		}
+		return t
+	}
+
+	q := Query{Patterns: make([]TriplePattern, len(patterns))}
+	for i, p := range patterns {
+		q.Patterns[i] = TriplePattern{S: bind(p.S), P: bind(p.P), O: bind(p.O)}
+	}
+	s := q.Execute(store)
+	for s.Next() {
+		merged, _ := merge(solution, s.Solution())
+		dst = append(dst, merged)
+	}
+	return dst, s.Err()
+}
+
+// merge returns the union of two solutions, or false if they bind a variable
+// to different terms.
+func merge(a, b map[string]RdfTerm) (map[string]RdfTerm, bool) {
+	merged := make(map[string]RdfTerm, len(a)+len(b))
+	for name, t := range a {
+		merged[name] = t
+	}
+	for name, t := range b {
+		if u, ok := merged[name]; ok && !u.Equal(t) {
+			return nil, false
+		}
+		merged[name] = t
+	}
+	return merged, true
+}
+
+// holdAll reports whether all the filters hold for a solution.
+func holdAll(filters []Expression, solution map[string]RdfTerm) bool {
+	for _, e := range filters {
+		if !e.Holds(solution) {
+			return false
+		}
+	}
+	return true
+}
+
+// A sparqlParser parses a SPARQL query. It uses a TurtleReader to read the
+// terminals that SPARQL shares with Turtle and to hold the prefixes and base
+// IRI declared by the query.
+type sparqlParser struct {
+	r      *TurtleReader
+	tok    turtleToken // lookahead token
+	hasTok bool
+
+	template bool            // whether blank nodes are read as terms rather than variables
+	vars     []string        // variables in the order they first appear in patterns
+	seen     map[string]bool // variables in vars
+}
+
+// isKeyword reports whether tok is the keyword name, ignoring case.
+func isKeyword(tok turtleToken, name string) bool {
+	return tok.kind == tokKeyword && strings.EqualFold(tok.text, name)
+}
+
+// peek returns the next token without consuming it.
+func (p *sparqlParser) peek() (turtleToken, error) {
+	if !p.hasTok {
+		tok, err := p.scan()
+		if err != nil {
+			return tok, err
+		}
+		p.tok = tok
+		p.hasTok = true
+	}
+	return p.tok, nil
+}
+
+// nextToken consumes and returns the next token.
+func (p *sparqlParser) nextToken() (turtleToken, error) {
+	tok, err := p.peek()
+	p.hasTok = false
+	return tok, err
+}
+
+// expect consumes the next token and checks that it is the punctuation or
+// keyword text.
+func (p *sparqlParser) expect(text string) error {
+	tok, err := p.nextToken()
+	if err != nil {
+		return err
+	}
+	if !tok.is(tokPunctuation, text) && !isKeyword(tok, text) {
+		return p.r.unexpected(tok)
+	}
+	return nil
+}
+
+// parseQuery parses a whole query.
+func (p *sparqlParser) parseQuery() (*SPARQLQuery, error) {
+	if err := p.parsePrologue(); err != nil {
+		return nil, err
+	}
+
+	tok, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	q := &SPARQLQuery{Limit: -1}
+	switch {
+	case isKeyword(tok, SPARQLSelect):
+		err = p.parseSelect(q)
+	case isKeyword(tok, SPARQLConstruct):
+		err = p.parseConstruct(q)
+	case isKeyword(tok, SPARQLAsk):
+		q.Form = SPARQLAsk
+		err = p.parseWhere(q)
+	case isKeyword(tok, "DESCRIBE"):
+		err = p.r.tokenError(tok, ErrUnsupportedSPARQL)
+	default:
+		err = p.r.unexpected(tok)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.parseModifiers(q); err != nil {
+		return nil, err
+	}
+	if tok, err = p.nextToken(); err != nil {
+		return nil, err
+	}
+	if tok.kind != tokEOF {
+		return nil, p.r.unexpected(tok)
+	}
+
+	if q.Form == SPARQLSelect && q.Variables == nil {
+		q.Variables = p.vars
+	}
+	return q, nil
+}
+
+// parsePrologue parses any PREFIX and BASE declarations.
+func (p *sparqlParser) parsePrologue() error {
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return err
+		}
+		prefix := isKeyword(tok, "PREFIX")
+		if !prefix && !isKeyword(tok, "BASE") {
+			return nil
+		}
+		p.nextToken()
+
+		var name turtleToken
+		if prefix {
+			if name, err = p.nextToken(); err != nil {
+				return err
+			}
+			if name.kind != tokPrefixedName || name.local != "" {
+				return p.r.unexpected(name)
+			}
+		}
+
+		tok, err = p.nextToken()
+		if err != nil {
+			return err
+		}
+		if tok.kind != tokIRI {
+			return p.r.unexpected(tok)
+		}
+		iri, err := p.r.resolve(tok, tok.text)
+		if err != nil {
+			return err
+		}
+
+		if prefix {
+			p.r.prefixes[name.text] = iri
+		} else {
+			p.r.base = iri
+		}
+	}
+}
+
+// parseSelect parses the remainder of a SELECT query after the keyword,
+// up to the solution modifiers.
+func (p *sparqlParser) parseSelect(q *SPARQLQuery) error {
+	q.Form = SPARQLSelect
+	tok, err := p.peek()
+	if err != nil {
+		return err
+	}
+	if isKeyword(tok, "DISTINCT") || isKeyword(tok, "REDUCED") {
+		p.nextToken()
+		q.Distinct = true
+	}
+
+	star := false
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tok.is(tokPunctuation, "*") && !star && q.Variables == nil:
+			star = true
+		case tok.kind == tokVariable && !star:
+			q.Variables = append(q.Variables, tok.text)
+		case tok.is(tokPunctuation, "("):
+			return p.r.tokenError(tok, ErrUnsupportedSPARQL)
+		case star || q.Variables != nil:
+			return p.parseWhere(q)
+		default:
+			return p.r.unexpected(tok)
+		}
+		p.nextToken()
+	}
+}
+
+// parseConstruct parses the remainder of a CONSTRUCT query after the
+// keyword, up to the solution modifiers.
+func (p *sparqlParser) parseConstruct(q *SPARQLQuery) error {
+	q.Form = SPARQLConstruct
+	tok, err := p.peek()
+	if err != nil {
+		return err
+	}
+
+	if !tok.is(tokPunctuation, "{") {
+		// CONSTRUCT WHERE uses the pattern as the template
+		if err := p.parseWhere(q); err != nil {
+			return err
+		}
+		// The short form allows only triple patterns
+		if len(q.where.filters) > 0 {
+			return p.r.tokenError(tok, ErrUnsupportedSPARQL)
+		}
+		for _, e := range q.where.elements {
+			if e.kind != elemTriples {
+				return p.r.tokenError(tok, ErrUnsupportedSPARQL)
+			}
+			q.template = append(q.template, e.triples...)
+		}
+		return nil
+	}
+
+	p.nextToken()
+	p.template = true
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return err
+		}
+		if tok.is(tokPunctuation, "}") {
+			p.nextToken()
+			break
+		}
+		if tok.is(tokPunctuation, ".") {
+			p.nextToken()
+			continue
+		}
+		if err := p.parseTriples(&q.template); err != nil {
+			return err
+		}
+	}
+	p.template = false
+	return p.parseWhere(q)
+}
+
+// parseWhere parses a WHERE clause, in which the keyword WHERE is optional.
+func (p *sparqlParser) parseWhere(q *SPARQLQuery) error {
+	tok, err := p.nextToken()
+	if err != nil {
+		return err
+	}
+	if isKeyword(tok, "FROM") {
+		return p.r.tokenError(tok, ErrUnsupportedSPARQL)
+	}
+	if isKeyword(tok, "WHERE") {
+		if tok, err = p.nextToken(); err != nil {
+			return err
+		}
+	}
+	if !tok.is(tokPunctuation, "{") {
+		return p.r.unexpected(tok)
+	}
+	q.where, err = p.parseGroup()
+	return err
+}
+
+// parseModifiers parses the ORDER BY, LIMIT and OFFSET clauses, if present.
+func (p *sparqlParser) parseModifiers(q *SPARQLQuery) error {
+	tok, err := p.peek()
+	if err != nil {
+		return err
+	}
+	if isKeyword(tok, "GROUP") || isKeyword(tok, "HAVING") {
+		return p.r.tokenError(tok, ErrUnsupportedSPARQL)
+	}
+
+	if isKeyword(tok, "ORDER") {
+		p.nextToken()
+		if err := p.expect("BY"); err != nil {
+			return err
+		}
+	conditions:
+		for {
+			tok, err := p.peek()
+			if err != nil {
+				return err
+			}
+
+			var o sparqlOrder
+			switch {
+			case isKeyword(tok, "ASC") || isKeyword(tok, "DESC"):
+				p.nextToken()
+				o.descending = isKeyword(tok, "DESC")
+				if err := p.expect("("); err != nil {
+					return err
+				}
+				if o.expression, err = p.parseExpression(); err != nil {
+					return err
+				}
+				if err := p.expect(")"); err != nil {
+					return err
+				}
+			case tok.kind == tokVariable, tok.is(tokPunctuation, "("), tok.kind == tokKeyword && isFunction(tok.text):
+				if o.expression, err = p.parsePrimary(); err != nil {
+					return err
+				}
+			case len(q.order) == 0:
+				return p.r.unexpected(tok)
+			default:
+				break conditions
+			}
+			q.order = append(q.order, o)
+		}
+	}
+
+	// LIMIT and OFFSET may appear in either order, but only once each
+	var hasLimit, hasOffset bool
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return err
+		}
+		limit := isKeyword(tok, "LIMIT")
+		switch {
+		case !limit && !isKeyword(tok, "OFFSET"):
+			return nil
+		case limit && hasLimit:
+			return p.r.tokenError(tok, ErrDuplicateLimit)
+		case !limit && hasOffset:
+			return p.r.tokenError(tok, ErrDuplicateOffset)
+		}
+		p.nextToken()
+
+		if tok, err = p.nextToken(); err != nil {
+			return err
+		}
+		if tok.kind != tokInteger {
+			return p.r.unexpected(tok)
+		}
+		n, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return p.r.tokenError(tok, ErrModifierRange)
+		}
+		if limit {
+			q.Limit, hasLimit = n, true
+		} else {
+			q.Offset, hasOffset = n, true
+		}
+	}
+}
+
+// parseGroup parses the remainder of a group graph pattern after the opening '{'.
+func (p *sparqlParser) parseGroup() (*sparqlGroup, error) {
+	g := &sparqlGroup{}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case tok.is(tokPunctuation, "}"):
+			p.nextToken()
+			return g, nil
+
+		case tok.is(tokPunctuation, "."):
+			p.nextToken()
+
+		case isKeyword(tok, "FILTER"):
+			p.nextToken()
+			e, err := p.parseConstraint()
+			if err != nil {
+				return nil, err
+			}
+			g.filters = append(g.filters, e)
+
+		case isKeyword(tok, "OPTIONAL"):
+			p.nextToken()
+			if err := p.expect("{"); err != nil {
+				return nil, err
+			}
+			optional, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			g.elements = append(g.elements, sparqlElement{kind: elemOptional, groups: []*sparqlGroup{optional}})
+
+		case tok.is(tokPunctuation, "{"):
+			e := sparqlElement{kind: elemGroup}
+			for {
+				p.nextToken()
+				group, err := p.parseGroup()
+				if err != nil {
+					return nil, err
+				}
+				e.groups = append(e.groups, group)
+
+				if tok, err = p.peek(); err != nil {
+					return nil, err
+				}
+				if !isKeyword(tok, "UNION") {
+					break
+				}
+				p.nextToken()
+				if tok, err = p.peek(); err != nil {
+					return nil, err
+				}
+				if !tok.is(tokPunctuation, "{") {
+					return nil, p.r.unexpected(tok)
+				}
+				e.kind = elemUnion
+			}
+			g.elements = append(g.elements, e)
+
+		case isKeyword(tok, "MINUS"), isKeyword(tok, "GRAPH"), isKeyword(tok, "SERVICE"),
+			isKeyword(tok, "BIND"), isKeyword(tok, "VALUES"), isKeyword(tok, "SELECT"):
+			return nil, p.r.tokenError(tok, ErrUnsupportedSPARQL)
+
+		default:
+			// Consecutive triple patterns form a single basic graph pattern
+			n := len(g.elements)
+			if n == 0 || g.elements[n-1].kind != elemTriples {
+				g.elements = append(g.elements, sparqlElement{kind: elemTriples})
+				n++
+			}
+			if err := p.parseTriples(&g.elements[n-1].triples); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// parseTriples parses a subject and its predicate object list, appending the
+// triple patterns to dst.
+func (p *sparqlParser) parseTriples(dst *[]TriplePattern) error {
+	tok, err := p.nextToken()
+	if err != nil {
+		return err
+	}
+
+	if tok.is(tokPunctuation, "[") {
+		subject, anon, err := p.parseBlankNodePropertyList(dst)
+		if err != nil {
+			return err
+		}
+
+		// A blank node property list may stand alone
+		if tok, err = p.peek(); err != nil {
+			return err
+		}
+		if !anon && !isVerb(tok) {
+			return nil
+		}
+		return p.parsePredicateObjectList(subject, dst)
+	}
+
+	subject, err := p.parseTerm(tok, dst)
+	if err != nil {
+		return err
+	}
+	return p.parsePredicateObjectList(subject, dst)
+}
+
+// isVerb reports whether tok can start a predicate.
+func isVerb(tok turtleToken) bool {
+	return tok.kind == tokVariable || tok.kind == tokIRI || tok.kind == tokPrefixedName || tok.is(tokKeyword, "a")
+}
+
+// parsePredicateObjectList parses one or more predicates, separated by ';',
+// each followed by a list of objects.
+func (p *sparqlParser) parsePredicateObjectList(subject PatternTerm, dst *[]TriplePattern) error {
+	for {
+		tok, err := p.nextToken()
+		if err != nil {
+			return err
+		}
+
+		var predicate PatternTerm
+		switch {
+		case tok.is(tokKeyword, "a"):
+			predicate = PatternTerm{Term: RdfTerm{Value: rdfType, TermType: RdfIri}}
+		case isVerb(tok):
+			if predicate, err = p.parseTerm(tok, dst); err != nil {
+				return err
+			}
+		case tok.is(tokPunctuation, "^"), tok.is(tokPunctuation, "("), tok.is(tokPunctuation, "!"):
+			return p.r.tokenError(tok, ErrUnsupportedSPARQL)
+		default:
+			return p.r.unexpected(tok)
+		}
+
+		// Property paths are not supported
+		if tok, err = p.peek(); err != nil {
+			return err
+		}
+		if tok.is(tokPunctuation, "/") || tok.is(tokPunctuation, "|") || tok.is(tokPunctuation, "*") {
+			return p.r.tokenError(tok, ErrUnsupportedSPARQL)
+		}
+
+		for {
+			object, err := p.parseObject(dst)
+			if err != nil {
+				return err
+			}
+			*dst = append(*dst, TriplePattern{S: subject, P: predicate, O: object})
+
+			if tok, err = p.peek(); err != nil {
+				return err
+			}
+			if !tok.is(tokPunctuation, ",") {
+				break
+			}
+			p.nextToken()
+		}
+
+		if !tok.is(tokPunctuation, ";") {
+			return nil
+		}
+		for tok.is(tokPunctuation, ";") {
+			p.nextToken()
+			if tok, err = p.peek(); err != nil {
+				return err
+			}
+		}
+
+		// A trailing ';' may end the list
+		if !isVerb(tok) {
+			return nil
+		}
+	}
+}
+
+// parseObject parses a single object, appending any triple patterns
+// produced by nested blank node property lists or collections to dst.
+func (p *sparqlParser) parseObject(dst *[]TriplePattern) (PatternTerm, error) {
+	tok, err := p.nextToken()
+	if err != nil {
+		return PatternTerm{}, err
+	}
+	if tok.is(tokPunctuation, "[") {
+		object, _, err := p.parseBlankNodePropertyList(dst)
+		return object, err
+	}
+	return p.parseTerm(tok, dst)
+}
+
+// parseTerm parses a single term starting with tok, which may be a variable
+// or a collection.
+func (p *sparqlParser) parseTerm(tok turtleToken, dst *[]TriplePattern) (PatternTerm, error) {
+	switch {
+	case tok.kind == tokVariable:
+		if !p.template && !p.seen[tok.text] {
+			p.seen[tok.text] = true
+			p.vars = append(p.vars, tok.text)
+		}
+		return PatternTerm{Variable: tok.text}, nil
+	case tok.kind == tokIRI, tok.kind == tokPrefixedName:
+		t, err := p.r.iri(tok)
+		return PatternTerm{Term: t}, err
+	case tok.kind == tokBlankNode:
+		return p.blankNode(p.r.blankNode(tok.text)), nil
+	case tok.is(tokPunctuation, "("):
+		return p.parseCollection(dst)
+	}
+	t, err := p.parseLiteral(tok)
+	return PatternTerm{Term: t}, err
+}
+
+// parseLiteral parses a literal starting with tok: a string with an optional
+// language tag or datatype, a number, which may have a sign, or a boolean.
+func (p *sparqlParser) parseLiteral(tok turtleToken) (RdfTerm, error) {
+	sign := ""
+	if tok.is(tokPunctuation, "-") || tok.is(tokPunctuation, "+") {
+		sign = tok.text
+		next, err := p.peek()
+		if err != nil {
+			return RdfTerm{}, err
+		}
+		if next.kind != tokInteger && next.kind != tokDecimal && next.kind != tokDouble {
+			return RdfTerm{}, p.r.unexpected(tok)
+		}
+		tok, _ = p.nextToken()
+	}
+
+	switch tok.kind {
+	case tokInteger:
+		return RdfTerm{Value: sign + tok.text, DataType: xsdInteger, TermType: RdfLiteral}, nil
+	case tokDecimal:
+		return RdfTerm{Value: sign + tok.text, DataType: xsdDecimal, TermType: RdfLiteral}, nil
+	case tokDouble:
+		return RdfTerm{Value: sign + tok.text, DataType: xsdDouble, TermType: RdfLiteral}, nil
+	case tokKeyword:
+		if tok.text == "true" || tok.text == "false" {
+			return RdfTerm{Value: tok.text, DataType: xsdBoolean, TermType: RdfLiteral}, nil
+		}
+	case tokString:
+		term := RdfTerm{Value: tok.text, TermType: RdfLiteral}
+		next, err := p.peek()
+		if err != nil {
+			return RdfTerm{}, err
+		}
+		switch next.kind {
+		case tokLangTag:
+			p.nextToken()
+			term.Language = next.text
+		case tokDatatype:
+			p.nextToken()
+			if next, err = p.nextToken(); err != nil {
+				return RdfTerm{}, err
+			}
+			if next.kind != tokIRI && next.kind != tokPrefixedName {
+				return RdfTerm{}, p.r.unexpected(next)
+			}
+			datatype, err := p.r.iri(next)
+			if err != nil {
+				return RdfTerm{}, err
+			}
+			term.DataType = datatype.Value
+		}
+		return term, nil
+	}
+	return RdfTerm{}, p.r.unexpected(tok)
+}
+
+// blankNode returns the term to use for the blank node b: b itself in a
+// CONSTRUCT template and otherwise a variable that cannot clash with the
+// variables of the query.
+func (p *sparqlParser) blankNode(b RdfTerm) PatternTerm {
+	if p.template {
+		return PatternTerm{Term: b}
+	}
+	return PatternTerm{Variable: "_:" + b.Value}
+}
+
+// parseBlankNodePropertyList parses the remainder of a blank node property
+// list after the opening '['. It reports whether the list was empty.
+func (p *sparqlParser) parseBlankNodePropertyList(dst *[]TriplePattern) (PatternTerm, bool, error) {
+	node := p.blankNode(p.r.newBlankNode())
+
+	tok, err := p.peek()
+	if err != nil {
+		return PatternTerm{}, false, err
+	}
+	if tok.is(tokPunctuation, "]") {
+		p.nextToken()
+		return node, true, nil
+	}
+
+	if err := p.parsePredicateObjectList(node, dst); err != nil {
+		return PatternTerm{}, false, err
+	}
+	if err := p.expect("]"); err != nil {
+		return PatternTerm{}, false, err
+	}
+	return node, false, nil
+}
+
+// parseCollection parses the remainder of a collection after the opening '('
+// and returns the head of the list.
+func (p *sparqlParser) parseCollection(dst *[]TriplePattern) (PatternTerm, error) {
+	var items []PatternTerm
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return PatternTerm{}, err
+		}
+		if tok.is(tokPunctuation, ")") {
+			p.nextToken()
+			break
+		}
+
+		item, err := p.parseObject(dst)
+		if err != nil {
+			return PatternTerm{}, err
+		}
+		items = append(items, item)
+	}
+
+	rest := PatternTerm{Term: RdfTerm{Value: rdfNil, TermType: RdfIri}}
+	for i := len(items) - 1; i >= 0; i-- {
+		node := p.blankNode(p.r.newBlankNode())
+		*dst = append(*dst,
+			TriplePattern{S: node, P: PatternTerm{Term: RdfTerm{Value: rdfFirst, TermType: RdfIri}}, O: items[i]},
+			TriplePattern{S: node, P: PatternTerm{Term: RdfTerm{Value: rdfRest, TermType: RdfIri}}, O: rest},
+		)
+		rest = node
+	}
+	return rest, nil
+}
+
+// parseConstraint parses the constraint of a FILTER: a bracketed expression
+// or a function call.
+func (p *sparqlParser) parseConstraint() (Expression, error) {
+	tok, err := p.peek()
+	if err != nil {
+		return Expression{}, err
+	}
+	if !tok.is(tokPunctuation, "(") && tok.kind != tokKeyword {
+		return Expression{}, p.r.unexpected(tok)
+	}
+	return p.parsePrimary()
+}
+
+// isFunction reports whether name is the name of a function of Expression.
+func isFunction(name string) bool {
+	name = strings.ToLower(name)
+	_, ok := opArgs[name]
+	return ok && name[0] >= 'a' && name[0] <= 'z'
+}
+
+// parseExpression parses an expression, starting with the operator of lowest
+// precedence.
+func (p *sparqlParser) parseExpression() (Expression, error) {
+	return p.parseBinary(0)
+}
+
+// sparqlPrecedence lists the binary operators at each level of precedence,
+// from lowest to highest.
+var sparqlPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"=", "!=", "<", ">", "<=", ">="},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+// parseBinary parses an expression made of operators with the given level of
+// precedence or higher. The relational operators, at level 2, do not associate.
+func (p *sparqlParser) parseBinary(level int) (Expression, error) {
+	if level == len(sparqlPrecedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return Expression{}, err
+	}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return Expression{}, err
+		}
+
+		if level == 2 && (isKeyword(tok, "IN") || isKeyword(tok, "NOT")) {
+			return p.parseIn(left)
+		}
+
+		op := ""
+		for _, o := range sparqlPrecedence[level] {
+			if tok.is(tokPunctuation, o) {
+				op = o
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		p.nextToken()
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return Expression{}, err
+		}
+		left = Expression{Op: op, Args: []Expression{left, right}}
+		if level == 2 {
+			return left, nil
+		}
+	}
+}
+
+// parseIn parses the remainder of an IN or NOT IN expression after left,
+// which is rewritten using = and ||.
+func (p *sparqlParser) parseIn(left Expression) (Expression, error) {
+	tok, _ := p.nextToken()
+	not := isKeyword(tok, "NOT")
+	if not {
+		if err := p.expect("IN"); err != nil {
+			return Expression{}, err
+		}
+	}
+	if err := p.expect("("); err != nil {
+		return Expression{}, err
+	}
+
+	in := Expression{Term: NewBoolLiteral(false)}
+	for first := true; ; first = false {
+		tok, err := p.peek()
+		if err != nil {
+			return Expression{}, err
+		}
+		if tok.is(tokPunctuation, ")") {
+			p.nextToken()
+			break
+		}
+		if !first {
+			if err := p.expect(","); err != nil {
+				return Expression{}, err
+			}
+		}
+
+		e, err := p.parseExpression()
+		if err != nil {
+			return Expression{}, err
+		}
+		eq := Expression{Op: "=", Args: []Expression{left, e}}
+		if first {
+			in = eq
+		} else {
+			in = Expression{Op: "||", Args: []Expression{in, eq}}
+		}
+	}
+
+	if not {
+		return Expression{Op: "!", Args: []Expression{in}}, nil
+	}
+	return in, nil
+}
+
+// parseUnary parses an expression with an optional unary operator.
+func (p *sparqlParser) parseUnary() (Expression, error) {
+	tok, err := p.peek()
+	if err != nil {
+		return Expression{}, err
+	}
+
+	switch {
+	case tok.is(tokPunctuation, "!"):
+		p.nextToken()
+		e, err := p.parseUnary()
+		if err != nil {
+			return Expression{}, err
+		}
+		return Expression{Op: "!", Args: []Expression{e}}, nil
+	case tok.is(tokPunctuation, "-"), tok.is(tokPunctuation, "+"):
+		p.nextToken()
+		next, err := p.peek()
+		if err != nil {
+			return Expression{}, err
+		}
+		if next.kind == tokInteger || next.kind == tokDecimal || next.kind == tokDouble {
+			t, err := p.parseLiteral(tok)
+			return Expression{Term: t}, err
+		}
+
+		e, err := p.parseUnary()
+		if err != nil || tok.text == "+" {
+			return e, err
+		}
+		zero := Expression{Term: NewInt64Literal(0)}
+		return Expression{Op: "-", Args: []Expression{zero, e}}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a bracketed expression, a function call or a term.
+func (p *sparqlParser) parsePrimary() (Expression, error) {
+	tok, err := p.nextToken()
+	if err != nil {
+		return Expression{}, err
+	}
+
+	switch {
+	case tok.is(tokPunctuation, "("):
+		e, err := p.parseExpression()
+		if err != nil {
+			return Expression{}, err
+		}
+		return e, p.expect(")")
+	case tok.kind == tokVariable:
+		return Expression{Variable: tok.text}, nil
+	case tok.kind == tokIRI, tok.kind == tokPrefixedName:
+		next, err := p.peek()
+		if err != nil {
+			return Expression{}, err
+		}
+		if next.is(tokPunctuation, "(") {
+			return Expression{}, p.r.tokenError(tok, ErrUnsupportedSPARQL)
+		}
+		t, err := p.r.iri(tok)
+		return Expression{Term: t}, err
+	case tok.kind == tokKeyword && tok.text != "true" && tok.text != "false":
+		return p.parseCall(tok)
+	}
+
+	t, err := p.parseLiteral(tok)
+	return Expression{Term: t}, err
+}
+
+// parseCall parses the remainder of a call to the function named by tok.
+func (p *sparqlParser) parseCall(name turtleToken) (Expression, error) {
+	if !isFunction(name.text) {
+		if isKeyword(name, "EXISTS") || isKeyword(name, "NOT") || sparqlFunctions[strings.ToUpper(name.text)] {
+			return Expression{}, p.r.tokenError(name, ErrUnsupportedSPARQL)
+		}
+		return Expression{}, p.r.unexpected(name)
+	}
+	if err := p.expect("("); err != nil {
+		return Expression{}, err
+	}
+
+	e := Expression{Op: strings.ToLower(name.text)}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return Expression{}, err
+		}
+		if tok.is(tokPunctuation, ")") {
+			p.nextToken()
+			break
+		}
+		if len(e.Args) > 0 {
+			if err := p.expect(","); err != nil {
+				return Expression{}, err
+			}
+		}
+
+		arg, err := p.parseExpression()
+		if err != nil {
+			return Expression{}, err
+		}
+		e.Args = append(e.Args, arg)
+	}
+
+	n := opArgs[e.Op]
+	if len(e.Args) < n[0] || len(e.Args) > n[1] || e.Op == "bound" && e.Args[0].Variable == "" {
+		return Expression{}, p.r.tokenError(name, ErrInvalidExpression)
+	}
+	return e, nil
+}
+
+// sparqlFunctions are the names of the other functions of SPARQL 1.1, which
+// are not supported.
+var sparqlFunctions = map[string]bool{
+	"IRI": true, "URI": true, "BNODE": true, "RAND": true, "ABS": true, "CEIL": true, "FLOOR": true,
+	"ROUND": true, "CONCAT": true, "STRLEN": true, "UCASE": true, "LCASE": true, "ENCODE_FOR_URI": true,
+	"CONTAINS": true, "STRSTARTS": true, "STRENDS": true, "STRBEFORE": true, "STRAFTER": true,
+	"YEAR": true, "MONTH": true, "DAY": true, "HOURS": true, "MINUTES": true, "SECONDS": true,
+	"TIMEZONE": true, "TZ": true, "NOW": true, "UUID": true, "STRUUID": true, "MD5": true, "SHA1": true,
+	"SHA256": true, "SHA384": true, "SHA512": true, "COALESCE": true, "IF": true, "STRLANG": true,
+	"STRDT": true, "SUBSTR": true, "REPLACE": true, "COUNT": true, "SUM": true, "MIN": true, "MAX": true,
+	"AVG": true, "SAMPLE": true, "GROUP_CONCAT": true,
+}
+
+// scan reads the next token.
+func (p *sparqlParser) scan() (turtleToken, error) {
+	r := p.r
+	r1, err := r.skipSpace()
+	if err != nil {
+		if err == io.EOF {
+			return turtleToken{kind: tokEOF, line: r.line, column: r.column + 1}, nil
+		}
+		return turtleToken{}, err
+	}
+
+	tok := turtleToken{kind: tokPunctuation, line: r.line, column: r.column}
+
+	switch {
+	case r1 == '<' && p.isIRIRef():
+		tok.kind = tokIRI
+		tok.text, err = r.scanIRI()
+	case r1 == '<' || r1 == '>' || r1 == '!':
+		// Any of these may be followed by '='
+		tok.text = string(r1)
+		if r2, err2 := r.readRune(); err2 == nil {
+			if r2 == '=' {
+				tok.text += "="
+			} else {
+				r.unreadRune(r2)
+			}
+		}
+	case r1 == '&' || r1 == '|':
+		tok.text = string(r1)
+		if r2, err2 := r.readRune(); err2 == nil {
+			if r2 == r1 {
+				tok.text += tok.text
+			} else {
+				r.unreadRune(r2)
+			}
+		}
+	case r1 == '?' || r1 == '$':
+		tok.kind = tokVariable
+		tok.text, err = p.scanVarName()
+	case r1 == '"' || r1 == '\'':
+		tok.kind = tokString
+		tok.text, err = r.scanString(r1)
+	case r1 == '@':
+		tok.kind = tokLangTag
+		tok.text, err = r.scanLangTag()
+	case r1 == '^':
+		// '^^' introduces a datatype and '^' alone is an inverse path
+		tok.text = "^"
+		if r2, err2 := r.readRune(); err2 == nil {
+			if r2 == '^' {
+				tok.kind = tokDatatype
+			} else {
+				r.unreadRune(r2)
+			}
+		}
+	case r1 == '_':
+		tok.kind = tokBlankNode
+		tok.text, err = r.scanBlankNodeLabel()
+	case r1 == ':' || isPNCharsBase(r1):
+		tok, err = p.scanName(tok, r1)
+	case isDigit(r1):
+		tok, err = r.scanNumber(tok, r1)
+	case r1 == '.':
+		// A '.' followed by a digit starts a decimal or double
+		if r2, err2 := r.readRune(); err2 == nil {
+			r.unreadRune(r2)
+			if isDigit(r2) {
+				return r.scanNumber(tok, r1)
+			}
+		}
+		tok.text = "."
+	case strings.ContainsRune("{}()[];,=*/+-", r1):
+		tok.text = string(r1)
+	default:
+		err = r.error(ErrUnexpectedCharacter)
+	}
+
+	return tok, err
+}
+
+// isIRIRef reports whether the runes following a '<' complete an IRIREF, in
+// which case the '<' is not an operator. The runes are not consumed.
+func (p *sparqlParser) isIRIRef() bool {
+	var runes []rune
+	defer func() {
+		for i := len(runes) - 1; i >= 0; i-- {
+			p.r.unreadRune(runes[i])
+		}
+	}()
+
+	for {
+		r1, err := p.r.readRune()
+		if err != nil {
+			return false
+		}
+		runes = append(runes, r1)
+		if r1 == '>' {
+			return true
+		}
+		if !isIRIChar(r1) && r1 != '\\' {
+			return false
+		}
+	}
+}
+
+// scanVarName reads the name of a variable after the '?' or '$'.
+func (p *sparqlParser) scanVarName() (string, error) {
+	r := p.r
+	r.buf.Reset()
+	for {
+		r1, err := r.readRune()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		if !isPNChars(r1) || r1 == '-' {
+			r.unreadRune(r1)
+			break
+		}
+		r.buf.WriteRune(r1)
+	}
+
+	if !isVarName(r.buf.String()) {
+		return "", r.error(ErrUnexpectedCharacter)
+	}
+	return r.buf.String(), nil
+}
+
+// scanName reads a prefixed name or keyword beginning with r1.
+func (p *sparqlParser) scanName(tok turtleToken, r1 rune) (turtleToken, error) {
+	r := p.r
+	r.buf.Reset()
+
+	if r1 != ':' {
+		r.buf.WriteRune(r1)
+		dots := 0
+		for {
+			var err error
+			r1, err = r.readRune()
+			if err != nil {
+				if err == io.EOF {
+					r1 = 0
+					break
+				}
+				return tok, err
+			}
+			if !isPNChars(r1) && r1 != '.' {
+				break
+			}
+			if r1 == '.' {
+				dots++
+			} else {
+				dots = 0
+			}
+			r.buf.WriteRune(r1)
+		}
+
+		if r1 != ':' || dots > 0 {
+			if r1 != 0 {
+				r.unreadRune(r1)
+			}
+			tok.kind = tokKeyword
+			tok.text = r.trimDots(dots)
+			return tok, nil
+		}
+	}
+
+	tok.kind = tokPrefixedName
+	tok.text = r.buf.String()
+
+	local, err := r.scanLocalName()
+	if err != nil {
+		return tok, err
+	}
+	tok.local = local
+	return tok, nil
+}
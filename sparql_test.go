@@ -0,0 +1,411 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const sparqlPrologue = `PREFIX ex: <http://example.org/>
+PREFIX foaf: <http://xmlns.com/foaf/0.1/>
+PREFIX xsd: <http://www.w3.org/2001/XMLSchema#>
+`
+
+// readQueryGraph returns the graph of queryDocument.
+func readQueryGraph(t *testing.T) *Graph {
+	return newTestGraph(t, queryDocument)
+}
+
+// formatSolutions returns each solution as a line of bindings in the order of
+// variables.
+func formatSolutions(variables []string, solutions []map[string]RdfTerm) []string {
+	lines := []string{}
+	for _, solution := range solutions {
+		var bindings []string
+		for _, name := range variables {
+			if t, ok := solution[name]; ok {
+				bindings = append(bindings, "?"+name+"="+t.String())
+			}
+		}
+		lines = append(lines, strings.Join(bindings, " "))
+	}
+	return lines
+}
+
+func TestSPARQLSelect(t *testing.T) {
+	g := readQueryGraph(t)
+
+	cases := []struct {
+		query    string
+		expected []string
+	}{
+		{
+			// Simple literals sort before language-tagged strings
+			query: `SELECT ?x ?n WHERE { ?x a ex:Person ; foaf:name ?n } ORDER BY ?n`,
+			expected: []string{
+				`?x=<http://example.org/bob> ?n="Bob"`,
+				`?x=<http://example.org/alice> ?n="Alice"@en`,
+				`?x=<http://example.org/bob> ?n="Robert"@en-GB`,
+			},
+		},
+		{
+			query: `SELECT * { ?x foaf:age ?a } ORDER BY DESC(?a)`,
+			expected: []string{
+				`?x=<http://example.org/alice> ?a="42"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`?x=<http://example.org/bob> ?a="7.5"^^<http://www.w3.org/2001/XMLSchema#decimal>`,
+			},
+		},
+		{
+			query: `SELECT DISTINCT ?type WHERE { ?x a ?type } ORDER BY ?type`,
+			expected: []string{
+				`?type=<http://example.org/Dog>`,
+				`?type=<http://example.org/Person>`,
+			},
+		},
+		{
+			query: `SELECT ?x WHERE { ?x a ?type } ORDER BY ?x LIMIT 1 OFFSET 1`,
+			expected: []string{
+				`?x=<http://example.org/bob>`,
+			},
+		},
+		{
+			query:    `SELECT ?x WHERE { ?x a ?type } OFFSET 5`,
+			expected: []string{},
+		},
+		{
+			query: `SELECT ?x ?n WHERE { ?x foaf:name ?n FILTER (lang(?n) = "en") } ORDER BY ?x`,
+			expected: []string{
+				`?x=<http://example.org/alice> ?n="Alice"@en`,
+				`?x=<http://example.org/rex> ?n="Rex"@en`,
+			},
+		},
+		{
+			query: `SELECT ?n WHERE { ?x foaf:name ?n . FILTER langMatches(LANG(?n), "EN") } ORDER BY ?n`,
+			expected: []string{
+				`?n="Alice"@en`,
+				`?n="Rex"@en`,
+				`?n="Robert"@en-GB`,
+			},
+		},
+		{
+			query: `SELECT ?x WHERE { ?x foaf:age ?a . FILTER(?a * 2 >= 15 && ?a < 40.0) }`,
+			expected: []string{
+				`?x=<http://example.org/bob>`,
+			},
+		},
+		{
+			query: `SELECT ?x WHERE { ?x foaf:age ?a . FILTER(-?a < -10) }`,
+			expected: []string{
+				`?x=<http://example.org/alice>`,
+			},
+		},
+		{
+			query: `SELECT ?n { ?x foaf:name ?n FILTER regex(str(?n), "^r", "i") } ORDER BY ?n`,
+			expected: []string{
+				`?n="Rex"@en`,
+				`?n="Robert"@en-GB`,
+			},
+		},
+		{
+			query: `SELECT ?x { ?x a ?type FILTER (?x IN (ex:alice, ex:rex)) } ORDER BY ?x`,
+			expected: []string{
+				`?x=<http://example.org/alice>`,
+				`?x=<http://example.org/rex>`,
+			},
+		},
+		{
+			query: `SELECT ?x { ?x a ?type FILTER (?x NOT IN (ex:alice, ex:rex)) }`,
+			expected: []string{
+				`?x=<http://example.org/bob>`,
+			},
+		},
+		{
+			query: `SELECT ?x ?a WHERE { ?x a ?type OPTIONAL { ?x foaf:age ?a } } ORDER BY ?x`,
+			expected: []string{
+				`?x=<http://example.org/alice> ?a="42"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`?x=<http://example.org/bob> ?a="7.5"^^<http://www.w3.org/2001/XMLSchema#decimal>`,
+				`?x=<http://example.org/rex>`,
+			},
+		},
+		{
+			// The filter of an OPTIONAL applies to the optional part only
+			query: `SELECT ?x ?a WHERE { ?x a ?type OPTIONAL { ?x foaf:age ?a FILTER(?a > 10) } } ORDER BY ?x`,
+			expected: []string{
+				`?x=<http://example.org/alice> ?a="42"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`?x=<http://example.org/bob>`,
+				`?x=<http://example.org/rex>`,
+			},
+		},
+		{
+			query: `SELECT ?x WHERE { ?x a ?type OPTIONAL { ?x foaf:age ?a } FILTER(!bound(?a)) }`,
+			expected: []string{
+				`?x=<http://example.org/rex>`,
+			},
+		},
+		{
+			query: `SELECT ?x ?y WHERE { { ?x foaf:age ?y } UNION { ?x foaf:knows ?y } UNION { ?x a ex:Dog } } ORDER BY ?x ?y`,
+			expected: []string{
+				`?x=<http://example.org/alice> ?y=<http://example.org/bob>`,
+				`?x=<http://example.org/alice> ?y="42"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`?x=<http://example.org/bob> ?y=<http://example.org/bob>`,
+				`?x=<http://example.org/bob> ?y="7.5"^^<http://www.w3.org/2001/XMLSchema#decimal>`,
+				`?x=<http://example.org/rex>`,
+			},
+		},
+		{
+			// A filter in a group cannot see the variables outside it
+			query:    `SELECT ?x WHERE { ?x a ex:Dog { FILTER(bound(?x)) } }`,
+			expected: []string{},
+		},
+		{
+			query: `SELECT ?n WHERE { [] foaf:knows [ foaf:name ?n ] } ORDER BY ?n`,
+			expected: []string{
+				`?n="Bob"`,
+				`?n="Bob"`,
+				`?n="Robert"@en-GB`,
+				`?n="Robert"@en-GB`,
+			},
+		},
+		{
+			query: `SELECT ?x WHERE { ?x foaf:knows _:b . _:b foaf:age 7.5 }`,
+			expected: []string{
+				`?x=<http://example.org/alice>`,
+				`?x=<http://example.org/bob>`,
+			},
+		},
+		{
+			query: `BASE <http://example.org/> SELECT ?x WHERE { ?x foaf:age "42"^^xsd:integer ; foaf:knows <bob> }`,
+			expected: []string{
+				`?x=<http://example.org/alice>`,
+			},
+		},
+		{
+			query: `SELECT $x WHERE { $x foaf:age 42 }`,
+			expected: []string{
+				`?x=<http://example.org/alice>`,
+			},
+		},
+		{
+			query:    `SELECT ?x WHERE { ?x foaf:age 42.0 }`,
+			expected: []string{},
+		},
+		{
+			query: `SELECT ?x WHERE { ?x foaf:knows ?x } # comment`,
+			expected: []string{
+				`?x=<http://example.org/bob>`,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		q, err := ParseSPARQL(sparqlPrologue + tc.query)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, tc.query)
+			continue
+		}
+		result, err := q.Execute(g)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, tc.query)
+			continue
+		}
+
+		actual := formatSolutions(result.Variables, result.Solutions)
+		if !strings.Contains(tc.query, "ORDER BY") {
+			sort.Strings(actual)
+		}
+		if strings.Join(actual, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("Expected %q for %s but got %q", tc.expected, tc.query, actual)
+		}
+	}
+}
+
+func TestSPARQLSelectVariables(t *testing.T) {
+	q, err := ParseSPARQL(sparqlPrologue + `SELECT * WHERE { ?x foaf:knows [ foaf:name ?n ] OPTIONAL { ?x foaf:age ?a } FILTER(?z) }`)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	expected := []string{"x", "n", "a"}
+	if strings.Join(q.Variables, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v but got %v", expected, q.Variables)
+	}
+	if q.Form != SPARQLSelect || q.Distinct || q.Limit != -1 || q.Offset != 0 {
+		t.Errorf("Got unexpected query %+v", q)
+	}
+}
+
+func TestSPARQLAsk(t *testing.T) {
+	g := readQueryGraph(t)
+
+	cases := map[string]bool{
+		`ASK { ex:alice foaf:knows ex:bob }`:                    true,
+		`ASK WHERE { ex:bob foaf:knows ex:alice }`:              false,
+		`ASK { ?x foaf:age ?a FILTER(?a > 100) }`:               false,
+		`ASK { ?x foaf:age ?a FILTER(isNumeric(?a)) }`:          true,
+		`ASK { ?x foaf:name "Bob" ; foaf:name "Robert"@en-gb }`: true,
+	}
+
+	for query, expected := range cases {
+		q, err := ParseSPARQL(sparqlPrologue + query)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, query)
+			continue
+		}
+		result, err := q.Execute(g)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, query)
+			continue
+		}
+		if result.Boolean != expected {
+			t.Errorf("Expected %v for %s but got %v", expected, query, result.Boolean)
+		}
+	}
+}
+
+func TestSPARQLConstruct(t *testing.T) {
+	g := readQueryGraph(t)
+
+	cases := map[string][]string{
+		`CONSTRUCT { ?y ex:knownBy ?x } WHERE { ?x foaf:knows ?y }`: {
+			`<http://example.org/bob> <http://example.org/knownBy> <http://example.org/alice> .`,
+			`<http://example.org/bob> <http://example.org/knownBy> <http://example.org/bob> .`,
+		},
+		`CONSTRUCT { ?x ex:age ?a } WHERE { ?x a ex:Person OPTIONAL { ?x foaf:age ?a FILTER(?a > 10) } }`: {
+			`<http://example.org/alice> <http://example.org/age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+		},
+		`CONSTRUCT { ?x a ex:Thing } WHERE { ?x foaf:name ?n }`: {
+			`<http://example.org/alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Thing> .`,
+			`<http://example.org/bob> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Thing> .`,
+			`<http://example.org/rex> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Thing> .`,
+		},
+		`CONSTRUCT { _:p ex:name ?n ; ex:of ?x } WHERE { ?x foaf:age ?a ; foaf:name ?n } ORDER BY ?n`: {
+			`_:b0 <http://example.org/name> "Bob" .`,
+			`_:b0 <http://example.org/of> <http://example.org/bob> .`,
+			`_:b1 <http://example.org/name> "Alice"@en .`,
+			`_:b1 <http://example.org/of> <http://example.org/alice> .`,
+			`_:b2 <http://example.org/name> "Robert"@en-GB .`,
+			`_:b2 <http://example.org/of> <http://example.org/bob> .`,
+		},
+		`CONSTRUCT WHERE { ?x foaf:knows ?x }`: {
+			`<http://example.org/bob> <http://xmlns.com/foaf/0.1/knows> <http://example.org/bob> .`,
+		},
+		`CONSTRUCT { ?n ex:of ?x } WHERE { ?x foaf:name ?n }`: nil,
+	}
+
+	for query, expected := range cases {
+		q, err := ParseSPARQL(sparqlPrologue + query)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, query)
+			continue
+		}
+		result, err := q.Execute(g)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, query)
+			continue
+		}
+
+		var actual []string
+		for _, triple := range result.Triples {
+			actual = append(actual, triple.String())
+		}
+		sort.Strings(actual)
+		if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("Expected %q for %s but got %q", expected, query, actual)
+		}
+	}
+
+	// New blank nodes do not clash with blank nodes from the data
+	var data Graph
+	if err := data.AddFrom(NewReader(strings.NewReader(`_:b0 <http://example.org/p> "1" .
+_:b1 <http://example.org/p> "2" .
+`))); err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	q, err := ParseSPARQL(`CONSTRUCT { ?s <http://example.org/r> _:n } WHERE { ?s <http://example.org/p> ?o } ORDER BY ?o`)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	result, err := q.Execute(&data)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	expected := []string{
+		`_:b0 <http://example.org/r> _:b2 .`,
+		`_:b1 <http://example.org/r> _:b3 .`,
+	}
+	var actual []string
+	for _, triple := range result.Triples {
+		actual = append(actual, triple.String())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %q but got %q", expected, actual)
+	}
+}
+
+func TestSPARQLExecuteError(t *testing.T) {
+	g := readQueryGraph(t)
+	errFailed := errors.New("failed")
+
+	q, err := ParseSPARQL(sparqlPrologue + `SELECT * WHERE { ?x a ex:Person }`)
+	if err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+	if _, err := q.Execute(&countingStore{Graph: g, fail: errFailed}); err != errFailed {
+		t.Errorf("Expected %v but got %v", errFailed, err)
+	}
+}
+
+func TestParseSPARQLErrors(t *testing.T) {
+	cases := []struct {
+		query    string
+		expected error
+		column   int
+	}{
+		{`SELECT ?x WHERE { ?x ?p ?o `, ErrUnexpectedEOF, 27},
+		{`SELECT WHERE { ?x ?p ?o }`, ErrUnexpectedCharacter, 7},
+		{`SELECT ?x WHERE { ?x ex:p ?o }`, ErrUndefinedPrefix, 21},
+		{`SELECT ?x WHERE { ?x <p> ?o }`, ErrRelativeIri, 21},
+		{`SELECT ?x WHERE { ?x ?p ?o } LIMIT ?x`, ErrUnexpectedCharacter, 35},
+		{`SELECT ?x WHERE { ?x ?p ?o } ORDER BY LIMIT 1`, ErrUnexpectedCharacter, 38},
+		{`SELECT ?x WHERE { ?x ?p ?o } OFFSET 99999999999999999999`, ErrModifierRange, 36},
+		{`SELECT ?x WHERE { ?x ?p ?o } LIMIT 99999999999999999999`, ErrModifierRange, 35},
+		{`SELECT ?x WHERE { ?x ?p ?o } LIMIT 1 LIMIT 2`, ErrDuplicateLimit, 37},
+		{`SELECT ?x WHERE { ?x ?p ?o } OFFSET 0 LIMIT 1 OFFSET 2`, ErrDuplicateOffset, 46},
+		{`SELECT ?x WHERE { ?x ?p ?o } extra`, ErrUnexpectedCharacter, 29},
+		{`SELECT ?x WHERE { ?x ?p ?o FILTER(?o = ) }`, ErrUnexpectedCharacter, 39},
+		{`SELECT ?x WHERE { ?x ?p ?o FILTER(bound("x")) }`, ErrInvalidExpression, 34},
+		{`SELECT ?x WHERE { ?x ?p ?o FILTER(lang(?o, ?p)) }`, ErrInvalidExpression, 34},
+		{`SELECT ?x WHERE { ?x ?p ?o FILTER(nofunction(?o)) }`, ErrUnexpectedCharacter, 34},
+		{`SELECT ?x WHERE { ?x ?p ?- }`, ErrUnexpectedCharacter, 25},
+		{`DESCRIBE ?x WHERE { ?x ?p ?o }`, ErrUnsupportedSPARQL, 0},
+		{`SELECT (COUNT(?x) AS ?n) WHERE { ?x ?p ?o }`, ErrUnsupportedSPARQL, 7},
+		{`SELECT ?x FROM <http://example.org/g> WHERE { ?x ?p ?o }`, ErrUnsupportedSPARQL, 10},
+		{`SELECT ?x WHERE { ?x <http://example.org/p>/<http://example.org/q> ?o }`, ErrUnsupportedSPARQL, 43},
+		{`SELECT ?x WHERE { ?x ^<http://example.org/p> ?o }`, ErrUnsupportedSPARQL, 21},
+		{`SELECT ?x WHERE { ?x ?p ?o MINUS { ?x ?p 1 } }`, ErrUnsupportedSPARQL, 27},
+		{`SELECT ?x WHERE { ?x ?p ?o FILTER NOT EXISTS { ?x ?p 1 } }`, ErrUnsupportedSPARQL, 34},
+		{`SELECT ?x WHERE { ?x ?p ?o FILTER(contains(?o, "a")) }`, ErrUnsupportedSPARQL, 34},
+		{`SELECT ?x WHERE { ?x ?p ?o FILTER(<http://example.org/f>(?o)) }`, ErrUnsupportedSPARQL, 34},
+		{`SELECT ?x WHERE { ?x ?p ?o } GROUP BY ?x`, ErrUnsupportedSPARQL, 29},
+		{`CONSTRUCT WHERE { ?x ?p ?o FILTER(true) }`, ErrUnsupportedSPARQL, 10},
+		{`CONSTRUCT WHERE { FILTER(true) }`, ErrUnsupportedSPARQL, 10},
+	}
+
+	for _, tc := range cases {
+		_, err := ParseSPARQL(tc.query)
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("Expected a ParseError for %s but got %v", tc.query, err)
+			continue
+		}
+		if pe.Err != tc.expected || pe.Line != 1 || pe.Column != tc.column {
+			t.Errorf("Expected %v at column %d for %s but got %v", tc.expected, tc.column, tc.query, err)
+		}
+	}
+}
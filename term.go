@@ -0,0 +1,520 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// NewIRI returns a term for the absolute IRI iri. It returns ErrInvalidIri
+// if iri has no scheme.
+func NewIRI(iri string) (RdfTerm, error) {
+	return newTerm(RdfTerm{Value: iri, TermType: RdfIri})
+}
+
+// NewBlankNode returns a blank node with the given label, which must be a
+// valid N-Triples blank node label without the leading "_:". It returns
+// ErrInvalidBlankNode if the label is not valid.
+func NewBlankNode(label string) (RdfTerm, error) {
+	return newTerm(RdfTerm{Value: label, TermType: RdfBlank})
+}
+
+// NewLiteral returns a simple literal with the lexical form value. Any string
+// is a valid simple literal.
+func NewLiteral(value string) RdfTerm {
+	return RdfTerm{Value: value, TermType: RdfLiteral}
+}
+
+// NewLangLiteral returns a literal with the lexical form value and the
+// language tag lang. It returns ErrInvalidLanguage if lang is empty or not a
+// well-formed language tag.
+func NewLangLiteral(value, lang string) (RdfTerm, error) {
+	if lang == "" {
+		return RdfTerm{}, ErrInvalidLanguage
+	}
+	return newTerm(RdfTerm{Value: value, Language: lang, TermType: RdfLiteral})
+}
+
+// NewTypedLiteral returns a literal with the lexical form value and the
+// datatype IRI dataType. It returns ErrInvalidIri if dataType is not an
+// absolute IRI and ErrInvalidLanguage if it is rdf:langString, which is only
+// used with a language tag; use NewLangLiteral instead. The lexical form is
+// not checked against the datatype; use ValidateLiteral for that.
+func NewTypedLiteral(value, dataType string) (RdfTerm, error) {
+	if dataType == "" {
+		return RdfTerm{}, ErrInvalidIri
+	}
+	return newTerm(RdfTerm{Value: value, DataType: dataType, TermType: RdfLiteral})
+}
+
+// NewTripleTerm returns a term that quotes the triple t, as in RDF-star and
+// RDF 1.2. It returns one of the errors returned by Writer.Write if t is not
+// a valid triple.
+func NewTripleTerm(t Triple) (RdfTerm, error) {
+	return newTerm(RdfTerm{TermType: RdfTriple, Triple: &t})
+}
+
+// newTerm returns t if it is valid, or the reason it is not.
+func newTerm(t RdfTerm) (RdfTerm, error) {
+	if err := t.Validate(); err != nil {
+		return RdfTerm{}, err
+	}
+	return t, nil
+}
+
+// Validate checks that t is a well-formed term that can be written as
+// N-Triples. It returns one of the errors returned by Writer.Write.
+func (t RdfTerm) Validate() error {
+	return validateTerm(t)
+}
+
+// Validate checks that t is a well-formed RDF triple: the subject must be an
+// IRI, blank node or quoted triple, the predicate must be an IRI and each term must be
+// valid, as for RdfTerm.Validate. It returns one of the errors returned by
+// Writer.Write.
+func (t Triple) Validate() error {
+	return validateTriple(t)
+}
+
+// Equal reports whether t and u are the same RDF term. Literals are equal if
+// they have the same lexical form, datatype and language tag, where simple
+// literals have the datatype xsd:string and language tags are compared
+// case-insensitively. Quoted triples are equal if their triples are Equal.
+func (t RdfTerm) Equal(u RdfTerm) bool {
+	if t.TermType != u.TermType || t.Value != u.Value {
+		return false
+	}
+	switch t.TermType {
+	case RdfLiteral:
+		return strings.EqualFold(t.Language, u.Language) && t.dataType() == u.dataType()
+	case RdfTriple:
+		if t.Triple == nil || u.Triple == nil {
+			return t.Triple == u.Triple
+		}
+		return t.Triple.Equal(*u.Triple)
+	}
+	return true
+}
+
+// ValueEqual reports whether t and u are equal terms or are literals with the
+// same value, following the = operator of SPARQL. Numeric literals are equal
+// if they have the same numeric value, with xsd:float and xsd:double values
+// compared as float64, so "1"^^xsd:integer equals "1.0"^^xsd:decimal. Values
+// of xsd:boolean, date and time datatypes and durations are compared within
+// their own datatypes. Times with and without a timezone are never equal.
+// Literals whose lexical forms are not valid for their datatypes are
+// compared as for Equal. Quoted triples are equal if each of their terms is
+// ValueEqual. Unlike in SPARQL, terms that are Equal are always ValueEqual,
+// even if their value is NaN.
+func (t RdfTerm) ValueEqual(u RdfTerm) bool {
+	if t.Equal(u) {
+		return true
+	}
+	if t.IsTriple() && u.IsTriple() && t.Triple != nil && u.Triple != nil {
+		return t.Triple.S.ValueEqual(u.Triple.S) && t.Triple.P.ValueEqual(u.Triple.P) && t.Triple.O.ValueEqual(u.Triple.O)
+	}
+	if !t.IsLiteral() || !u.IsLiteral() {
+		return false
+	}
+
+	switch c := literalCategory(t); {
+	case c != literalCategory(u):
+		return false
+	case c == literalNumeric:
+		if isFloatDataType(t.DataType) || isFloatDataType(u.DataType) {
+			f, _ := t.Float64()
+			g, _ := u.Float64()
+			return f == g
+		}
+		r, _ := t.BigRat()
+		s, _ := u.BigRat()
+		return r.Cmp(s) == 0
+	case c == literalBoolean:
+		b, _ := t.Bool()
+		c, _ := u.Bool()
+		return b == c
+	case c == literalDateTime, c == literalDate:
+		tm, _ := t.Time()
+		um, _ := u.Time()
+		return tm.Equal(um) && hasTimezone(t.Value) == hasTimezone(u.Value)
+	case c == literalDuration:
+		d, err := t.Duration()
+		e, err2 := u.Duration()
+		return err == nil && err2 == nil && d == e
+	}
+	return false
+}
+
+// Compare returns an integer comparing t and u, which is negative if t sorts
+// before u, positive if it sorts after and 0 only if the terms are Equal.
+//
+// The order is total and compatible with ORDER BY in SPARQL: terms of type
+// RdfUnknown, which represent unbound values, sort first followed by blank
+// nodes, IRIs, literals and then quoted triples, which are ordered as for
+// Triple.Compare. Literals are grouped into numbers,
+// booleans, times, dates, durations, simple literals and xsd:string,
+// language-tagged strings and then all other literals. Numbers and dates and times are
+// ordered by value and the rest by lexical form. Literals with equal values
+// are ordered by datatype IRI and lexical form. Times without a timezone are
+// compared as if they were in UTC.
+func (t RdfTerm) Compare(u RdfTerm) int {
+	if c := compareInts(termRank(t.TermType), termRank(u.TermType)); c != 0 {
+		return c
+	}
+	switch t.TermType {
+	case RdfLiteral:
+	case RdfTriple:
+		if t.Triple == nil || u.Triple == nil {
+			return compareInts(boolRank(t.Triple != nil), boolRank(u.Triple != nil))
+		}
+		return t.Triple.Compare(*u.Triple)
+	default:
+		return strings.Compare(t.Value, u.Value)
+	}
+
+	tc, uc := literalCategory(t), literalCategory(u)
+	if c := compareInts(tc, uc); c != 0 {
+		return c
+	}
+
+	var c int
+	switch tc {
+	case literalNumeric:
+		c = compareNumbers(t, u)
+	case literalBoolean:
+		b, _ := t.Bool()
+		d, _ := u.Bool()
+		c = compareInts(boolRank(b), boolRank(d))
+	case literalDateTime, literalDate:
+		tm, _ := t.Time()
+		um, _ := u.Time()
+		switch {
+		case tm.Before(um):
+			c = -1
+		case tm.After(um):
+			c = 1
+		}
+	case literalLangString:
+		if c = strings.Compare(t.Value, u.Value); c == 0 {
+			c = strings.Compare(strings.ToLower(t.Language), strings.ToLower(u.Language))
+		}
+	}
+
+	if c == 0 {
+		c = strings.Compare(t.dataType(), u.dataType())
+	}
+	if c == 0 {
+		c = strings.Compare(t.Value, u.Value)
+	}
+	return c
+}
+
+// Hash returns a 64-bit hash of t that is the same for terms that are Equal.
+// The hash does not depend on the process, so it can be used to partition
+// terms between machines.
+func (t RdfTerm) Hash() uint64 {
+	return t.hash(fnvOffset64)
+}
+
+// Equal reports whether t and u have Equal subjects, predicates and objects.
+func (t Triple) Equal(u Triple) bool {
+	return t.S.Equal(u.S) && t.P.Equal(u.P) && t.O.Equal(u.O)
+}
+
+// Compare returns an integer comparing t and u by subject, then predicate,
+// then object, as for RdfTerm.Compare.
+func (t Triple) Compare(u Triple) int {
+	if c := t.S.Compare(u.S); c != 0 {
+		return c
+	}
+	if c := t.P.Compare(u.P); c != 0 {
+		return c
+	}
+	return t.O.Compare(u.O)
+}
+
+// Hash returns a 64-bit hash of t that is the same for triples that are
+// Equal, as for RdfTerm.Hash.
+func (t Triple) Hash() uint64 {
+	return t.hash(fnvOffset64)
+}
+
+// hash combines the hash of each term of t with h.
+func (t Triple) hash(h uint64) uint64 {
+	return t.O.hash(t.P.hash(t.S.hash(h)))
+}
+
+// Constants for the 64-bit FNV-1a hash.
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// hash combines the hash of t with h, using FNV-1a over the parts of t that
+// determine equality. Each string is preceded by its length so that
+// different terms cannot produce the same sequence of bytes.
+func (t RdfTerm) hash(h uint64) uint64 {
+	h = hashUint(h, uint64(t.TermType))
+	h = hashString(h, t.Value, false)
+	switch t.TermType {
+	case RdfLiteral:
+		h = hashString(h, t.Language, true)
+		h = hashString(h, t.dataType(), false)
+	case RdfTriple:
+		if t.Triple != nil {
+			h = t.Triple.hash(h)
+		}
+	}
+	return h
+}
+
+// hashString combines the length and bytes of s with h, lowercasing ASCII
+// letters if lower is true.
+func hashString(h uint64, s string, lower bool) uint64 {
+	h = hashUint(h, uint64(len(s)))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if lower && c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		h = (h ^ uint64(c)) * fnvPrime64
+	}
+	return h
+}
+
+// hashUint combines the bytes of v with h.
+func hashUint(h, v uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h = (h ^ (v & 0xff)) * fnvPrime64
+		v >>= 8
+	}
+	return h
+}
+
+// dataType returns the datatype IRI of the literal t, which is xsd:string for
+// simple literals and rdf:langString for language-tagged strings.
+func (t RdfTerm) dataType() string {
+	switch {
+	case t.Language != "":
+		return rdfLangString
+	case t.DataType == "":
+		return xsdString
+	}
+	return t.DataType
+}
+
+// Categories of literals that are compared by value. The order of the
+// constants is the order used by Compare.
+const (
+	literalNumeric = iota
+	literalBoolean
+	literalDateTime
+	literalDate
+	literalDuration
+	literalString
+	literalLangString
+	literalOther
+)
+
+// literalCategory returns the category of the literal t. Literals whose
+// lexical forms are not valid for their datatype are in literalOther.
+func literalCategory(t RdfTerm) int {
+	if t.Language != "" {
+		return literalLangString
+	}
+
+	var err error
+	c := literalOther
+	switch t.DataType {
+	case "", xsdString:
+		return literalString
+	case xsdDecimal, xsdDouble, xsdFloat:
+		c = literalNumeric
+		_, err = t.Float64()
+	case xsdBoolean:
+		c = literalBoolean
+		_, err = t.Bool()
+	case xsdDateTime, xsdDateTimeStamp:
+		c = literalDateTime
+		_, err = t.Time()
+	case xsdDate:
+		c = literalDate
+		_, err = t.Time()
+	case xsdDuration, xsdDayTimeDuration, xsdYearMonthDuration:
+		c = literalDuration
+		_, err = t.Duration()
+	default:
+		if _, ok := integerTypes[t.DataType]; ok {
+			c = literalNumeric
+			_, err = t.BigInt()
+		}
+	}
+
+	if err != nil && !errors.Is(err, ErrNotRepresentable) {
+		return literalOther
+	}
+	return c
+}
+
+// compareNumbers compares two valid numeric literals by value. NaN sorts
+// before all other numbers.
+func compareNumbers(t, u RdfTerm) int {
+	r, tk := numericValue(t)
+	s, uk := numericValue(u)
+	if c := compareInts(tk, uk); c != 0 || r == nil {
+		return c
+	}
+	return r.Cmp(s)
+}
+
+// numericValue returns the exact value of the valid numeric literal t. For the
+// special floating point values it instead returns a rank in the order NaN,
+// -INF, finite values and INF, where finite values have the rank 0.
+func numericValue(t RdfTerm) (*big.Rat, int) {
+	if r, err := t.BigRat(); err == nil {
+		return r, 0
+	}
+	f, _ := t.Float64()
+	switch {
+	case math.IsNaN(f):
+		return nil, -2
+	case f < 0:
+		return nil, -1
+	}
+	return nil, 1
+}
+
+// isFloatDataType reports whether dataType is xsd:float or xsd:double.
+func isFloatDataType(dataType string) bool {
+	return dataType == xsdFloat || dataType == xsdDouble
+}
+
+// hasTimezone reports whether the lexical form of a date or time ends with a timezone.
+func hasTimezone(s string) bool {
+	n := len(s)
+	return strings.HasSuffix(s, "Z") || n >= 6 && (s[n-6] == '+' || s[n-6] == '-') && s[n-3] == ':'
+}
+
+// termRank returns the position of terms of type termType in the order used by Compare.
+func termRank(termType TermType) int {
+	switch termType {
+	case RdfBlank:
+		return 1
+	case RdfIri:
+		return 2
+	case RdfLiteral:
+		return 3
+	case RdfTriple:
+		return 4
+	}
+	return 0
+}
+
+// isVarName reports whether s is a valid SPARQL variable name, excluding the
+// leading '?' or '$'.
+func isVarName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r1 := range s {
+		if i == 0 && !isPNCharsU(r1) && !isDigit(r1) || i > 0 && (!isPNChars(r1) || r1 == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// boolRank returns 0 for false and 1 for true.
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compareInts returns -1, 0 or 1 as a is less than, equal to or greater than b.
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// ParseTerm parses a single IRI, blank node, literal or quoted triple written
+// as in N-Triples, such as <http://example.org/> or "chat"@en. Spaces and tabs
+// around the term are ignored. Errors are reported as a ParseError on line 1.
+func ParseTerm(text string) (RdfTerm, error) {
+	s := newTextScanner(text)
+
+	var t RawTerm
+	if err := s.scanTerm(posObject, &t); err != nil {
+		return RdfTerm{}, err
+	}
+	s.skipSpace()
+	if s.pos < len(s.data) {
+		return RdfTerm{}, s.unexpected(s.pos, "end of term")
+	}
+	return t.Term(), nil
+}
+
+// ParseTriple parses a single triple written as a line of N-Triples,
+// including the final '.', such as the result of Triple.String. Errors are
+// reported as a ParseError on line 1.
+func ParseTriple(text string) (Triple, error) {
+	s := newTextScanner(text)
+	if err := s.scanLineTerms(); err != nil {
+		return Triple{}, err
+	}
+	return Triple{S: s.Subject().Term(), P: s.Predicate().Term(), O: s.Object().Term()}, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The term is
+// encoded as in N-Triples, as for String. An error is returned if the term
+// is not valid, as for Validate.
+func (t RdfTerm) MarshalText() ([]byte, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return appendTerm(nil, t)
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The term
+// is parsed as for ParseTerm.
+func (t *RdfTerm) UnmarshalText(text []byte) error {
+	term, err := ParseTerm(string(text))
+	if err != nil {
+		return err
+	}
+	*t = term
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The triple is
+// encoded as a line of N-Triples without a line ending, as for String. An
+// error is returned if the triple is not valid, as for Validate.
+func (t Triple) MarshalText() ([]byte, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return appendTriple(nil, t)
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The
+// triple is parsed as for ParseTriple.
+func (t *Triple) UnmarshalText(text []byte) error {
+	triple, err := ParseTriple(string(text))
+	if err != nil {
+		return err
+	}
+	*t = triple
+	return nil
+}
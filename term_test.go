@@ -0,0 +1,409 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// quote returns a term that quotes the triple s p o.
+func quote(s, p, o RdfTerm) RdfTerm {
+	return RdfTerm{TermType: RdfTriple, Triple: &Triple{S: s, P: p, O: o}}
+}
+
+func TestNewTerms(t *testing.T) {
+	must := func(term RdfTerm, err error) RdfTerm {
+		if err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+		return term
+	}
+
+	cases := map[string]RdfTerm{
+		"<http://example.org/s>": must(NewIRI("http://example.org/s")),
+		"_:b1":                   must(NewBlankNode("b1")),
+		`"chat"`:                 NewLiteral("chat"),
+		`"chat"@en-GB`:           must(NewLangLiteral("chat", "en-GB")),
+		`"1"^^<http://www.w3.org/2001/XMLSchema#integer>`: must(NewTypedLiteral("1", xsdInteger)),
+		`<< _:b1 <http://example.org/p> "chat" >>`: must(NewTripleTerm(Triple{
+			S: must(NewBlankNode("b1")),
+			P: must(NewIRI("http://example.org/p")),
+			O: NewLiteral("chat"),
+		})),
+	}
+	for expected, term := range cases {
+		if actual := term.String(); actual != expected {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+	}
+
+	errorCases := map[error]func() (RdfTerm, error){
+		ErrInvalidIri:       func() (RdfTerm, error) { return NewIRI("relative") },
+		ErrInvalidBlankNode: func() (RdfTerm, error) { return NewBlankNode("b 1") },
+		ErrInvalidLanguage:  func() (RdfTerm, error) { return NewLangLiteral("chat", "en_GB") },
+		ErrInvalidSubject: func() (RdfTerm, error) {
+			return NewTripleTerm(Triple{S: NewLiteral("s"), P: RdfTerm{Value: "http://example.org/p", TermType: RdfIri}, O: NewLiteral("o")})
+		},
+	}
+	for expected, f := range errorCases {
+		if _, err := f(); err != expected {
+			t.Errorf("Expected %v but got %v", expected, err)
+		}
+	}
+
+	for _, dataType := range []string{"", "integer"} {
+		if _, err := NewTypedLiteral("1", dataType); err != ErrInvalidIri {
+			t.Errorf("Expected %v for %q but got %v", ErrInvalidIri, dataType, err)
+		}
+	}
+	// A language tag is required by rdf:langString and must not be empty
+	if _, err := NewLangLiteral("chat", ""); err != ErrInvalidLanguage {
+		t.Errorf("Expected %v but got %v", ErrInvalidLanguage, err)
+	}
+	if _, err := NewTypedLiteral("chat", rdfLangString); err != ErrInvalidLanguage {
+		t.Errorf("Expected %v but got %v", ErrInvalidLanguage, err)
+	}
+}
+
+func TestTripleValidate(t *testing.T) {
+	for _, triple := range writeCases {
+		if err := triple.Validate(); err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, triple)
+		}
+	}
+
+	for _, tc := range writeErrorCases {
+		if err := tc.triple.Validate(); err != tc.expected {
+			t.Errorf("Expected %v for %#v but got %v", tc.expected, tc.triple, err)
+		}
+	}
+
+	q := Quad{Triple: writeCases["<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ."]}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Got unexpected error %v", err)
+	}
+	q.G = RdfTerm{Value: "g", TermType: RdfLiteral}
+	if err := q.Validate(); err != ErrInvalidGraph {
+		t.Errorf("Expected %v but got %v", ErrInvalidGraph, err)
+	}
+	q.G = quote(q.S, q.P, q.O)
+	if err := q.Validate(); err != ErrInvalidGraph {
+		t.Errorf("Expected %v but got %v", ErrInvalidGraph, err)
+	}
+
+	quoted := quote(q.S, q.P, q.O)
+	if err := (Triple{S: quoted, P: q.P, O: quoted}).Validate(); err != nil {
+		t.Errorf("Got unexpected error %v", err)
+	}
+	invalid := quote(q.S, q.P, RdfTerm{Value: "relative", TermType: RdfIri})
+	if err := (Triple{S: invalid, P: q.P, O: q.O}).Validate(); err != ErrInvalidIri {
+		t.Errorf("Expected %v but got %v", ErrInvalidIri, err)
+	}
+	if err := (Triple{S: q.S, P: q.P, O: RdfTerm{TermType: RdfTriple}}).Validate(); err != ErrInvalidTriple {
+		t.Errorf("Expected %v but got %v", ErrInvalidTriple, err)
+	}
+}
+
+func TestTermEqual(t *testing.T) {
+	equal := [][2]RdfTerm{
+		{{Value: "http://example.org/", TermType: RdfIri}, {Value: "http://example.org/", TermType: RdfIri}},
+		{{Value: "b1", TermType: RdfBlank}, {Value: "b1", TermType: RdfBlank}},
+		{NewLiteral("1"), typed("1", xsdString)},
+		{{Value: "chat", Language: "en-GB", TermType: RdfLiteral}, {Value: "chat", Language: "en-gb", TermType: RdfLiteral}},
+		{{Value: "chat", Language: "en", TermType: RdfLiteral}, {Value: "chat", Language: "en", DataType: rdfLangString, TermType: RdfLiteral}},
+		{
+			quote(RdfTerm{Value: "b1", TermType: RdfBlank}, RdfTerm{Value: rdfType, TermType: RdfIri}, NewLiteral("1")),
+			quote(RdfTerm{Value: "b1", TermType: RdfBlank}, RdfTerm{Value: rdfType, TermType: RdfIri}, typed("1", xsdString)),
+		},
+	}
+	for _, pair := range equal {
+		if !pair[0].Equal(pair[1]) || !pair[1].Equal(pair[0]) {
+			t.Errorf("Expected %s to equal %s", pair[0], pair[1])
+		}
+		if pair[0].Hash() != pair[1].Hash() {
+			t.Errorf("Expected %s and %s to have the same hash", pair[0], pair[1])
+		}
+		if pair[0].Compare(pair[1]) != 0 {
+			t.Errorf("Expected %s and %s to compare equal", pair[0], pair[1])
+		}
+	}
+
+	notEqual := [][2]RdfTerm{
+		{{Value: "http://example.org/", TermType: RdfIri}, {Value: "http://example.org/", TermType: RdfBlank}},
+		{{Value: "http://example.org/", TermType: RdfIri}, NewLiteral("http://example.org/")},
+		{NewLiteral("1"), typed("1", xsdInteger)},
+		{typed("1", xsdInteger), typed("01", xsdInteger)},
+		{{Value: "chat", Language: "en", TermType: RdfLiteral}, {Value: "chat", Language: "fr", TermType: RdfLiteral}},
+		{{Value: "chat", Language: "en", TermType: RdfLiteral}, NewLiteral("chat")},
+		{
+			quote(RdfTerm{Value: "b1", TermType: RdfBlank}, RdfTerm{Value: rdfType, TermType: RdfIri}, NewLiteral("1")),
+			quote(RdfTerm{Value: "b1", TermType: RdfBlank}, RdfTerm{Value: rdfType, TermType: RdfIri}, typed("1", xsdInteger)),
+		},
+		{quote(RdfTerm{Value: "b1", TermType: RdfBlank}, RdfTerm{Value: rdfType, TermType: RdfIri}, NewLiteral("1")), {TermType: RdfTriple}},
+	}
+	for _, pair := range notEqual {
+		if pair[0].Equal(pair[1]) || pair[1].Equal(pair[0]) {
+			t.Errorf("Expected %s not to equal %s", pair[0], pair[1])
+		}
+		if pair[0].Hash() == pair[1].Hash() {
+			t.Errorf("Expected %s and %s to have different hashes", pair[0], pair[1])
+		}
+		if pair[0].Compare(pair[1]) == 0 {
+			t.Errorf("Expected %s and %s not to compare equal", pair[0], pair[1])
+		}
+	}
+}
+
+func TestTermValueEqual(t *testing.T) {
+	equal := [][2]RdfTerm{
+		{typed("1", xsdInteger), typed("1.0", xsdDecimal)},
+		{typed("01", xsdByte), typed("+1", xsdInteger)},
+		{typed("1", xsdInteger), typed("1E0", xsdDouble)},
+		{typed("0.5", xsdFloat), typed("0.5", xsdDecimal)},
+		{typed("INF", xsdFloat), typed("INF", xsdDouble)},
+		{typed("1", xsdBoolean), typed("true", xsdBoolean)},
+		{typed("2002-10-10T12:00:00-05:00", xsdDateTime), typed("2002-10-10T17:00:00Z", xsdDateTime)},
+		{typed("2002-10-10T12:00:00Z", xsdDateTimeStamp), typed("2002-10-10T12:00:00+00:00", xsdDateTime)},
+		{typed("2002-10-10", xsdDate), typed("2002-10-10", xsdDate)},
+		{typed("P1Y", xsdDuration), typed("P12M", xsdYearMonthDuration)},
+		{typed("PT36H", xsdDuration), typed("P1DT12H", xsdDayTimeDuration)},
+		{NewLiteral("a"), typed("a", xsdString)},
+		{
+			quote(RdfTerm{Value: "b1", TermType: RdfBlank}, RdfTerm{Value: rdfType, TermType: RdfIri}, typed("1", xsdInteger)),
+			quote(RdfTerm{Value: "b1", TermType: RdfBlank}, RdfTerm{Value: rdfType, TermType: RdfIri}, typed("1.0", xsdDecimal)),
+		},
+	}
+	for _, pair := range equal {
+		if !pair[0].ValueEqual(pair[1]) || !pair[1].ValueEqual(pair[0]) {
+			t.Errorf("Expected %s to equal %s by value", pair[0], pair[1])
+		}
+	}
+
+	notEqual := [][2]RdfTerm{
+		{typed("0.1", xsdFloat), typed("0.1", xsdDouble)},
+		{typed("NaN", xsdDouble), typed("NaN", xsdFloat)},
+		{typed("1", xsdInteger), typed("1", xsdBoolean)},
+		{typed("1", xsdInteger), NewLiteral("1")},
+		{typed("abc", xsdInteger), typed("abc", xsdDecimal)},
+		{typed("2002-10-10T12:00:00", xsdDateTime), typed("2002-10-10T12:00:00Z", xsdDateTime)},
+		{typed("2002-10-10", xsdDate), typed("2002-10-10T00:00:00", xsdDateTime)},
+		{typed("P1M", xsdDuration), typed("P30D", xsdDuration)},
+		{{Value: "1", TermType: RdfIri}, NewLiteral("1")},
+	}
+	for _, pair := range notEqual {
+		if pair[0].ValueEqual(pair[1]) || pair[1].ValueEqual(pair[0]) {
+			t.Errorf("Expected %s not to equal %s by value", pair[0], pair[1])
+		}
+	}
+}
+
+func TestTermCompare(t *testing.T) {
+	// Terms in ascending order
+	ordered := []RdfTerm{
+		{},
+		{Value: "a", TermType: RdfBlank},
+		{Value: "b", TermType: RdfBlank},
+		{Value: "http://example.org/a", TermType: RdfIri},
+		{Value: "http://example.org/b", TermType: RdfIri},
+		typed("NaN", xsdDouble),
+		typed("-INF", xsdDouble),
+		typed("-1.5", xsdDecimal),
+		typed("1.0", xsdDecimal),
+		typed("1", xsdInteger),
+		typed("1", xsdUnsignedByte),
+		typed("1.1", xsdDecimal),
+		typed("2", xsdInteger),
+		typed("10", xsdInteger),
+		typed("1E300", xsdDouble),
+		typed("INF", xsdFloat),
+		typed("false", xsdBoolean),
+		typed("1", xsdBoolean),
+		typed("true", xsdBoolean),
+		typed("2002-10-10T12:00:00Z", xsdDateTime),
+		typed("2002-10-10T10:00:00-05:00", xsdDateTime),
+		typed("2002-10-10T16:00:00", xsdDateTime),
+		typed("2002-10-09", xsdDate),
+		typed("P1D", xsdDuration),
+		NewLiteral(""),
+		NewLiteral("10"),
+		NewLiteral("9"),
+		NewLiteral("a"),
+		{Value: "a", Language: "en", TermType: RdfLiteral},
+		{Value: "a", Language: "fr", TermType: RdfLiteral},
+		{Value: "b", Language: "de", TermType: RdfLiteral},
+		typed("x", "http://example.org/datatype"),
+		typed("abc", xsdInteger),
+		{TermType: RdfTriple},
+		quote(RdfTerm{Value: "a", TermType: RdfBlank}, RdfTerm{Value: "http://example.org/b", TermType: RdfIri}, NewLiteral("a")),
+		quote(RdfTerm{Value: "a", TermType: RdfBlank}, RdfTerm{Value: "http://example.org/b", TermType: RdfIri}, NewLiteral("b")),
+		quote(RdfTerm{Value: "http://example.org/a", TermType: RdfIri}, RdfTerm{Value: "http://example.org/a", TermType: RdfIri}, NewLiteral("a")),
+	}
+
+	for i, a := range ordered {
+		for j, b := range ordered {
+			expected := compareInts(i, j)
+			if actual := a.Compare(b); actual != expected {
+				t.Errorf("Expected %d comparing %s to %s but got %d", expected, a, b, actual)
+			}
+		}
+	}
+}
+
+func TestTripleEqual(t *testing.T) {
+	a := Triple{
+		S: RdfTerm{Value: "s", TermType: RdfBlank},
+		P: RdfTerm{Value: "http://example.org/p", TermType: RdfIri},
+		O: RdfTerm{Value: "chat", Language: "EN", TermType: RdfLiteral},
+	}
+	b := a
+	b.O.Language = "en"
+
+	if !a.Equal(b) || a.Compare(b) != 0 || a.Hash() != b.Hash() {
+		t.Errorf("Expected %s to equal %s", a, b)
+	}
+
+	b.P.Value = "http://example.org/q"
+	if a.Equal(b) || a.Compare(b) >= 0 || a.Hash() == b.Hash() {
+		t.Errorf("Expected %s to sort before %s", a, b)
+	}
+
+	q := Quad{Triple: a, G: RdfTerm{Value: "http://example.org/g", TermType: RdfIri}}
+	if q.Equal(Quad{Triple: a}) || q.Compare(Quad{Triple: a}) <= 0 || q.Hash() == a.Hash() {
+		t.Errorf("Expected the graph label of %s to be compared", q)
+	}
+
+	// The hash must not depend on the process
+	if h := NewLiteral("chat").Hash(); h != 0x70bb0759cf08e277 {
+		t.Errorf("Expected a stable hash but got %#x", h)
+	}
+}
+
+func TestParseTerm(t *testing.T) {
+	cases := map[string]RdfTerm{
+		"<http://example.org/>":            {Value: "http://example.org/", TermType: RdfIri},
+		" _:b1\t":                          {Value: "b1", TermType: RdfBlank},
+		`"chat"`:                           NewLiteral("chat"),
+		`"chat"@en-GB`:                     {Value: "chat", Language: "en-GB", TermType: RdfLiteral},
+		`"a\tbé"^^<http://example.org/dt>`: typed("a\tbé", "http://example.org/dt"),
+	}
+	for text, expected := range cases {
+		actual, err := ParseTerm(text)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, text)
+		} else if actual != expected {
+			t.Errorf("Expected %#v for %s but got %#v", expected, text, actual)
+		}
+	}
+
+	text := `<<( _:a <http://example.org/p> << _:b <http://example.org/p> "c" >> )>>`
+	expected := quote(
+		RdfTerm{Value: "a", TermType: RdfBlank},
+		RdfTerm{Value: "http://example.org/p", TermType: RdfIri},
+		quote(RdfTerm{Value: "b", TermType: RdfBlank}, RdfTerm{Value: "http://example.org/p", TermType: RdfIri}, NewLiteral("c")),
+	)
+	if actual, err := ParseTerm(text); err != nil || !actual.Equal(expected) {
+		t.Errorf("Expected %s for %s but got %s, %v", expected, text, actual, err)
+	}
+
+	errorCases := map[string]error{
+		"":                                  ErrUnexpectedEOF,
+		"http://example.org/":               ErrUnexpectedCharacter,
+		"<relative>":                        ErrRelativeIri,
+		`"unterminated`:                     ErrUnexpectedEOF,
+		"<http://example.org/> <x>":         ErrUnexpectedCharacter,
+		`"chat"@`:                           ErrUnexpectedCharacter,
+		"<< _:a <http://example.org/p> _:b": ErrUnexpectedEOF,
+	}
+	for text, expected := range errorCases {
+		_, err := ParseTerm(text)
+		perr, ok := err.(*ParseError)
+		if !ok || perr.Err != expected || perr.Line != 1 {
+			t.Errorf("Expected %v for %q but got %#v", expected, text, err)
+		}
+	}
+}
+
+func TestParseTriple(t *testing.T) {
+	for text, expected := range testCases {
+		if strings.ContainsAny(text, "\r\n") {
+			continue
+		}
+		actual, err := ParseTriple(text)
+		if err != nil {
+			t.Errorf("Got unexpected error %v for %s", err, text)
+		} else if actual != expected {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+	}
+
+	for text, expected := range negativeCases {
+		if strings.ContainsAny(text, "\r\n") {
+			continue
+		}
+		_, err := ParseTriple(text)
+		if perr, ok := err.(*ParseError); !ok || perr.Err != expected {
+			// The end of the text is the end of the input rather than a line ending
+			if !ok || perr.Err != ErrUnexpectedEOF && perr.Err != ErrUnterminatedTriple {
+				t.Errorf("Expected %v for %q but got %v", expected, text, err)
+			}
+		}
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	triple := writeCases["<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ."]
+	cases := map[string]interface {
+		encoding.TextMarshaler
+	}{
+		`"chat\n"@en`: RdfTerm{Value: "chat\n", Language: "en", TermType: RdfLiteral},
+		"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> .":     triple,
+		"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> _:g .": Quad{Triple: triple, G: RdfTerm{Value: "g", TermType: RdfBlank}},
+	}
+
+	for expected, v := range cases {
+		text, err := v.MarshalText()
+		if err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+		if string(text) != expected {
+			t.Errorf("Expected %s but got %s", expected, text)
+		}
+
+		var actual encoding.TextUnmarshaler
+		switch v.(type) {
+		case RdfTerm:
+			actual = new(RdfTerm)
+		case Triple:
+			actual = new(Triple)
+		case Quad:
+			actual = new(Quad)
+		}
+		if err := actual.UnmarshalText(text); err != nil {
+			t.Fatalf("Got unexpected error %v", err)
+		}
+		if actual := reflect.ValueOf(actual).Elem().Interface(); actual != v {
+			t.Errorf("Expected %#v but got %#v", v, actual)
+		}
+	}
+
+	// Map keys are encoded as text by encoding/json
+	data, err := json.Marshal(map[RdfTerm]int{NewLiteral("a"): 1})
+	if err != nil || string(data) != `{"\"a\"":1}` {
+		t.Errorf("Expected term to be encoded as a map key but got %s, %v", data, err)
+	}
+
+	if _, err := (RdfTerm{Value: "relative", TermType: RdfIri}).MarshalText(); err != ErrInvalidIri {
+		t.Errorf("Expected %v but got %v", ErrInvalidIri, err)
+	}
+	var term RdfTerm
+	if err := term.UnmarshalText([]byte("chat")); err == nil {
+		t.Errorf("Expected an error but got %s", term)
+	}
+}
@@ -0,0 +1,1224 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUndefinedPrefix is returned in a ParseError when a prefixed name uses a
+// prefix that has not been declared.
+var ErrUndefinedPrefix = errors.New("undefined prefix")
+
+// A TurtleReader reads triples from a Turtle document.
+//
+// Triples are produced in the order their statements appear in the document.
+// Blank node labels used in the document are preserved, except that labels
+// beginning with "genid" are prefixed with "genid_" so that they cannot clash
+// with the labels "genid0", "genid1", ... generated for anonymous blank nodes
+// and collections.
+//
+// Quoted triples are read as in Turtle-star, written << s p o >> in the
+// subject or object of a triple, as are RDF 1.2 triple terms, written
+// <<( s p o )>> in the object. Both are read as terms of type RdfTriple.
+type TurtleReader struct {
+	// Base is the IRI against which relative IRIs are resolved until the
+	// document declares its own base. It may be changed before the first
+	// call to Next. If Base is empty then relative IRIs are an error unless
+	// the document declares a base.
+	Base string
+
+	// ValidateLiterals, if true, makes Next check that the lexical form of
+	// each typed literal is valid for its datatype, as for ValidateLiteral.
+	// An ill-typed literal is reported as a ParseError at the start of the
+	// literal.
+	ValidateLiterals bool
+
+	// ValidateIRIs, if true, makes Next check that each IRI conforms to the
+	// syntax of RFC 3987 once it has been resolved. A malformed IRI is
+	// reported as a ParseError with ErrMalformedIri.
+	ValidateIRIs bool
+
+	r       *bufio.Reader
+	pending []turtleRune // runes that have been unread
+	line    int          // line of the last rune read
+	column  int          // column of the last rune read
+	rline   int          // line of the last rune read from r
+	rcolumn int          // column of the last rune read from r
+	newline bool         // whether the last rune read from r ended a line
+	buf     bytes.Buffer
+
+	tok    turtleToken // lookahead token
+	hasTok bool
+
+	started  bool
+	base     string
+	prefixes map[string]string
+	genid    int
+
+	triples []Triple // triples produced by the current statement
+	next    int      // index of the next triple to return from triples
+	err     error
+	t       Triple
+}
+
+// A turtleRune is a rune with the position it was read from.
+type turtleRune struct {
+	r      rune
+	line   int
+	column int
+}
+
+// Kinds of turtleToken
+const (
+	tokEOF          = iota
+	tokIRI          // IRIREF, text is the unresolved IRI
+	tokPrefixedName // PNAME_NS or PNAME_LN, text is the prefix and local is the local name
+	tokBlankNode    // BLANK_NODE_LABEL, text is the label
+	tokString       // any string literal, text is the unescaped string
+	tokLangTag      // LANGTAG, text is the tag without the '@'
+	tokDatatype     // the '^^' datatype marker
+	tokInteger      // INTEGER, text is the lexical form
+	tokDecimal      // DECIMAL, text is the lexical form
+	tokDouble       // DOUBLE, text is the lexical form
+	tokPunctuation  // one of . ; , [ ] ( ) << <<( >>
+	tokKeyword      // one of a, true, false, PREFIX or BASE, or any name in SPARQL
+	tokVariable     // VAR1 or VAR2 in SPARQL, text is the name without the '?' or '$'
+)
+
+// A turtleToken is a single terminal of the Turtle grammar.
+type turtleToken struct {
+	kind   int
+	text   string
+	local  string
+	line   int
+	column int
+}
+
+func (t turtleToken) is(kind int, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// NewTurtleReader returns a new TurtleReader that reads from r.
+func NewTurtleReader(r io.Reader) *TurtleReader {
+	return &TurtleReader{
+		r:        bufio.NewReader(r),
+		newline:  true,
+		prefixes: map[string]string{},
+	}
+}
+
+// Err returns any error encountered while reading. If Err is non-nil then Next will always return false.
+func (r *TurtleReader) Err() error {
+	return r.err
+}
+
+// Triple returns the last triple read
+func (r *TurtleReader) Triple() Triple {
+	return r.t
+}
+
+// Next attempts to read the next triple from the underlying reader. It returns false if no triple could be read which
+// may indicate an error has occurred or the end of the input stream has been reached.
+func (r *TurtleReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	if !r.started {
+		r.started = true
+		r.base = r.Base
+	}
+
+	for r.next >= len(r.triples) {
+		r.triples = r.triples[:0]
+		r.next = 0
+		if err := r.parseStatement(); err != nil {
+			if err != io.EOF {
+				r.err = err
+			}
+			return false
+		}
+	}
+
+	r.t = r.triples[r.next]
+	r.next++
+	return true
+}
+
+// error creates a new ParseError based on err at the position of the last rune read.
+func (r *TurtleReader) error(err error) error {
+	return &ParseError{
+		Line:   r.line,
+		Column: r.column,
+		Rune:   -1,
+		Err:    err,
+	}
+}
+
+// tokenError creates a new ParseError based on err at the start of tok.
+func (r *TurtleReader) tokenError(tok turtleToken, err error) error {
+	return &ParseError{
+		Line:   tok.line,
+		Column: tok.column,
+		Rune:   -1,
+		Err:    err,
+	}
+}
+
+// unexpected returns an error describing tok as unexpected.
+func (r *TurtleReader) unexpected(tok turtleToken) error {
+	if tok.kind == tokEOF {
+		return r.tokenError(tok, ErrUnexpectedEOF)
+	}
+	return r.tokenError(tok, ErrUnexpectedCharacter)
+}
+
+// emit queues a triple to be returned by Next.
+func (r *TurtleReader) emit(s, p, o RdfTerm) {
+	r.triples = append(r.triples, Triple{S: s, P: p, O: o})
+}
+
+// newBlankNode returns a blank node with a label that cannot clash with any
+// label used in the document.
+func (r *TurtleReader) newBlankNode() RdfTerm {
+	label := "genid" + strconv.Itoa(r.genid)
+	r.genid++
+	return RdfTerm{Value: label, TermType: RdfBlank}
+}
+
+// blankNode returns the blank node for a label used in the document.
+func (r *TurtleReader) blankNode(label string) RdfTerm {
+	if strings.HasPrefix(label, "genid") {
+		label = "genid_" + label
+	}
+	return RdfTerm{Value: label, TermType: RdfBlank}
+}
+
+// resolve resolves iri against the current base IRI.
+func (r *TurtleReader) resolve(tok turtleToken, iri string) (string, error) {
+	switch {
+	case isAbsoluteIRI(iri):
+		iri = resolveIRI(iri, iri)
+	case r.base == "":
+		return "", r.tokenError(tok, ErrRelativeIri)
+	default:
+		iri = resolveIRI(r.base, iri)
+	}
+
+	if r.ValidateIRIs && !isIRIReference(iri) {
+		return "", r.tokenError(tok, ErrMalformedIri)
+	}
+	return iri, nil
+}
+
+// peek returns the next token without consuming it.
+func (r *TurtleReader) peek() (turtleToken, error) {
+	if !r.hasTok {
+		tok, err := r.scan()
+		if err != nil {
+			return tok, err
+		}
+		r.tok = tok
+		r.hasTok = true
+	}
+	return r.tok, nil
+}
+
+// nextToken consumes and returns the next token.
+func (r *TurtleReader) nextToken() (turtleToken, error) {
+	tok, err := r.peek()
+	r.hasTok = false
+	return tok, err
+}
+
+// expect consumes the next token and checks that it is the punctuation p.
+func (r *TurtleReader) expect(p string) error {
+	tok, err := r.nextToken()
+	if err != nil {
+		return err
+	}
+	if !tok.is(tokPunctuation, p) {
+		return r.unexpected(tok)
+	}
+	return nil
+}
+
+// parseStatement parses a single directive or set of triples, queueing any
+// triples produced. It returns io.EOF when there are no more statements.
+func (r *TurtleReader) parseStatement() error {
+	tok, err := r.nextToken()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case tok.kind == tokEOF:
+		return io.EOF
+	case tok.is(tokLangTag, "prefix"):
+		if err := r.parsePrefix(); err != nil {
+			return err
+		}
+		return r.expect(".")
+	case tok.is(tokLangTag, "base"):
+		if err := r.parseBase(); err != nil {
+			return err
+		}
+		return r.expect(".")
+	case tok.kind == tokKeyword && strings.EqualFold(tok.text, "PREFIX"):
+		return r.parsePrefix()
+	case tok.kind == tokKeyword && strings.EqualFold(tok.text, "BASE"):
+		return r.parseBase()
+	}
+
+	if err := r.parseTriples(tok); err != nil {
+		return err
+	}
+	return r.expect(".")
+}
+
+// parsePrefix parses the remainder of a prefix directive.
+func (r *TurtleReader) parsePrefix() error {
+	tok, err := r.nextToken()
+	if err != nil {
+		return err
+	}
+	if tok.kind != tokPrefixedName || tok.local != "" {
+		return r.unexpected(tok)
+	}
+	prefix := tok.text
+
+	tok, err = r.nextToken()
+	if err != nil {
+		return err
+	}
+	if tok.kind != tokIRI {
+		return r.unexpected(tok)
+	}
+	iri, err := r.resolve(tok, tok.text)
+	if err != nil {
+		return err
+	}
+
+	r.prefixes[prefix] = iri
+	return nil
+}
+
+// parseBase parses the remainder of a base directive.
+func (r *TurtleReader) parseBase() error {
+	tok, err := r.nextToken()
+	if err != nil {
+		return err
+	}
+	if tok.kind != tokIRI {
+		return r.unexpected(tok)
+	}
+	iri, err := r.resolve(tok, tok.text)
+	if err != nil {
+		return err
+	}
+
+	r.base = iri
+	return nil
+}
+
+// parseTriples parses a subject, starting with tok, and its predicate object list.
+func (r *TurtleReader) parseTriples(tok turtleToken) error {
+	if tok.is(tokPunctuation, "[") {
+		subject, anon, err := r.parseBlankNodePropertyList()
+		if err != nil {
+			return err
+		}
+
+		// A blank node property list may stand alone as a statement
+		if !anon {
+			tok, err := r.peek()
+			if err != nil {
+				return err
+			}
+			if tok.is(tokPunctuation, ".") {
+				return nil
+			}
+		}
+		return r.parsePredicateObjectList(subject)
+	}
+
+	var subject RdfTerm
+	var err error
+
+	switch {
+	case tok.kind == tokIRI, tok.kind == tokPrefixedName:
+		subject, err = r.iri(tok)
+	case tok.kind == tokBlankNode:
+		subject = r.blankNode(tok.text)
+	case tok.is(tokPunctuation, "("):
+		subject, err = r.parseCollection()
+	case tok.is(tokPunctuation, "<<"):
+		subject, err = r.parseQuotedTriple(tok)
+	default:
+		return r.unexpected(tok)
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.parsePredicateObjectList(subject)
+}
+
+// parsePredicateObjectList parses one or more predicates, separated by ';',
+// each followed by a list of objects.
+func (r *TurtleReader) parsePredicateObjectList(subject RdfTerm) error {
+	for {
+		tok, err := r.nextToken()
+		if err != nil {
+			return err
+		}
+
+		predicate, err := r.parseVerb(tok)
+		if err != nil {
+			return err
+		}
+
+		if err := r.parseObjectList(subject, predicate); err != nil {
+			return err
+		}
+
+		tok, err = r.peek()
+		if err != nil {
+			return err
+		}
+		if !tok.is(tokPunctuation, ";") {
+			return nil
+		}
+		for tok.is(tokPunctuation, ";") {
+			r.nextToken()
+			tok, err = r.peek()
+			if err != nil {
+				return err
+			}
+		}
+
+		// A trailing ';' may end the list
+		if tok.kind != tokIRI && tok.kind != tokPrefixedName && !tok.is(tokKeyword, "a") {
+			return nil
+		}
+	}
+}
+
+// parseVerb parses a predicate, starting with tok, which is an IRI or the
+// keyword 'a'.
+func (r *TurtleReader) parseVerb(tok turtleToken) (RdfTerm, error) {
+	switch {
+	case tok.kind == tokIRI, tok.kind == tokPrefixedName:
+		return r.iri(tok)
+	case tok.is(tokKeyword, "a"):
+		return RdfTerm{Value: rdfType, TermType: RdfIri}, nil
+	}
+	return RdfTerm{}, r.unexpected(tok)
+}
+
+// parseObjectList parses one or more objects separated by ','.
+func (r *TurtleReader) parseObjectList(subject, predicate RdfTerm) error {
+	for {
+		object, err := r.parseObject()
+		if err != nil {
+			return err
+		}
+		r.emit(subject, predicate, object)
+
+		tok, err := r.peek()
+		if err != nil {
+			return err
+		}
+		if !tok.is(tokPunctuation, ",") {
+			return nil
+		}
+		r.nextToken()
+	}
+}
+
+// parseObject parses a single object, queueing any triples produced by
+// nested blank node property lists or collections.
+func (r *TurtleReader) parseObject() (RdfTerm, error) {
+	tok, err := r.nextToken()
+	if err != nil {
+		return RdfTerm{}, err
+	}
+
+	switch tok.kind {
+	case tokIRI, tokPrefixedName:
+		return r.iri(tok)
+	case tokBlankNode:
+		return r.blankNode(tok.text), nil
+	case tokString:
+		return r.parseLiteral(tok)
+	case tokInteger:
+		return RdfTerm{Value: tok.text, DataType: xsdInteger, TermType: RdfLiteral}, nil
+	case tokDecimal:
+		return RdfTerm{Value: tok.text, DataType: xsdDecimal, TermType: RdfLiteral}, nil
+	case tokDouble:
+		return RdfTerm{Value: tok.text, DataType: xsdDouble, TermType: RdfLiteral}, nil
+	case tokKeyword:
+		if tok.text == "true" || tok.text == "false" {
+			return RdfTerm{Value: tok.text, DataType: xsdBoolean, TermType: RdfLiteral}, nil
+		}
+	case tokPunctuation:
+		switch tok.text {
+		case "[":
+			object, _, err := r.parseBlankNodePropertyList()
+			return object, err
+		case "(":
+			return r.parseCollection()
+		case "<<", "<<(":
+			return r.parseQuotedTriple(tok)
+		}
+	}
+
+	return RdfTerm{}, r.unexpected(tok)
+}
+
+// parseQuotedTriple parses the remainder of a quoted triple after the opening
+// '<<', or of a triple term after the opening '<<(', including the closing
+// '>>' or ')>>'.
+func (r *TurtleReader) parseQuotedTriple(open turtleToken) (RdfTerm, error) {
+	tok, err := r.nextToken()
+	if err != nil {
+		return RdfTerm{}, err
+	}
+
+	var t Triple
+	switch {
+	case tok.kind == tokIRI, tok.kind == tokPrefixedName:
+		t.S, err = r.iri(tok)
+	case tok.kind == tokBlankNode:
+		t.S = r.blankNode(tok.text)
+	case tok.is(tokPunctuation, "<<"):
+		t.S, err = r.parseQuotedTriple(tok)
+	default:
+		return RdfTerm{}, r.unexpected(tok)
+	}
+	if err != nil {
+		return RdfTerm{}, err
+	}
+
+	if tok, err = r.nextToken(); err != nil {
+		return RdfTerm{}, err
+	}
+	if t.P, err = r.parseVerb(tok); err != nil {
+		return RdfTerm{}, err
+	}
+	if t.O, err = r.parseObject(); err != nil {
+		return RdfTerm{}, err
+	}
+
+	if open.text == "<<(" {
+		if err := r.expect(")"); err != nil {
+			return RdfTerm{}, err
+		}
+	}
+	if err := r.expect(">>"); err != nil {
+		return RdfTerm{}, err
+	}
+	return RdfTerm{TermType: RdfTriple, Triple: &t}, nil
+}
+
+// parseLiteral parses the optional language tag or datatype following a string.
+func (r *TurtleReader) parseLiteral(str turtleToken) (RdfTerm, error) {
+	term := RdfTerm{Value: str.text, TermType: RdfLiteral}
+
+	tok, err := r.peek()
+	if err != nil {
+		return RdfTerm{}, err
+	}
+
+	switch tok.kind {
+	case tokLangTag:
+		r.nextToken()
+		term.Language = tok.text
+	case tokDatatype:
+		r.nextToken()
+		tok, err = r.nextToken()
+		if err != nil {
+			return RdfTerm{}, err
+		}
+		if tok.kind != tokIRI && tok.kind != tokPrefixedName {
+			return RdfTerm{}, r.unexpected(tok)
+		}
+		datatype, err := r.iri(tok)
+		if err != nil {
+			return RdfTerm{}, err
+		}
+		term.DataType = datatype.Value
+	}
+
+	if r.ValidateLiterals && term.DataType != "" {
+		if err := ValidateLiteral(term); err != nil {
+			return RdfTerm{}, r.tokenError(str, err)
+		}
+	}
+	return term, nil
+}
+
+// parseBlankNodePropertyList parses the remainder of a blank node property
+// list after the opening '['. It reports whether the list was empty, in which
+// case it represents a single anonymous blank node.
+func (r *TurtleReader) parseBlankNodePropertyList() (RdfTerm, bool, error) {
+	node := r.newBlankNode()
+
+	tok, err := r.peek()
+	if err != nil {
+		return RdfTerm{}, false, err
+	}
+	if tok.is(tokPunctuation, "]") {
+		r.nextToken()
+		return node, true, nil
+	}
+
+	if err := r.parsePredicateObjectList(node); err != nil {
+		return RdfTerm{}, false, err
+	}
+	if err := r.expect("]"); err != nil {
+		return RdfTerm{}, false, err
+	}
+	return node, false, nil
+}
+
+// parseCollection parses the remainder of a collection after the opening '('
+// and returns the head of the list.
+func (r *TurtleReader) parseCollection() (RdfTerm, error) {
+	var items []RdfTerm
+	for {
+		tok, err := r.peek()
+		if err != nil {
+			return RdfTerm{}, err
+		}
+		if tok.is(tokPunctuation, ")") {
+			r.nextToken()
+			break
+		}
+
+		item, err := r.parseObject()
+		if err != nil {
+			return RdfTerm{}, err
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return RdfTerm{Value: rdfNil, TermType: RdfIri}, nil
+	}
+
+	first := RdfTerm{Value: rdfFirst, TermType: RdfIri}
+	rest := RdfTerm{Value: rdfRest, TermType: RdfIri}
+
+	head := r.newBlankNode()
+	node := head
+	for i, item := range items {
+		r.emit(node, first, item)
+		if i == len(items)-1 {
+			r.emit(node, rest, RdfTerm{Value: rdfNil, TermType: RdfIri})
+		} else {
+			next := r.newBlankNode()
+			r.emit(node, rest, next)
+			node = next
+		}
+	}
+	return head, nil
+}
+
+// iri returns the IRI term for an IRIREF or prefixed name token.
+func (r *TurtleReader) iri(tok turtleToken) (RdfTerm, error) {
+	if tok.kind == tokPrefixedName {
+		ns, ok := r.prefixes[tok.text]
+		if !ok {
+			return RdfTerm{}, r.tokenError(tok, ErrUndefinedPrefix)
+		}
+		iri := ns + tok.local
+		if r.ValidateIRIs && !isIRIReference(iri) {
+			return RdfTerm{}, r.tokenError(tok, ErrMalformedIri)
+		}
+		return RdfTerm{Value: iri, TermType: RdfIri}, nil
+	}
+
+	iri, err := r.resolve(tok, tok.text)
+	if err != nil {
+		return RdfTerm{}, err
+	}
+	return RdfTerm{Value: iri, TermType: RdfIri}, nil
+}
+
+// readRune reads one rune, keeping track of the line and column it was read from.
+func (r *TurtleReader) readRune() (rune, error) {
+	if n := len(r.pending); n > 0 {
+		p := r.pending[n-1]
+		r.pending = r.pending[:n-1]
+		r.line, r.column = p.line, p.column
+		return p.r, nil
+	}
+
+	r1, _, err := r.r.ReadRune()
+	if err != nil {
+		return r1, err
+	}
+
+	if r.newline {
+		r.rline++
+		r.rcolumn = 0
+	} else {
+		r.rcolumn++
+	}
+	r.newline = r1 == '\n'
+	r.line, r.column = r.rline, r.rcolumn
+	return r1, nil
+}
+
+// nextRune reads one rune, reporting the end of input as ErrUnexpectedEOF.
+func (r *TurtleReader) nextRune() (rune, error) {
+	r1, err := r.readRune()
+	if err != nil {
+		if err == io.EOF {
+			return r1, r.error(ErrUnexpectedEOF)
+		}
+		return r1, err
+	}
+	return r1, nil
+}
+
+// unreadRune puts r1 back so that it is returned by the next call to
+// readRune, with the position of the last rune read. Runes are returned in
+// the reverse order that they were unread.
+func (r *TurtleReader) unreadRune(r1 rune) {
+	r.pending = append(r.pending, turtleRune{r: r1, line: r.line, column: r.column})
+}
+
+// skipSpace skips whitespace and comments, returning the first rune that is neither.
+func (r *TurtleReader) skipSpace() (rune, error) {
+	for {
+		r1, err := r.readRune()
+		if err != nil {
+			return r1, err
+		}
+
+		switch r1 {
+		case ' ', '\t', '\n', '\r':
+		case '#':
+			for r1 != '\n' && r1 != '\r' {
+				r1, err = r.readRune()
+				if err != nil {
+					return r1, err
+				}
+			}
+		default:
+			return r1, nil
+		}
+	}
+}
+
+// scan reads the next token.
+func (r *TurtleReader) scan() (turtleToken, error) {
+	r1, err := r.skipSpace()
+	if err != nil {
+		if err == io.EOF {
+			return turtleToken{kind: tokEOF, line: r.line, column: r.column + 1}, nil
+		}
+		return turtleToken{}, err
+	}
+
+	tok := turtleToken{line: r.line, column: r.column}
+
+	switch {
+	case r1 == '<':
+		// '<<' opens a quoted triple and '<<(' a triple term
+		if r2, err2 := r.readRune(); err2 == nil {
+			if r2 == '<' {
+				tok.kind = tokPunctuation
+				tok.text = "<<"
+				if r3, err3 := r.readRune(); err3 == nil {
+					if r3 == '(' {
+						tok.text = "<<("
+					} else {
+						r.unreadRune(r3)
+					}
+				}
+				break
+			}
+			r.unreadRune(r2)
+		}
+		tok.kind = tokIRI
+		tok.text, err = r.scanIRI()
+	case r1 == '>':
+		tok.kind = tokPunctuation
+		tok.text = ">>"
+		if r1, err = r.nextRune(); err == nil && r1 != '>' {
+			err = r.error(ErrUnexpectedCharacter)
+		}
+	case r1 == '"' || r1 == '\'':
+		tok.kind = tokString
+		tok.text, err = r.scanString(r1)
+	case r1 == '@':
+		tok.kind = tokLangTag
+		tok.text, err = r.scanLangTag()
+	case r1 == '^':
+		tok.kind = tokDatatype
+		r1, err = r.nextRune()
+		if err == nil && r1 != '^' {
+			err = r.error(ErrUnexpectedCharacter)
+		}
+	case r1 == '_':
+		tok.kind = tokBlankNode
+		tok.text, err = r.scanBlankNodeLabel()
+	case r1 == ':' || isPNCharsBase(r1):
+		tok, err = r.scanName(tok, r1)
+	case isDigit(r1) || r1 == '+' || r1 == '-':
+		tok, err = r.scanNumber(tok, r1)
+	case r1 == '.':
+		// A '.' followed by a digit starts a decimal or double
+		r2, err2 := r.readRune()
+		if err2 == nil {
+			r.unreadRune(r2)
+			if isDigit(r2) {
+				return r.scanNumber(tok, r1)
+			}
+		}
+		tok.kind = tokPunctuation
+		tok.text = "."
+	case strings.ContainsRune(";,[]()", r1):
+		tok.kind = tokPunctuation
+		tok.text = string(r1)
+	default:
+		err = r.error(ErrUnexpectedCharacter)
+	}
+
+	return tok, err
+}
+
+// scanIRI reads the remainder of an IRIREF after the opening '<'.
+func (r *TurtleReader) scanIRI() (string, error) {
+	r.buf.Reset()
+	for {
+		r1, err := r.nextRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case r1 == '>':
+			return r.buf.String(), nil
+		case r1 == '\\':
+			r1, err = r.scanUchar()
+			if err != nil {
+				return "", err
+			}
+		case !isIRIChar(r1):
+			return "", r.error(ErrUnexpectedCharacter)
+		}
+		r.buf.WriteRune(r1)
+	}
+}
+
+// scanUchar reads the remainder of a UCHAR after the '\'.
+func (r *TurtleReader) scanUchar() (rune, error) {
+	r1, err := r.nextRune()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 4
+	switch r1 {
+	case 'u':
+	case 'U':
+		n = 8
+	default:
+		return 0, r.error(ErrUnexpectedCharacter)
+	}
+
+	var codepoint rune
+	for i := 0; i < n; i++ {
+		r1, err = r.nextRune()
+		if err != nil {
+			return 0, err
+		}
+		d, ok := hexValue(r1)
+		if !ok {
+			return 0, r.error(ErrUnexpectedCharacter)
+		}
+		codepoint = codepoint<<4 | d
+	}
+
+	if !utf8.ValidRune(codepoint) {
+		return 0, r.error(ErrUnexpectedCharacter)
+	}
+	return codepoint, nil
+}
+
+// scanString reads the remainder of a string literal after the opening quote q,
+// which may be the start of a long string.
+func (r *TurtleReader) scanString(q rune) (string, error) {
+	r.buf.Reset()
+
+	r1, err := r.nextRune()
+	if err != nil {
+		return "", err
+	}
+	long := false
+	if r1 == q {
+		r2, err := r.readRune()
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if err == nil && r2 == q {
+			long = true
+		} else {
+			if err == nil {
+				r.unreadRune(r2)
+			}
+			return "", nil
+		}
+	} else {
+		r.unreadRune(r1)
+	}
+
+	quotes := 0 // consecutive unescaped closing quotes seen in a long string
+	for {
+		r1, err := r.nextRune()
+		if err != nil {
+			return "", err
+		}
+
+		if r1 == q {
+			if !long {
+				return r.buf.String(), nil
+			}
+			quotes++
+			if quotes == 3 {
+				return r.buf.String(), nil
+			}
+			continue
+		}
+		for ; quotes > 0; quotes-- {
+			r.buf.WriteRune(q)
+		}
+
+		switch r1 {
+		case '\\':
+			r1, err = r.scanEscape()
+			if err != nil {
+				return "", err
+			}
+		case '\n', '\r':
+			if !long {
+				return "", r.error(ErrUnexpectedCharacter)
+			}
+		}
+		r.buf.WriteRune(r1)
+	}
+}
+
+// scanEscape reads the remainder of an ECHAR or UCHAR after the '\'.
+func (r *TurtleReader) scanEscape() (rune, error) {
+	r1, err := r.nextRune()
+	if err != nil {
+		return 0, err
+	}
+
+	switch r1 {
+	case 't':
+		return '\t', nil
+	case 'b':
+		return '\b', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 'f':
+		return '\f', nil
+	case '"', '\'', '\\':
+		return r1, nil
+	case 'u', 'U':
+		r.unreadRune(r1)
+		return r.scanUchar()
+	}
+	return 0, r.error(ErrUnexpectedCharacter)
+}
+
+// scanLangTag reads the remainder of a LANGTAG, or the name of a directive,
+// after the '@'.
+func (r *TurtleReader) scanLangTag() (string, error) {
+	r.buf.Reset()
+	for {
+		r1, err := r.readRune()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		if r1 != '-' && !isAlpha(r1) && !isDigit(r1) {
+			r.unreadRune(r1)
+			break
+		}
+		r.buf.WriteRune(r1)
+	}
+
+	if !isLangTag(r.buf.String()) {
+		return "", r.error(ErrUnexpectedCharacter)
+	}
+	return r.buf.String(), nil
+}
+
+// scanBlankNodeLabel reads the remainder of a BLANK_NODE_LABEL after the '_'.
+func (r *TurtleReader) scanBlankNodeLabel() (string, error) {
+	r1, err := r.nextRune()
+	if err != nil {
+		return "", err
+	}
+	if r1 != ':' {
+		return "", r.error(ErrUnexpectedCharacter)
+	}
+
+	r1, err = r.nextRune()
+	if err != nil {
+		return "", err
+	}
+	if !isPNCharsU(r1) && !isDigit(r1) {
+		return "", r.error(ErrUnexpectedCharacter)
+	}
+
+	r.buf.Reset()
+	r.buf.WriteRune(r1)
+	dots := 0
+	for {
+		r1, err = r.readRune()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		if !isPNChars(r1) && r1 != '.' {
+			r.unreadRune(r1)
+			break
+		}
+		if r1 == '.' {
+			dots++
+		} else {
+			dots = 0
+		}
+		r.buf.WriteRune(r1)
+	}
+
+	return r.trimDots(dots), nil
+}
+
+// trimDots removes the final n '.' characters from r.buf, unreading them so
+// that they can be read as punctuation, and returns the remaining contents.
+func (r *TurtleReader) trimDots(n int) string {
+	s := r.buf.String()
+	for i := 0; i < n; i++ {
+		r.unreadRune('.')
+	}
+	return s[:len(s)-n]
+}
+
+// scanName reads a prefixed name or keyword beginning with r1.
+func (r *TurtleReader) scanName(tok turtleToken, r1 rune) (turtleToken, error) {
+	r.buf.Reset()
+
+	if r1 != ':' {
+		r.buf.WriteRune(r1)
+		dots := 0
+		for {
+			var err error
+			r1, err = r.readRune()
+			if err != nil {
+				if err == io.EOF {
+					r1 = 0
+					break
+				}
+				return tok, err
+			}
+			if !isPNChars(r1) && r1 != '.' {
+				break
+			}
+			if r1 == '.' {
+				dots++
+			} else {
+				dots = 0
+			}
+			r.buf.WriteRune(r1)
+		}
+
+		if r1 != ':' || dots > 0 {
+			if r1 != 0 {
+				r.unreadRune(r1)
+			}
+			tok.kind = tokKeyword
+			tok.text = r.trimDots(dots)
+			switch {
+			case tok.text == "a", tok.text == "true", tok.text == "false",
+				strings.EqualFold(tok.text, "PREFIX"), strings.EqualFold(tok.text, "BASE"):
+				return tok, nil
+			}
+			return tok, r.tokenError(tok, ErrUnexpectedCharacter)
+		}
+	}
+
+	tok.kind = tokPrefixedName
+	tok.text = r.buf.String()
+
+	local, err := r.scanLocalName()
+	if err != nil {
+		return tok, err
+	}
+	tok.local = local
+	return tok, nil
+}
+
+// scanLocalName reads the PN_LOCAL part of a prefixed name, which may be empty.
+func (r *TurtleReader) scanLocalName() (string, error) {
+	r.buf.Reset()
+	dots := 0
+	for first := true; ; first = false {
+		r1, err := r.readRune()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+
+		switch {
+		case r1 == '\\':
+			r1, err = r.nextRune()
+			if err != nil {
+				return "", err
+			}
+			if !strings.ContainsRune("_~.-!$&'()*+,;=/?#@%", r1) {
+				return "", r.error(ErrUnexpectedCharacter)
+			}
+			r.buf.WriteRune(r1)
+			dots = 0
+			continue
+		case r1 == '%':
+			r.buf.WriteRune(r1)
+			for i := 0; i < 2; i++ {
+				r1, err = r.nextRune()
+				if err != nil {
+					return "", err
+				}
+				if _, ok := hexValue(r1); !ok {
+					return "", r.error(ErrUnexpectedCharacter)
+				}
+				r.buf.WriteRune(r1)
+			}
+			dots = 0
+			continue
+		case first && (isPNCharsU(r1) || r1 == ':' || isDigit(r1)):
+		case !first && (isPNChars(r1) || r1 == ':' || r1 == '.'):
+		default:
+			r.unreadRune(r1)
+			return r.trimDots(dots), nil
+		}
+
+		if r1 == '.' {
+			dots++
+		} else {
+			dots = 0
+		}
+		r.buf.WriteRune(r1)
+	}
+
+	return r.trimDots(dots), nil
+}
+
+// scanNumber reads an INTEGER, DECIMAL or DOUBLE beginning with r1.
+func (r *TurtleReader) scanNumber(tok turtleToken, r1 rune) (turtleToken, error) {
+	r.buf.Reset()
+	tok.kind = tokInteger
+
+	var err error
+	if r1 == '+' || r1 == '-' {
+		r.buf.WriteRune(r1)
+		r1, err = r.nextRune()
+		if err != nil {
+			return tok, err
+		}
+	}
+
+	digits := 0
+	for isDigit(r1) {
+		r.buf.WriteRune(r1)
+		digits++
+		if r1, err = r.readRune(); err != nil {
+			r1 = 0
+			break
+		}
+	}
+
+	if r1 == '.' {
+		r2, err := r.readRune()
+		if err == nil && (isDigit(r2) || (digits > 0 && (r2 == 'e' || r2 == 'E'))) {
+			tok.kind = tokDecimal
+			r.buf.WriteRune(r1)
+			r1 = r2
+			for isDigit(r1) {
+				r.buf.WriteRune(r1)
+				digits++
+				if r1, err = r.readRune(); err != nil {
+					r1 = 0
+					break
+				}
+			}
+		} else {
+			// The '.' ends the statement rather than being part of the number
+			if err == nil {
+				r.unreadRune(r2)
+			}
+		}
+	}
+
+	if digits == 0 {
+		return tok, r.error(ErrUnexpectedCharacter)
+	}
+
+	if r1 == 'e' || r1 == 'E' {
+		tok.kind = tokDouble
+		r.buf.WriteRune(r1)
+		r1, err = r.nextRune()
+		if err != nil {
+			return tok, err
+		}
+		if r1 == '+' || r1 == '-' {
+			r.buf.WriteRune(r1)
+			r1, err = r.nextRune()
+			if err != nil {
+				return tok, err
+			}
+		}
+		if !isDigit(r1) {
+			return tok, r.error(ErrUnexpectedCharacter)
+		}
+		for isDigit(r1) {
+			r.buf.WriteRune(r1)
+			if r1, err = r.readRune(); err != nil {
+				r1 = 0
+				break
+			}
+		}
+	}
+
+	if r1 != 0 {
+		r.unreadRune(r1)
+	}
+	tok.text = r.buf.String()
+	return tok, nil
+}
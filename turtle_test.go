@@ -0,0 +1,285 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var turtleCases = map[string]string{
+	// Plain triples
+	`<http://example.org/s> <http://example.org/p> <http://example.org/o> .`: `<http://example.org/s> <http://example.org/p> <http://example.org/o> .`,
+
+	// Prefixes and base
+	`@prefix ex: <http://example.org/> .
+	 @prefix : <http://example.org/default#> .
+	 ex:s :p ex:o .`: `<http://example.org/s> <http://example.org/default#p> <http://example.org/o> .`,
+
+	`PREFIX ex: <http://example.org/>
+	 prefix ex2: <http://example.org/2/>
+	 ex:s ex2:p ex:o .`: `<http://example.org/s> <http://example.org/2/p> <http://example.org/o> .`,
+
+	`@base <http://example.org/a/b/c> .
+	 <s> <#p> <../o> .
+	 BASE <http://example.org/x/>
+	 @prefix rel: <y/> .
+	 <> rel:p <//other.org/z> .`: `<http://example.org/a/b/s> <http://example.org/a/b/c#p> <http://example.org/a/o> .
+<http://example.org/x/> <http://example.org/x/y/p> <http://other.org/z> .`,
+
+	// Prefixed names
+	`@prefix ex: <http://example.org/> .
+	 @prefix ex.2: <http://example.org/2/> .
+	 ex:s.1 ex.2:p-q_r ex:a\~b\.c%20d:e.`: `<http://example.org/s.1> <http://example.org/2/p-q_r> <http://example.org/a~b.c%20d:e> .`,
+
+	`@prefix ex: <http://example.org/> .
+	 ex: ex:1 ex:o.`: `<http://example.org/> <http://example.org/1> <http://example.org/o> .`,
+
+	// Keyword a
+	`@prefix ex: <http://example.org/> . ex:s a ex:Type .`: `<http://example.org/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Type> .`,
+
+	// Predicate and object lists
+	`@prefix ex: <http://example.org/> .
+	 ex:s ex:p ex:o1 , ex:o2 ;
+	      ex:q ex:o3 ;;
+	      a ex:T ; .`: `<http://example.org/s> <http://example.org/p> <http://example.org/o1> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o2> .
+<http://example.org/s> <http://example.org/q> <http://example.org/o3> .
+<http://example.org/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/T> .`,
+
+	// Blank nodes
+	`@prefix ex: <http://example.org/> .
+	 _:a ex:p _:b.c .
+	 _:genid0 ex:p [] .`: `_:a <http://example.org/p> _:b.c .
+_:genid_genid0 <http://example.org/p> _:genid0 .`,
+
+	`@prefix ex: <http://example.org/> .
+	 ex:s ex:p [ ex:q "x" ; ex:r [ ex:t ex:u ] ] .`: `_:genid0 <http://example.org/q> "x" .
+_:genid1 <http://example.org/t> <http://example.org/u> .
+_:genid0 <http://example.org/r> _:genid1 .
+<http://example.org/s> <http://example.org/p> _:genid0 .`,
+
+	`@prefix ex: <http://example.org/> .
+	 [ ex:q ex:o ] .
+	 [ ex:q ex:o ] ex:p ex:o2 .
+	 [] ex:p ex:o3 .`: `_:genid0 <http://example.org/q> <http://example.org/o> .
+_:genid1 <http://example.org/q> <http://example.org/o> .
+_:genid1 <http://example.org/p> <http://example.org/o2> .
+_:genid2 <http://example.org/p> <http://example.org/o3> .`,
+
+	// Collections
+	`@prefix ex: <http://example.org/> .
+	 ex:s ex:p ( ex:a "b" ( ) 1 ) .`: `_:genid0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/a> .
+_:genid0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:genid1 .
+_:genid1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "b" .
+_:genid1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:genid2 .
+_:genid2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+_:genid2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:genid3 .
+_:genid3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+_:genid3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+<http://example.org/s> <http://example.org/p> _:genid0 .`,
+
+	`@prefix ex: <http://example.org/> .
+	 ( ex:a ) ex:p () .`: `_:genid0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/a> .
+_:genid0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+_:genid0 <http://example.org/p> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .`,
+
+	// Numeric and boolean literals
+	`@prefix ex: <http://example.org/> .
+	 ex:s ex:p 1, -2, +3.5, .5, 4, 1e10, -1.5E-3, 2.e1, true, false.`: `<http://example.org/s> <http://example.org/p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/s> <http://example.org/p> "-2"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/s> <http://example.org/p> "+3.5"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<http://example.org/s> <http://example.org/p> ".5"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<http://example.org/s> <http://example.org/p> "4"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/s> <http://example.org/p> "1e10"^^<http://www.w3.org/2001/XMLSchema#double> .
+<http://example.org/s> <http://example.org/p> "-1.5E-3"^^<http://www.w3.org/2001/XMLSchema#double> .
+<http://example.org/s> <http://example.org/p> "2.e1"^^<http://www.w3.org/2001/XMLSchema#double> .
+<http://example.org/s> <http://example.org/p> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<http://example.org/s> <http://example.org/p> "false"^^<http://www.w3.org/2001/XMLSchema#boolean> .`,
+
+	// String literals
+	`@prefix ex: <http://example.org/> .
+	 @prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+	 ex:s ex:p "a\tb", 'c"d', "chat"@en-GB, "5"^^xsd:int, '1'^^<http://example.org/dt>, "", '' .`: `<http://example.org/s> <http://example.org/p> "a\tb" .
+<http://example.org/s> <http://example.org/p> "c\"d" .
+<http://example.org/s> <http://example.org/p> "chat"@en-GB .
+<http://example.org/s> <http://example.org/p> "5"^^<http://www.w3.org/2001/XMLSchema#int> .
+<http://example.org/s> <http://example.org/p> "1"^^<http://example.org/dt> .
+<http://example.org/s> <http://example.org/p> "" .
+<http://example.org/s> <http://example.org/p> "" .`,
+
+	"@prefix ex: <http://example.org/> .\nex:s ex:p \"\"\"line 1\n\"line\" \"\"2\\\"\"\"\" , '''it's\r\n\\''''@en, \"\"\"\"\"\" .": `<http://example.org/s> <http://example.org/p> "line 1\n\"line\" \"\"2\"" .
+<http://example.org/s> <http://example.org/p> "it's\r\n'"@en .
+<http://example.org/s> <http://example.org/p> "" .`,
+
+	// Quoted triples
+	`@prefix ex: <http://example.org/> .
+	 << ex:s a ex:C >> ex:source <<( _:b ex:p << ex:s ex:p 1 >> )>>, <<<http://example.org/s>ex:p"x">> .`: `<< <http://example.org/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/C> >> <http://example.org/source> << _:b <http://example.org/p> << <http://example.org/s> <http://example.org/p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> >> >> .
+<< <http://example.org/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/C> >> <http://example.org/source> << <http://example.org/s> <http://example.org/p> "x" >> .`,
+
+	// Comments and whitespace
+	"# comment\n@prefix ex: <http://example.org/> . # comment\n\n ex:s # comment\n ex:p\n\tex:o # comment\n .": `<http://example.org/s> <http://example.org/p> <http://example.org/o> .`,
+}
+
+var negativeTurtleCases = map[string]error{
+	`<http://example.org/s> <http://example.org/p> <http://example.org/o>`:        ErrUnexpectedEOF,
+	`<http://example.org/s> <http://example.org/p> .`:                             ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> <http://example.org/o> ;; , .`: ErrUnexpectedCharacter,
+	`ex:s <http://example.org/p> <http://example.org/o> .`:                        ErrUndefinedPrefix,
+	`<s> <http://example.org/p> <http://example.org/o> .`:                         ErrRelativeIri,
+	`"s" <http://example.org/p> <http://example.org/o> .`:                         ErrUnexpectedCharacter,
+	`<http://example.org/s> "p" <http://example.org/o> .`:                         ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> "abc .`:                        ErrUnexpectedEOF,
+	"<http://example.org/s> <http://example.org/p> \"a\nb\" .":                    ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> "\q" .`:                        ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> "x"@1 .`:                       ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> [ <http://example.org/q> .`:    ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> ( <http://example.org/q> .`:    ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> 1e .`:                          ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> maybe .`:                       ErrUnexpectedCharacter,
+	`[] .`:                                                   ErrUnexpectedCharacter,
+	`@prefix ex <http://example.org/> .`:                     ErrUnexpectedCharacter,
+	`@prefix ex: <http://example.org/>`:                      ErrUnexpectedEOF,
+	`@prefix ex.: <http://example.org/> .`:                   ErrUnexpectedCharacter,
+	`@prefix ex: <http://example.org/> . ex:a\z ex:p ex:o .`: ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> _:a:b .`:  ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> <http://example.org/o> <http://e.org/g> .`:                 ErrUnexpectedCharacter,
+	`<<( <http://example.org/s> <http://example.org/p> <http://example.org/o> )>> <http://example.org/p> 1 .`: ErrUnexpectedCharacter,
+	`<< "s" <http://example.org/p> <http://example.org/o> >> <http://example.org/p> 1 .`:                      ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> << <http://example.org/s> <http://example.org/p> 1 > .`:    ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> <<( <http://example.org/s> <http://example.org/p> 1 >> .`:  ErrUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> << <http://example.org/s> <http://example.org/p> 1`:        ErrUnexpectedEOF,
+}
+
+// readTurtle reads all the triples from a Turtle document, returning them in N-Triples.
+func readTurtle(doc string) (string, error) {
+	var lines []string
+	r := NewTurtleReader(strings.NewReader(doc))
+	for r.Next() {
+		lines = append(lines, r.Triple().String())
+	}
+	return strings.Join(lines, "\n"), r.Err()
+}
+
+func TestReadTurtle(t *testing.T) {
+	for doc, expected := range turtleCases {
+		t.Run("", func(t *testing.T) {
+			actual, err := readTurtle(doc)
+			if err != nil {
+				t.Fatalf("Got unexpected error %v for:\n%s", err, doc)
+			}
+			if actual != expected {
+				t.Errorf("Expected:\n%s\nbut got:\n%s\nfor:\n%s", expected, actual, doc)
+			}
+		})
+	}
+}
+
+func TestReadTurtleErrors(t *testing.T) {
+	for doc, expected := range negativeTurtleCases {
+		t.Run("", func(t *testing.T) {
+			_, err := readTurtle(doc)
+			if err == nil {
+				t.Errorf("Expected %s for %s but no error reported", expected, doc)
+			} else if err.(*ParseError).Err != expected {
+				t.Errorf("Expected %s for %s but got error %s", expected, doc, err)
+			}
+		})
+	}
+}
+
+func TestReadTurtleValidateLiterals(t *testing.T) {
+	r := NewTurtleReader(strings.NewReader("@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .\n<http://example.org/s> <http://example.org/p> 1, \"2002-02-30\"^^xsd:date ."))
+	r.ValidateLiterals = true
+	if r.Next() {
+		t.Fatalf("Expected an error but got %s", r.Triple())
+	}
+
+	err, ok := r.Err().(*ParseError)
+	if !ok || err.Line != 2 || err.Column != 49 || !errors.Is(err, ErrIllTypedLiteral) {
+		t.Errorf("Got unexpected error %#v", r.Err())
+	}
+}
+
+func TestReadTurtleBase(t *testing.T) {
+	r := NewTurtleReader(strings.NewReader(`<s> <p> <#o> .`))
+	r.Base = "http://example.org/doc"
+	if !r.Next() {
+		t.Fatalf("Got unexpected error %v", r.Err())
+	}
+
+	expected := `<http://example.org/s> <http://example.org/p> <http://example.org/doc#o> .`
+	if r.Triple().String() != expected {
+		t.Errorf("Expected %s but got %s", expected, r.Triple())
+	}
+}
+
+func TestReadTurtleErrorPosition(t *testing.T) {
+	_, err := readTurtle("@prefix ex: <http://example.org/> .\n\nex:s ex:p\n   nope:o .")
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("Expected ParseError but got %v", err)
+	}
+	if perr.Line != 4 || perr.Column != 3 || perr.Err != ErrUndefinedPrefix {
+		t.Errorf("Expected line 4, column 3: %s but got %s", ErrUndefinedPrefix, perr)
+	}
+}
+
+func TestResolveIRI(t *testing.T) {
+	// Examples from RFC 3986 section 5.4
+	base := "http://a/b/c/d;p?q"
+	cases := map[string]string{
+		"g:h":           "g:h",
+		"g":             "http://a/b/c/g",
+		"./g":           "http://a/b/c/g",
+		"g/":            "http://a/b/c/g/",
+		"/g":            "http://a/g",
+		"//g":           "http://g",
+		"?y":            "http://a/b/c/d;p?y",
+		"g?y":           "http://a/b/c/g?y",
+		"#s":            "http://a/b/c/d;p?q#s",
+		"g#s":           "http://a/b/c/g#s",
+		"g?y#s":         "http://a/b/c/g?y#s",
+		";x":            "http://a/b/c/;x",
+		"g;x":           "http://a/b/c/g;x",
+		"g;x?y#s":       "http://a/b/c/g;x?y#s",
+		"":              "http://a/b/c/d;p?q",
+		".":             "http://a/b/c/",
+		"./":            "http://a/b/c/",
+		"..":            "http://a/b/",
+		"../":           "http://a/b/",
+		"../g":          "http://a/b/g",
+		"../..":         "http://a/",
+		"../../":        "http://a/",
+		"../../g":       "http://a/g",
+		"../../../g":    "http://a/g",
+		"../../../../g": "http://a/g",
+		"/./g":          "http://a/g",
+		"/../g":         "http://a/g",
+		"g.":            "http://a/b/c/g.",
+		".g":            "http://a/b/c/.g",
+		"g..":           "http://a/b/c/g..",
+		"..g":           "http://a/b/c/..g",
+		"./../g":        "http://a/b/g",
+		"./g/.":         "http://a/b/c/g/",
+		"g/./h":         "http://a/b/c/g/h",
+		"g/../h":        "http://a/b/c/h",
+		"g;x=1/./y":     "http://a/b/c/g;x=1/y",
+		"g;x=1/../y":    "http://a/b/c/y",
+		"g?y/./x":       "http://a/b/c/g?y/./x",
+		"g?y/../x":      "http://a/b/c/g?y/../x",
+		"g#s/./x":       "http://a/b/c/g#s/./x",
+		"g#s/../x":      "http://a/b/c/g#s/../x",
+		"http:g":        "http:g",
+	}
+
+	for ref, expected := range cases {
+		if actual := resolveIRI(base, ref); actual != expected {
+			t.Errorf("resolveIRI(%q, %q): expected %q but got %q", base, ref, expected, actual)
+		}
+	}
+}
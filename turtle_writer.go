@@ -0,0 +1,514 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A TurtleWriter writes triples as a Turtle document.
+//
+// Triples are buffered by Write and the document is only produced by Flush,
+// since abbreviating blank nodes requires knowledge of every triple that
+// refers to them. Triples with the same subject are grouped into a single
+// statement, using ';' between predicates and ',' between objects. Blank
+// nodes that are the object of exactly one triple are written inline as
+// [ ... ] and well-formed rdf:first/rdf:rest lists as collections. Quoted
+// triples are written as in Turtle-star.
+//
+// The exported fields can be changed to customize the details before the
+// first call to Flush.
+type TurtleWriter struct {
+	// Prefixes maps prefix names to namespace IRIs. IRIs are abbreviated to
+	// prefixed names using the longest matching namespace when the remainder
+	// is a legal local name. Only prefixes that are used are declared.
+	Prefixes map[string]string
+
+	w       *bufio.Writer
+	triples []Triple
+	seen    map[string]bool // canonical encodings of the triples added
+}
+
+// NewTurtleWriter returns a new TurtleWriter that writes to w.
+func NewTurtleWriter(w io.Writer) *TurtleWriter {
+	return &TurtleWriter{
+		w:    bufio.NewWriter(w),
+		seen: map[string]bool{},
+	}
+}
+
+// Write adds a single triple to the document. The triple is validated first
+// and is not added if it cannot be represented in Turtle. Duplicate triples
+// are ignored, comparing triples as for Triple.Equal.
+func (w *TurtleWriter) Write(t Triple) error {
+	if err := validateTriple(t); err != nil {
+		return err
+	}
+	key := t.CanonicalString()
+	if w.seen[key] {
+		return nil
+	}
+	w.seen[key] = true
+	w.triples = append(w.triples, t)
+	return nil
+}
+
+// WriteAll adds multiple triples to the document using Write and then calls Flush.
+func (w *TurtleWriter) WriteAll(triples []Triple) error {
+	for _, t := range triples {
+		if err := w.Write(t); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Flush writes the triples added since the last call to Flush as a Turtle
+// document to the underlying io.Writer. To check if an error occurred during
+// the Flush, call Error.
+func (w *TurtleWriter) Flush() {
+	if len(w.triples) > 0 {
+		s := newTurtleSerializer(w.Prefixes, w.triples)
+		w.w.Write(s.serialize())
+		w.triples = nil
+		w.seen = map[string]bool{}
+	}
+	w.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Flush.
+func (w *TurtleWriter) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+var (
+	turtleInteger = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	turtleDecimal = regexp.MustCompile(`^[+-]?[0-9]*\.[0-9]+$`)
+	turtleDouble  = regexp.MustCompile(`^[+-]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)[eE][+-]?[0-9]+$`)
+)
+
+// A turtlePrefix is a prefix name and the namespace it abbreviates.
+type turtlePrefix struct {
+	name      string
+	namespace string
+}
+
+// A turtleSerializer writes a single set of triples as a Turtle document.
+type turtleSerializer struct {
+	prefixes []turtlePrefix // longest namespace first
+	used     map[string]bool
+
+	// Terms are keyed by their canonical encoding so that terms that are
+	// Equal share an entry, including quoted triples that do not share a
+	// pointer.
+	subjects []RdfTerm           // subjects in order of first appearance
+	props    map[string][]Triple // triples for each subject
+	refs     map[string]int      // number of references to each blank node, see addRefs
+	emitted  map[string]bool     // subjects and blank nodes that have been written
+	labels   map[string]string   // labels for blank nodes that cannot be written inline
+
+	buf []byte
+}
+
+func newTurtleSerializer(prefixes map[string]string, triples []Triple) *turtleSerializer {
+	s := &turtleSerializer{
+		used:    map[string]bool{},
+		props:   map[string][]Triple{},
+		refs:    map[string]int{},
+		emitted: map[string]bool{},
+		labels:  map[string]string{},
+	}
+
+	for name, ns := range prefixes {
+		if isTurtlePrefix(name) && ns != "" {
+			s.prefixes = append(s.prefixes, turtlePrefix{name: name, namespace: ns})
+		}
+	}
+	sort.Slice(s.prefixes, func(i, j int) bool {
+		if len(s.prefixes[i].namespace) != len(s.prefixes[j].namespace) {
+			return len(s.prefixes[i].namespace) > len(s.prefixes[j].namespace)
+		}
+		return s.prefixes[i].name < s.prefixes[j].name
+	})
+
+	for _, t := range triples {
+		key := t.S.CanonicalString()
+		if _, exists := s.props[key]; !exists {
+			s.subjects = append(s.subjects, t.S)
+		}
+		s.props[key] = append(s.props[key], t)
+		s.addRefs(t.S, 0)
+		s.addRefs(t.O, 1)
+	}
+
+	return s
+}
+
+// addRefs adds n to the number of references to t if it is a blank node. A
+// blank node in a quoted triple can only be written with a label, so each
+// blank node in a quoted triple t counts as two references.
+func (s *turtleSerializer) addRefs(t RdfTerm, n int) {
+	switch {
+	case t.IsBlank():
+		s.refs[t.CanonicalString()] += n
+	case t.IsTriple() && t.Triple != nil:
+		s.addRefs(t.Triple.S, 2)
+		s.addRefs(t.Triple.O, 2)
+	}
+}
+
+// serialize returns the document, with prefix declarations for every prefix used.
+func (s *turtleSerializer) serialize() []byte {
+	// Subjects that cannot be written inline are written first, in the order
+	// they were seen. Any remaining blank nodes form cycles that could not
+	// be written inline so one of each cycle is written with a label.
+	for _, subject := range s.subjects {
+		if !s.inlinable(subject) {
+			s.writeStatement(subject)
+		}
+	}
+	for _, subject := range s.subjects {
+		if !s.emitted[subject.CanonicalString()] {
+			s.writeStatement(subject)
+		}
+	}
+
+	var names []string
+	for _, p := range s.prefixes {
+		if s.used[p.name] {
+			names = append(names, p.name)
+		}
+	}
+	sort.Strings(names)
+
+	var doc []byte
+	for _, name := range names {
+		for _, p := range s.prefixes {
+			if p.name == name {
+				doc = append(doc, "@prefix "...)
+				doc = append(doc, name...)
+				doc = append(doc, ": "...)
+				doc = appendIRI(doc, p.namespace)
+				doc = append(doc, " .\n"...)
+			}
+		}
+	}
+	if len(names) > 0 {
+		doc = append(doc, '\n')
+	}
+
+	return append(doc, s.buf...)
+}
+
+// inlinable reports whether t is a blank node that can be written inline as
+// the object of the only triple that refers to it.
+func (s *turtleSerializer) inlinable(t RdfTerm) bool {
+	return t.IsBlank() && s.refs[t.CanonicalString()] == 1
+}
+
+// writeStatement writes subject and all its triples as a single statement.
+func (s *turtleSerializer) writeStatement(subject RdfTerm) {
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	}
+	s.emitted[subject.CanonicalString()] = true
+
+	if subject.IsBlank() && s.refs[subject.CanonicalString()] == 0 {
+		s.buf = append(s.buf, '[', ' ')
+		s.writePredicateObjectList(subject, 1)
+		s.buf = append(s.buf, " ] .\n"...)
+		return
+	}
+
+	s.writeTerm(subject)
+	s.buf = append(s.buf, ' ')
+	s.writePredicateObjectList(subject, 1)
+	s.buf = append(s.buf, " .\n"...)
+}
+
+// writePredicateObjectList writes the triples of subject grouped by predicate.
+func (s *turtleSerializer) writePredicateObjectList(subject RdfTerm, depth int) {
+	triples := s.props[subject.CanonicalString()]
+
+	var predicates []RdfTerm
+	objects := map[RdfTerm][]RdfTerm{}
+	for _, t := range triples {
+		if _, exists := objects[t.P]; !exists {
+			predicates = append(predicates, t.P)
+		}
+		objects[t.P] = append(objects[t.P], t.O)
+	}
+
+	for i, p := range predicates {
+		if i > 0 {
+			s.buf = append(s.buf, " ;\n"...)
+			s.indent(depth)
+		}
+		if p.Value == rdfType {
+			s.buf = append(s.buf, 'a')
+		} else {
+			s.writeTerm(p)
+		}
+		s.buf = append(s.buf, ' ')
+
+		for j, o := range objects[p] {
+			if j > 0 {
+				s.buf = append(s.buf, ", "...)
+			}
+			s.writeObject(o, depth)
+		}
+	}
+}
+
+// writeObject writes o, inlining it if it is a blank node that is not referred
+// to elsewhere.
+func (s *turtleSerializer) writeObject(o RdfTerm, depth int) {
+	if o.IsIRI() && o.Value == rdfNil {
+		s.buf = append(s.buf, '(', ')')
+		return
+	}
+
+	if !s.inlinable(o) || s.emitted[o.CanonicalString()] {
+		s.writeTerm(o)
+		return
+	}
+	s.emitted[o.CanonicalString()] = true
+
+	if items, ok := s.collection(o); ok {
+		s.buf = append(s.buf, '(')
+		for _, item := range items {
+			s.buf = append(s.buf, ' ')
+			s.writeObject(item, depth)
+		}
+		s.buf = append(s.buf, ' ', ')')
+		return
+	}
+
+	if len(s.props[o.CanonicalString()]) == 0 {
+		s.buf = append(s.buf, '[', ']')
+		return
+	}
+
+	s.buf = append(s.buf, "[\n"...)
+	s.indent(depth + 1)
+	s.writePredicateObjectList(o, depth+1)
+	s.buf = append(s.buf, '\n')
+	s.indent(depth)
+	s.buf = append(s.buf, ']')
+}
+
+// collection returns the items of the list whose head is node, if node
+// begins a well-formed list that can be written as a collection. Every node
+// of the list is marked as emitted.
+func (s *turtleSerializer) collection(head RdfTerm) ([]RdfTerm, bool) {
+	var items []RdfTerm
+	var nodes []RdfTerm
+	visited := map[string]bool{}
+
+	node := head
+	for {
+		if visited[node.CanonicalString()] || (node != head && (!s.inlinable(node) || s.emitted[node.CanonicalString()])) {
+			return nil, false
+		}
+		visited[node.CanonicalString()] = true
+
+		triples := s.props[node.CanonicalString()]
+		if len(triples) != 2 {
+			return nil, false
+		}
+
+		var first, rest *RdfTerm
+		for i := range triples {
+			switch triples[i].P.Value {
+			case rdfFirst:
+				first = &triples[i].O
+			case rdfRest:
+				rest = &triples[i].O
+			}
+		}
+		if first == nil || rest == nil {
+			return nil, false
+		}
+
+		items = append(items, *first)
+		nodes = append(nodes, node)
+
+		if rest.IsIRI() && rest.Value == rdfNil {
+			break
+		}
+		if !rest.IsBlank() {
+			return nil, false
+		}
+		node = *rest
+	}
+
+	for _, node := range nodes {
+		s.emitted[node.CanonicalString()] = true
+	}
+	return items, true
+}
+
+// writeTerm writes t, abbreviating it where possible.
+func (s *turtleSerializer) writeTerm(t RdfTerm) {
+	switch t.TermType {
+	case RdfIri:
+		s.writeIRI(t.Value)
+	case RdfBlank:
+		s.buf = append(s.buf, '_', ':')
+		s.buf = append(s.buf, s.label(t)...)
+	case RdfLiteral:
+		switch {
+		case t.DataType == xsdInteger && turtleInteger.MatchString(t.Value),
+			t.DataType == xsdDecimal && turtleDecimal.MatchString(t.Value),
+			t.DataType == xsdDouble && turtleDouble.MatchString(t.Value),
+			t.DataType == xsdBoolean && (t.Value == "true" || t.Value == "false"):
+			s.buf = append(s.buf, t.Value...)
+			return
+		}
+
+		s.buf = appendLiteral(s.buf, t.Value)
+		if t.Language != "" {
+			s.buf = append(s.buf, '@')
+			s.buf = append(s.buf, t.Language...)
+		} else if t.DataType != "" && t.DataType != xsdString {
+			s.buf = append(s.buf, '^', '^')
+			s.writeIRI(t.DataType)
+		}
+	case RdfTriple:
+		s.buf = append(s.buf, '<', '<', ' ')
+		s.writeTerm(t.Triple.S)
+		s.buf = append(s.buf, ' ')
+		s.writeTerm(t.Triple.P)
+		s.buf = append(s.buf, ' ')
+		s.writeTerm(t.Triple.O)
+		s.buf = append(s.buf, ' ', '>', '>')
+	}
+}
+
+// writeIRI writes iri as a prefixed name if possible, otherwise as an IRIREF.
+func (s *turtleSerializer) writeIRI(iri string) {
+	for _, p := range s.prefixes {
+		if !strings.HasPrefix(iri, p.namespace) {
+			continue
+		}
+		local, ok := turtleLocalName(iri[len(p.namespace):])
+		if !ok {
+			continue
+		}
+		s.used[p.name] = true
+		s.buf = append(s.buf, p.name...)
+		s.buf = append(s.buf, ':')
+		s.buf = append(s.buf, local...)
+		return
+	}
+	s.buf = appendIRI(s.buf, iri)
+}
+
+// label returns the label to use for the blank node t. Labels that are not
+// legal in Turtle are replaced by ones that are not used by any other blank node.
+func (s *turtleSerializer) label(t RdfTerm) string {
+	if label, ok := s.labels[t.CanonicalString()]; ok {
+		return label
+	}
+
+	label := t.Value
+	if !isTurtleBlankNodeLabel(label) {
+		for i := 0; ; i++ {
+			label = "b" + strconv.Itoa(i)
+			candidate := RdfTerm{Value: label, TermType: RdfBlank}
+			if _, exists := s.props[candidate.CanonicalString()]; !exists && s.refs[candidate.CanonicalString()] == 0 && !s.isLabel(label) {
+				break
+			}
+		}
+	}
+
+	s.labels[t.CanonicalString()] = label
+	return label
+}
+
+// isLabel reports whether label has already been assigned to a blank node.
+func (s *turtleSerializer) isLabel(label string) bool {
+	for _, l := range s.labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *turtleSerializer) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		s.buf = append(s.buf, "    "...)
+	}
+}
+
+// turtleLocalName returns local escaped as a PN_LOCAL, or false if it cannot
+// be written as one.
+func turtleLocalName(local string) (string, bool) {
+	var b strings.Builder
+	for i, r1 := range local {
+		first := i == 0
+		last := i+len(string(r1)) == len(local)
+
+		switch {
+		case r1 == '%':
+			if i+2 < len(local) && isHex(local[i+1]) && isHex(local[i+2]) {
+				b.WriteRune(r1)
+			} else {
+				b.WriteString(`\%`)
+			}
+		case r1 == '.' && (first || last):
+			b.WriteString(`\.`)
+		case first && (isPNCharsU(r1) || r1 == ':' || isDigit(r1)),
+			!first && (isPNChars(r1) || r1 == ':' || r1 == '.'):
+			b.WriteRune(r1)
+		case strings.ContainsRune("_~.-!$&'()*+,;=/?#@", r1):
+			b.WriteByte('\\')
+			b.WriteRune(r1)
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// isTurtlePrefix reports whether s is a legal PN_PREFIX, which may be empty.
+func isTurtlePrefix(s string) bool {
+	if s == "" {
+		return true
+	}
+	if s[len(s)-1] == '.' {
+		return false
+	}
+	for i, r1 := range s {
+		if i == 0 {
+			if !isPNCharsBase(r1) {
+				return false
+			}
+		} else if !isPNChars(r1) && r1 != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// isTurtleBlankNodeLabel reports whether s is a legal Turtle blank node label,
+// which unlike N-Triples may not contain ':'.
+func isTurtleBlankNodeLabel(s string) bool {
+	return isBlankNodeLabel(s) && !strings.ContainsRune(s, ':')
+}
+
+func isHex(c byte) bool {
+	_, ok := hexValue(rune(c))
+	return ok
+}
@@ -0,0 +1,277 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var turtleWritePrefixes = map[string]string{
+	"ex":   "http://example.org/",
+	"ex2":  "http://example.org/2/",
+	"rdf":  rdfNamespace,
+	"xsd":  xsdNamespace,
+	"":     "http://example.org/default#",
+	"bad.": "http://example.org/bad/",
+}
+
+// turtleWriteCases maps N-Triples input to the Turtle expected from a
+// TurtleWriter using turtleWritePrefixes.
+var turtleWriteCases = map[string]string{
+	// Prefixed names
+	`<http://example.org/s> <http://example.org/p> <http://example.org/o> .`: `@prefix ex: <http://example.org/> .
+
+ex:s ex:p ex:o .
+`,
+
+	`<http://example.org/2/s> <http://example.org/default#p> <http://other.org/o> .`: `@prefix : <http://example.org/default#> .
+@prefix ex2: <http://example.org/2/> .
+
+ex2:s :p <http://other.org/o> .
+`,
+
+	`<http://example.org/> <http://example.org/-a.b.> <http://example.org/a~b%20c%2> .`: `@prefix ex: <http://example.org/> .
+
+ex: ex:\-a.b\. ex:a\~b%20c\%2 .
+`,
+
+	`<http://example.org/bad/s> <http://example.org/p\u0020q> <http://example.org/1> .`: `@prefix ex: <http://example.org/> .
+
+ex:bad\/s <http://example.org/p\u0020q> ex:1 .
+`,
+
+	// Literals
+	`<http://example.org/s> <http://example.org/p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/s> <http://example.org/p> "-1.5"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<http://example.org/s> <http://example.org/p> "1.0E3"^^<http://www.w3.org/2001/XMLSchema#double> .
+<http://example.org/s> <http://example.org/p> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<http://example.org/s> <http://example.org/p> "1."^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<http://example.org/s> <http://example.org/p> "yes"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<http://example.org/s> <http://example.org/p> "a\nb"@en .
+<http://example.org/s> <http://example.org/p> "c"^^<http://www.w3.org/2001/XMLSchema#string> .`: `@prefix ex: <http://example.org/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+ex:s ex:p 1, -1.5, 1.0E3, true, "1."^^xsd:decimal, "yes"^^xsd:boolean, "a\nb"@en, "c" .
+`,
+
+	// Equal quoted triples are grouped under one subject
+	`<< <http://example.org/a> <http://example.org/b> <http://example.org/c> >> <http://example.org/p> "1" .
+<< <http://example.org/a> <http://example.org/b> <http://example.org/c> >> <http://example.org/p> "1" .
+<< <http://example.org/a> <http://example.org/b> "c"@EN >> <http://example.org/q> "2" .
+<< <http://example.org/a> <http://example.org/b> <http://example.org/c> >> <http://example.org/q> "3" .
+<< <http://example.org/a> <http://example.org/b> "c"@en >> <http://example.org/q> "4" .`: `@prefix ex: <http://example.org/> .
+
+<< ex:a ex:b ex:c >> ex:p "1" ;
+    ex:q "3" .
+
+<< ex:a ex:b "c"@EN >> ex:q "2", "4" .
+`,
+
+	// Equal triples written differently are only written once
+	`<http://example.org/s> <http://example.org/p> "1"^^<http://www.w3.org/2001/XMLSchema#string> .
+<http://example.org/s> <http://example.org/p> "1" .
+<http://example.org/s> <http://example.org/p> "a"@en .
+<http://example.org/s> <http://example.org/p> "a"@EN .`: `@prefix ex: <http://example.org/> .
+
+ex:s ex:p "1", "a"@en .
+`,
+
+	// Grouping
+	`<http://example.org/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/C> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o1> .
+<http://example.org/t> <http://example.org/p> <http://example.org/o1> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o2> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o2> .
+<http://example.org/s> <http://example.org/q> <http://example.org/o3> .`: `@prefix ex: <http://example.org/> .
+
+ex:s a ex:C ;
+    ex:p ex:o1, ex:o2 ;
+    ex:q ex:o3 .
+
+ex:t ex:p ex:o1 .
+`,
+
+	// Blank nodes
+	`<http://example.org/s> <http://example.org/p> _:b .
+_:b <http://example.org/q> _:c .
+_:c <http://example.org/r> "x" .
+_:c <http://example.org/r> "y" .
+_:b <http://example.org/q2> _:d .`: `@prefix ex: <http://example.org/> .
+
+ex:s ex:p [
+        ex:q [
+            ex:r "x", "y"
+        ] ;
+        ex:q2 []
+    ] .
+`,
+
+	`_:a <http://example.org/p> <http://example.org/o> .
+_:a <http://example.org/q> _:b .
+<http://example.org/s> <http://example.org/p> _:b .
+_:b <http://example.org/r> "x" .`: `@prefix ex: <http://example.org/> .
+
+[ ex:p ex:o ;
+    ex:q _:b ] .
+
+ex:s ex:p _:b .
+
+_:b ex:r "x" .
+`,
+
+	`_:a <http://example.org/p> _:b .
+_:b <http://example.org/p> _:a .
+_:c:d <http://example.org/p> _:b1 .
+_:b1 <http://example.org/p> _:c:d .`: `@prefix ex: <http://example.org/> .
+
+_:a ex:p [
+        ex:p _:a
+    ] .
+
+_:b0 ex:p [
+        ex:p _:b0
+    ] .
+`,
+
+	// Quoted triples
+	`<< <http://example.org/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/C> >> <http://example.org/source> << <http://example.org/s> <http://example.org/p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> >> .`: `@prefix ex: <http://example.org/> .
+@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+
+<< ex:s rdf:type ex:C >> ex:source << ex:s ex:p 1 >> .
+`,
+
+	`<http://example.org/s> <http://example.org/p> _:b0 .
+_:b0 <http://example.org/q> <http://example.org/o> .
+<http://example.org/s> <http://example.org/source> << _:b0 <http://example.org/q> <http://example.org/o> >> .`: `@prefix ex: <http://example.org/> .
+
+ex:s ex:p _:b0 ;
+    ex:source << _:b0 ex:q ex:o >> .
+
+_:b0 ex:q ex:o .
+`,
+
+	// Collections
+	`<http://example.org/s> <http://example.org/p> _:l1 .
+_:l1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/a> .
+_:l1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:l2 .
+_:l2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> _:x .
+_:l2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+_:x <http://example.org/q> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/s> <http://example.org/p> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .`: `@prefix ex: <http://example.org/> .
+
+ex:s ex:p ( ex:a [
+        ex:q 1
+    ] ), () .
+`,
+
+	`<http://example.org/s> <http://example.org/p> _:l1 .
+_:l1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/a> .
+_:l1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://example.org/tail> .`: `@prefix ex: <http://example.org/> .
+@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+
+ex:s ex:p [
+        rdf:first ex:a ;
+        rdf:rest ex:tail
+    ] .
+`,
+}
+
+// writeTurtle writes the triples in an N-Triples document as Turtle.
+func writeTurtle(doc string, prefixes map[string]string) (string, error) {
+	var buf bytes.Buffer
+	w := NewTurtleWriter(&buf)
+	w.Prefixes = prefixes
+
+	r := NewReader(strings.NewReader(doc))
+	for r.Next() {
+		if err := w.Write(r.Triple()); err != nil {
+			return "", err
+		}
+	}
+	if r.Err() != nil {
+		return "", r.Err()
+	}
+
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+func TestWriteTurtle(t *testing.T) {
+	for doc, expected := range turtleWriteCases {
+		t.Run("", func(t *testing.T) {
+			actual, err := writeTurtle(doc, turtleWritePrefixes)
+			if err != nil {
+				t.Fatalf("Got unexpected error %v for:\n%s", err, doc)
+			}
+			if actual != expected {
+				t.Errorf("Expected:\n%s\nbut got:\n%s\nfor:\n%s", expected, actual, doc)
+			}
+		})
+	}
+}
+
+func TestWriteTurtleRoundTrip(t *testing.T) {
+	for doc := range turtleWriteCases {
+		t.Run("", func(t *testing.T) {
+			turtle, err := writeTurtle(doc, turtleWritePrefixes)
+			if err != nil {
+				t.Fatalf("Got unexpected error %v for:\n%s", err, doc)
+			}
+			actual, err := readGraph(NewTurtleReader(strings.NewReader(turtle)))
+			if err != nil {
+				t.Fatalf("Got unexpected error %v for:\n%s", err, turtle)
+			}
+			expected, err := readGraph(NewReader(strings.NewReader(doc)))
+			if err != nil {
+				t.Fatalf("Got unexpected error %v for:\n%s", err, doc)
+			}
+
+			// Blank node labels are not preserved so only the size of graphs
+			// containing blank nodes can be compared.
+			if strings.Contains(doc, "_:") {
+				if len(actual) != len(expected) {
+					t.Errorf("Expected %d triples but got %d for:\n%s", len(expected), len(actual), turtle)
+				}
+			} else if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("Expected:\n%s\nbut got:\n%s\nfor:\n%s", expected, actual, turtle)
+			}
+		})
+	}
+}
+
+func TestWriteTurtleErrors(t *testing.T) {
+	for _, tc := range writeErrorCases {
+		t.Run("", func(t *testing.T) {
+			w := NewTurtleWriter(&bytes.Buffer{})
+			if err := w.Write(tc.triple); err != tc.expected {
+				t.Errorf("Expected %v for %#v but got %v", tc.expected, tc.triple, err)
+			}
+		})
+	}
+}
+
+// readGraph reads all the triples from r, returning the distinct triples in
+// canonical N-Triples in sorted order.
+func readGraph(r interface {
+	Next() bool
+	Triple() Triple
+	Err() error
+}) ([]string, error) {
+	seen := map[string]bool{}
+	var lines []string
+	for r.Next() {
+		line := r.Triple().CanonicalString()
+		if !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	return lines, r.Err()
+}
@@ -0,0 +1,119 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+const w3cSuiteDir = "testdata/w3c/ntriples"
+
+// A manifestEntry is a single test described by a W3C rdf-tests manifest.
+type manifestEntry struct {
+	Name     string
+	Type     string
+	Action   string
+	Positive bool
+}
+
+var (
+	manifestSubject = regexp.MustCompile(`^<#([^>]+)>`)
+	manifestType    = regexp.MustCompile(`rdf:type\s+rdft:(\w+)`)
+	manifestAction  = regexp.MustCompile(`mf:action\s+<([^>]+)>`)
+)
+
+// readManifest extracts the test entries from a W3C rdf-tests manifest. The
+// manifests are written in a regular subset of Turtle, one statement per
+// line, so a line-based scan is sufficient.
+func readManifest(filename string) ([]manifestEntry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []manifestEntry
+	var current *manifestEntry
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if m := manifestSubject.FindStringSubmatch(line); m != nil {
+			entries = append(entries, manifestEntry{Name: m[1]})
+			current = &entries[len(entries)-1]
+		}
+		if current == nil {
+			continue
+		}
+		if m := manifestType.FindStringSubmatch(line); m != nil {
+			current.Type = m[1]
+			current.Positive = m[1] == "TestNTriplesPositiveSyntax"
+		}
+		if m := manifestAction.FindStringSubmatch(line); m != nil {
+			current.Action = m[1]
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// parseFile reads every triple in filename, returning the first error encountered.
+func parseFile(filename string) (int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	count := 0
+	r := NewReader(f)
+	for r.Next() {
+		count++
+	}
+	return count, r.Err()
+}
+
+func TestW3CSuite(t *testing.T) {
+	entries, err := readManifest(filepath.Join(w3cSuiteDir, "manifest.ttl"))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatalf("no tests found in manifest")
+	}
+
+	passed := 0
+	for _, e := range entries {
+		e := e
+		ok := t.Run(e.Name, func(t *testing.T) {
+			switch e.Type {
+			case "TestNTriplesPositiveSyntax", "TestNTriplesNegativeSyntax":
+			default:
+				t.Skipf("unsupported test type %s", e.Type)
+			}
+
+			count, err := parseFile(filepath.Join(w3cSuiteDir, e.Action))
+			if e.Positive && err != nil {
+				t.Errorf("%s: expected to parse but got error %v after %d triples", e.Action, err, count)
+			}
+			if !e.Positive && err == nil {
+				t.Errorf("%s: expected a syntax error but parsed %d triples", e.Action, count)
+			}
+		})
+		if ok {
+			passed++
+		}
+	}
+
+	t.Logf("W3C N-Triples conformance: passed %d of %d tests", passed, len(entries))
+}
@@ -0,0 +1,308 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// These are the errors that can be returned by Writer.Write when a triple
+// cannot be represented as valid N-Triples.
+var (
+	ErrInvalidSubject   = errors.New("subject must be an IRI, blank node or quoted triple")
+	ErrInvalidPredicate = errors.New("predicate must be an IRI")
+	ErrInvalidIri       = errors.New("invalid IRI, expecting absolute IRI")
+	ErrInvalidBlankNode = errors.New("invalid blank node label")
+	ErrInvalidLiteral   = errors.New("literal cannot have both a language and a datatype")
+	ErrInvalidLanguage  = errors.New("invalid language tag")
+	ErrUnknownTermType  = errors.New("unknown term type")
+	ErrInvalidTriple    = errors.New("quoted triple term has no triple")
+)
+
+// A Writer writes triples using N-Triples encoding.
+//
+// Each triple is written on its own line terminated by a newline. Writes are
+// buffered, so Flush must be called to ensure that the triples have been
+// written to the underlying io.Writer.
+//
+// The exported fields can be changed to customize the details before the
+// first call to Write.
+type Writer struct {
+	// Canonical selects the canonical N-Triples form, in which identical
+	// triples are always written as identical bytes however they were
+	// originally encoded. Language tags are written in lowercase and the
+	// xsd:string datatype is omitted from simple literals.
+	Canonical bool
+
+	w *bufio.Writer
+	b []byte
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: bufio.NewWriter(w),
+	}
+}
+
+// Write writes a single triple to w. The triple is validated first and
+// nothing is written if it cannot be represented as valid N-Triples.
+func (w *Writer) Write(t Triple) error {
+	if err := validateTriple(t); err != nil {
+		return err
+	}
+
+	if w.Canonical {
+		t = canonicalTriple(t)
+	}
+
+	var err error
+	w.b, err = appendTriple(w.b[:0], t)
+	if err != nil {
+		return err
+	}
+	w.b = append(w.b, '\n')
+	_, err = w.w.Write(w.b)
+	return err
+}
+
+// WriteAll writes multiple triples to w using Write and then calls Flush.
+func (w *Writer) WriteAll(triples []Triple) error {
+	for _, t := range triples {
+		if err := w.Write(t); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+// To check if an error occurred during the Flush, call Error.
+func (w *Writer) Flush() {
+	w.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Write or Flush.
+func (w *Writer) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// validateTriple checks that each term of t is valid for its position.
+func validateTriple(t Triple) error {
+	if t.S.TermType != RdfIri && t.S.TermType != RdfBlank && t.S.TermType != RdfTriple {
+		return ErrInvalidSubject
+	}
+	if t.P.TermType != RdfIri {
+		return ErrInvalidPredicate
+	}
+
+	for _, term := range []RdfTerm{t.S, t.P, t.O} {
+		if err := validateTerm(term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTerm checks that t can be represented in N-Triples.
+func validateTerm(t RdfTerm) error {
+	switch t.TermType {
+	case RdfIri:
+		if !isAbsoluteIRI(t.Value) {
+			return ErrInvalidIri
+		}
+	case RdfBlank:
+		if !isBlankNodeLabel(t.Value) {
+			return ErrInvalidBlankNode
+		}
+	case RdfLiteral:
+		if t.Language != "" {
+			if t.DataType != "" {
+				return ErrInvalidLiteral
+			}
+			if !isLangTag(t.Language) {
+				return ErrInvalidLanguage
+			}
+		}
+		if t.DataType != "" && !isAbsoluteIRI(t.DataType) {
+			return ErrInvalidIri
+		}
+		if t.Language == "" && t.DataType == rdfLangString {
+			return ErrInvalidLanguage
+		}
+	case RdfTriple:
+		if t.Triple == nil {
+			return ErrInvalidTriple
+		}
+		return validateTriple(*t.Triple)
+	default:
+		return ErrUnknownTermType
+	}
+	return nil
+}
+
+// canonicalTriple returns t with each term in canonical form.
+func canonicalTriple(t Triple) Triple {
+	return Triple{
+		S: canonicalTerm(t.S),
+		P: canonicalTerm(t.P),
+		O: canonicalTerm(t.O),
+	}
+}
+
+// canonicalTerm returns t with its language tag in lowercase and without an
+// xsd:string datatype, which is implied for literals with no language tag.
+// The terms of a quoted triple are made canonical in a copy of the triple.
+func canonicalTerm(t RdfTerm) RdfTerm {
+	switch t.TermType {
+	case RdfLiteral:
+		t.Language = strings.ToLower(t.Language)
+		if t.DataType == xsdString {
+			t.DataType = ""
+		}
+	case RdfTriple:
+		if t.Triple != nil {
+			triple := canonicalTriple(*t.Triple)
+			t.Triple = &triple
+		}
+	}
+	return t
+}
+
+// appendTriple appends the N-Triples encoding of t, without a trailing newline, to dst.
+func appendTriple(dst []byte, t Triple) ([]byte, error) {
+	dst, err := appendTerms(dst, t.S, t.P, t.O)
+	return append(dst, ' ', '.'), err
+}
+
+// appendTerms appends terms separated by spaces to dst.
+func appendTerms(dst []byte, terms ...RdfTerm) ([]byte, error) {
+	var err error
+	for i, term := range terms {
+		if i > 0 {
+			dst = append(dst, ' ')
+		}
+		if dst, err = appendTerm(dst, term); err != nil {
+			return dst, err
+		}
+	}
+	return dst, nil
+}
+
+// appendTerm appends the N-Triples encoding of t to dst, writing quoted
+// triples as in N-Triples-star. It returns ErrUnknownTermType if t is not of
+// a known type.
+func appendTerm(dst []byte, t RdfTerm) ([]byte, error) {
+	switch t.TermType {
+	case RdfIri:
+		return appendIRI(dst, t.Value), nil
+	case RdfBlank:
+		dst = append(dst, '_', ':')
+		return append(dst, t.Value...), nil
+	case RdfLiteral:
+		dst = appendLiteral(dst, t.Value)
+		if t.Language != "" {
+			dst = append(dst, '@')
+			return append(dst, t.Language...), nil
+		}
+		if t.DataType != "" {
+			dst = append(dst, '^', '^')
+			return appendIRI(dst, t.DataType), nil
+		}
+		return dst, nil
+	case RdfTriple:
+		if t.Triple == nil {
+			return dst, ErrInvalidTriple
+		}
+		dst = append(dst, '<', '<', ' ')
+		dst, err := appendTerms(dst, t.Triple.S, t.Triple.P, t.Triple.O)
+		return append(dst, ' ', '>', '>'), err
+	}
+	return dst, ErrUnknownTermType
+}
+
+// formatText returns text as a string for use by the String methods, which
+// cannot return an error. If err is not nil the result describes the error in
+// the style of the fmt package instead.
+func formatText(text []byte, err error) string {
+	if err != nil {
+		return "%!(" + err.Error() + ")"
+	}
+	return string(text)
+}
+
+// appendIRI appends s as an IRIREF, escaping any characters that may not
+// appear in an IRIREF with UCHAR escapes.
+func appendIRI(dst []byte, s string) []byte {
+	dst = append(dst, '<')
+	for _, r1 := range s {
+		if isIRIChar(r1) {
+			dst = appendRune(dst, r1)
+		} else {
+			dst = appendUchar(dst, r1)
+		}
+	}
+	return append(dst, '>')
+}
+
+// appendLiteral appends s as a STRING_LITERAL_QUOTE, using ECHAR escapes
+// where available and UCHAR escapes for any other control characters. This
+// matches the escaping required by canonical N-Triples: all other characters,
+// including non-ASCII, are written unescaped.
+func appendLiteral(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for _, r1 := range s {
+		switch r1 {
+		case '"', '\\':
+			dst = append(dst, '\\', byte(r1))
+		case '\b':
+			dst = append(dst, '\\', 'b')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\f':
+			dst = append(dst, '\\', 'f')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		default:
+			if r1 < 0x20 || r1 == 0x7F {
+				dst = appendUchar(dst, r1)
+			} else {
+				dst = appendRune(dst, r1)
+			}
+		}
+	}
+	return append(dst, '"')
+}
+
+// appendUchar appends r1 as a \u or \U escape with uppercase hex digits.
+func appendUchar(dst []byte, r1 rune) []byte {
+	const hex = "0123456789ABCDEF"
+	digits := 4
+	if r1 > 0xFFFF {
+		dst = append(dst, '\\', 'U')
+		digits = 8
+	} else {
+		dst = append(dst, '\\', 'u')
+	}
+	for i := digits - 1; i >= 0; i-- {
+		dst = append(dst, hex[(r1>>uint(4*i))&0xF])
+	}
+	return dst
+}
+
+// appendRune appends the UTF-8 encoding of r1 to dst.
+func appendRune(dst []byte, r1 rune) []byte {
+	var b [utf8.UTFMax]byte
+	n := utf8.EncodeRune(b[:], r1)
+	return append(dst, b[:n]...)
+}
@@ -0,0 +1,256 @@
+/*
+  This is free and unencumbered software released into the public domain. For more
+  information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package ntriples
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var writeCases = map[string]Triple{
+	`<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> .`: {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+	},
+
+	`_:anon <http://example.org/property> _:a.b .`: {
+		S: RdfTerm{Value: "anon", TermType: RdfBlank},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "a.b", TermType: RdfBlank},
+	},
+
+	`<http://example.org/a\u0020b\u007Bc\u007D> <http://example.org/property> <http://example.org/résumé> .`: {
+		S: RdfTerm{Value: "http://example.org/a b{c}", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "http://example.org/résumé", TermType: RdfIri},
+	},
+
+	`<http://example.org/resource1> <http://example.org/property> "quote:\" backslash:\\ newline:\n return:\r tab:\t" .`: {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "quote:\" backslash:\\ newline:\n return:\r tab:\t", TermType: RdfLiteral},
+	},
+
+	`<http://example.org/resource1> <http://example.org/property> "\u0000\b\f\u001F\u007F é 😀" .`: {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "\x00\b\f\x1f\x7f é 😀", TermType: RdfLiteral},
+	},
+
+	`<http://example.org/resource1> <http://example.org/property> "chat"@en-GB .`: {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "chat", Language: "en-GB", TermType: RdfLiteral},
+	},
+
+	`<http://example.org/resource1> <http://example.org/property> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .`: {
+		S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+		P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+		O: RdfTerm{Value: "1", DataType: "http://www.w3.org/2001/XMLSchema#integer", TermType: RdfLiteral},
+	},
+}
+
+var writeErrorCases = []struct {
+	triple   Triple
+	expected error
+}{
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "chat", DataType: rdfLangString, TermType: RdfLiteral},
+		},
+		expected: ErrInvalidLanguage,
+	},
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "foo", TermType: RdfLiteral},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+		},
+		expected: ErrInvalidSubject,
+	},
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "anon", TermType: RdfBlank},
+			O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+		},
+		expected: ErrInvalidPredicate,
+	},
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+		},
+		expected: ErrInvalidIri,
+	},
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "a b", TermType: RdfBlank},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "http://example.org/resource2", TermType: RdfIri},
+		},
+		expected: ErrInvalidBlankNode,
+	},
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "ab.", TermType: RdfBlank},
+		},
+		expected: ErrInvalidBlankNode,
+	},
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "chat", Language: "en", DataType: "http://example.org/DataType1", TermType: RdfLiteral},
+		},
+		expected: ErrInvalidLiteral,
+	},
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "chat", Language: "en gb", TermType: RdfLiteral},
+		},
+		expected: ErrInvalidLanguage,
+	},
+	{
+		triple: Triple{
+			S: RdfTerm{Value: "http://example.org/resource1", TermType: RdfIri},
+			P: RdfTerm{Value: "http://example.org/property", TermType: RdfIri},
+			O: RdfTerm{Value: "chat"},
+		},
+		expected: ErrUnknownTermType,
+	},
+}
+
+func TestWrite(t *testing.T) {
+	for expected, triple := range writeCases {
+		t.Run("", func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			if err := w.Write(triple); err != nil {
+				t.Fatalf("Write(%#v) returned unexpected error %v", triple, err)
+			}
+			w.Flush()
+			if err := w.Error(); err != nil {
+				t.Fatalf("Got unexpected error %v", err)
+			}
+
+			if buf.String() != expected+"\n" {
+				t.Errorf("Expected %s but got %s", expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	var triples []Triple
+	for _, triple := range testCases {
+		triples = append(triples, triple)
+	}
+	for _, triple := range writeCases {
+		triples = append(triples, triple)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteAll(triples); err != nil {
+		t.Fatalf("Got unexpected error %v", err)
+	}
+
+	count := 0
+	r := NewReader(strings.NewReader(buf.String()))
+	for r.Next() {
+		if r.Triple() != triples[count] {
+			t.Errorf("Expected %s but got %s", triples[count], r.Triple())
+		}
+		count++
+	}
+
+	if r.Err() != nil {
+		t.Fatalf("Got unexpected error %v", r.Err())
+	}
+
+	if count != len(triples) {
+		t.Errorf("Expected %d but only parsed %d triples", len(triples), count)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	for _, tc := range writeErrorCases {
+		t.Run("", func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			err := w.Write(tc.triple)
+			if err != tc.expected {
+				t.Errorf("Expected %v for %#v but got %v", tc.expected, tc.triple, err)
+			}
+			w.Flush()
+			if buf.Len() != 0 {
+				t.Errorf("Expected nothing to be written but got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteCanonical(t *testing.T) {
+	equivalents := [][]string{
+		{
+			`<http://example.org/resource1> <http://example.org/property> "A" .`,
+			`<http://example.org/resource1>	<http://example.org/property>   "A". # comment`,
+			`<http://example.org/resource1> <http://example.org/property> "\U00000041"^^<http://www.w3.org/2001/XMLSchema#string> .`,
+		},
+		{
+			`<http://example.org/resource1> <http://example.org/property> "a\tb\u000Bc\"dé" .`,
+			`<http://example.org/resource1> <http://example.org/property> "a\u0009b\u000bc\u0022d\u00E9" .`,
+		},
+		{
+			`<http://example.org/resource1> <http://example.org/property> "chat"@en-gb .`,
+			`<http://example.org/resource1> <http://example.org/property> "chat"@EN-GB .`,
+			`<http://example.org/resource1> <http://example.org/property> "chat"@en-GB .`,
+		},
+		{
+			`<http://example.org/résumé> <http://example.org/property> _:b0 .`,
+			`<http://example.org/résumé> <http://example.org/property> _:b0.`,
+		},
+		{
+			`<< _:b0 <http://example.org/property> "chat"@en >> <http://example.org/property> << <http://example.org/a> <http://example.org/b> "A" >> .`,
+			`<<_:b0 <http://example.org/property> "chat"@EN>> <http://example.org/property> <<( <http://example.org/a> <http://example.org/b> "\u0041"^^<http://www.w3.org/2001/XMLSchema#string> )>> .`,
+		},
+	}
+
+	for _, inputs := range equivalents {
+		expected := inputs[0] + "\n"
+		for _, input := range inputs {
+			r := NewReader(strings.NewReader(input))
+			if !r.Next() {
+				t.Fatalf("Failed to read %s: %v", input, r.Err())
+			}
+
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			w.Canonical = true
+			if err := w.Write(r.Triple()); err != nil {
+				t.Fatalf("Got unexpected error %v", err)
+			}
+			w.Flush()
+
+			if buf.String() != expected {
+				t.Errorf("Expected %q for %s but got %q", expected, input, buf.String())
+			}
+			if r.Triple().CanonicalString()+"\n" != expected {
+				t.Errorf("Expected %q for %s but got %q", expected, input, r.Triple().CanonicalString())
+			}
+		}
+	}
+}